@@ -0,0 +1,123 @@
+// Package client is typed Go client of ranking server, models.go and operations.go are generated from openapi/openapi.yaml
+package client
+
+//go:generate go run ./internal/gen -out .
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error is error response of server, Code is empty for endpoints that answer plain text
+type Error struct {
+	StatusCode int               `json:"-"`
+	Code       string            `json:"code"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("ranking server: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("ranking server: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client call ranking server at base URL
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Option configure Client
+type Option func(*Client)
+
+// WithHTTPClient use httpClient instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New create client of server at baseURL, ex. https://localhost:8444
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// do send request with JSON body when body is not nil and decode JSON response to out when out is not nil,
+// out of *string get body as text
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// old endpoints answer 204 for missing parameter
+	if resp.StatusCode == http.StatusNoContent {
+		return &Error{StatusCode: resp.StatusCode, Message: "invalid param"}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp, respBody)
+	}
+	if text, ok := out.(*string); ok {
+		*text = string(respBody)
+		return nil
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func decodeError(resp *http.Response, respBody []byte) error {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			Error *Error `json:"error"`
+		}
+		if err := json.Unmarshal(respBody, &body); err == nil && body.Error != nil {
+			body.Error.StatusCode = resp.StatusCode
+			return body.Error
+		}
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+}
@@ -0,0 +1,314 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"rangkingserver/client/internal/spec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// contractServer is fake ranking server that check every request against openapi.yaml and answer sample of response schema,
+// request that break the document get 400 with the reason as plain text
+type contractServer struct {
+	doc *spec.Document
+
+	mu sync.Mutex
+	// called is operationId of last request
+	called string
+}
+
+func (s *contractServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	operation, params := s.doc.Find(r.Method, r.URL.Path)
+	if operation == nil {
+		http.Error(w, fmt.Sprintf("%s %s is not in openapi.yaml", r.Method, r.URL.Path), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.called = operation.OperationID
+	s.mu.Unlock()
+	if err := s.check(operation, params, r); err != nil {
+		http.Error(w, operation.OperationID+": "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := operation.Responses["200"]
+	if schema := spec.JSONSchema(response.Content); schema != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.doc.Sample(schema))
+		return
+	}
+	if len(response.Content) > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok"))
+	}
+}
+
+// check path, query and body of request against operation
+func (s *contractServer) check(operation *spec.Operation, params map[string]string, r *http.Request) error {
+	if err := s.doc.ValidatePath(operation, params); err != nil {
+		return err
+	}
+	if err := s.doc.ValidateQuery(operation, r.URL.Query()); err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if operation.RequestBody == nil {
+		if len(body) > 0 {
+			return errors.New("operation has no request body")
+		}
+		return nil
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("content type is %q", r.Header.Get("Content-Type"))
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return err
+	}
+	return s.doc.Validate(spec.JSONSchema(operation.RequestBody.Content), value)
+}
+
+func (s *contractServer) lastCalled() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.called
+}
+
+func newContractServer(t *testing.T) (*contractServer, *Client) {
+	t.Helper()
+	doc, err := spec.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := &contractServer{doc: doc}
+	server := httptest.NewServer(contract)
+	t.Cleanup(server.Close)
+	return contract, New(server.URL, WithHTTPClient(server.Client()))
+}
+
+// TestClientFollowsOpenAPI call every operation of openapi.yaml, requests must match the document
+// and every property of sample responses must survive decode into client models
+func TestClientFollowsOpenAPI(t *testing.T) {
+	contract, c := newContractServer(t)
+	ctx := context.Background()
+	dimensions := map[string]string{"game_mode": "1"}
+	startAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	calls := map[string]func() (interface{}, error){
+		"clearLeaderboards": func() (interface{}, error) {
+			return c.ClearLeaderboards(ctx, ClearLeaderboardsParams{Period: "ScoreKey"})
+		},
+		"getSeasons": func() (interface{}, error) { return c.GetSeasons(ctx) },
+		"endSeason":  func() (interface{}, error) { return c.EndSeason(ctx) },
+		"getSeasonEntries": func() (interface{}, error) {
+			return c.GetSeasonEntries(ctx, "1", 3, GetSeasonEntriesParams{Limit: 10, Dimensions: dimensions})
+		},
+		"submitScore": func() (interface{}, error) {
+			return c.SubmitScore(ctx, "1", ScoreRequest{UID: "u1", Name: "one", Amount: 10, Dimensions: dimensions})
+		},
+		"submitMatch": func() (interface{}, error) {
+			return c.SubmitMatch(ctx, "1", MatchRequest{Placements: []string{"u1", "u2", "u3"}})
+		},
+		"getRating": func() (interface{}, error) {
+			return c.GetRating(ctx, "1", "u1", GetRatingParams{Period: "ScoreKey", Dimensions: dimensions})
+		},
+		"getMilestones": func() (interface{}, error) { return c.GetMilestones(ctx, "1", "u1") },
+		"getEntries": func() (interface{}, error) {
+			return c.GetEntries(ctx, "1", GetEntriesParams{Period: "ScoreKey", Limit: 10, Dimensions: dimensions})
+		},
+		"getEntry": func() (interface{}, error) {
+			return c.GetEntry(ctx, "1", "u1", GetEntryParams{Period: "ScoreKey", Dimensions: dimensions})
+		},
+		"getFriendEntries": func() (interface{}, error) {
+			return c.GetFriendEntries(ctx, "1", "u1", GetFriendEntriesParams{Friends: []string{"u2", "u3"}})
+		},
+		"getTeams": func() (interface{}, error) {
+			return c.GetTeams(ctx, "1", GetTeamsParams{Limit: 10})
+		},
+		"getTeam": func() (interface{}, error) {
+			return c.GetTeam(ctx, "1", "red", GetTeamParams{Period: "ScoreKey"})
+		},
+		"getLeague":        func() (interface{}, error) { return c.GetLeague(ctx, "1", "u1") },
+		"getLeagueHistory": func() (interface{}, error) { return c.GetLeagueHistory(ctx, "1", "u1") },
+		"getUserTeam":      func() (interface{}, error) { return c.GetUserTeam(ctx, "u1") },
+		"joinTeam": func() (interface{}, error) {
+			return c.JoinTeam(ctx, "u1", TeamRequest{Team: "red"})
+		},
+		"leaveTeam":  func() (interface{}, error) { return c.LeaveTeam(ctx, "u1") },
+		"getFriends": func() (interface{}, error) { return c.GetFriends(ctx, "u1") },
+		"addFriends": func() (interface{}, error) {
+			return c.AddFriends(ctx, "u1", FriendsRequest{UIDs: []string{"u2"}})
+		},
+		"removeFriend": func() (interface{}, error) { return c.RemoveFriend(ctx, "u1", "u2") },
+		"getTournaments": func() (interface{}, error) {
+			return c.GetTournaments(ctx, GetTournamentsParams{Status: "active", Limit: 5})
+		},
+		"createTournament": func() (interface{}, error) {
+			endAt := startAt.Add(time.Hour)
+			return c.CreateTournament(ctx, TournamentRequest{
+				ID:                   "t1",
+				StartAt:              startAt,
+				EndAt:                endAt,
+				RegistrationRequired: true,
+				RegistrationEndAt:    &endAt,
+				MaxParticipants:      10,
+			})
+		},
+		"getTournament": func() (interface{}, error) { return c.GetTournament(ctx, "t1") },
+		"registerTournament": func() (interface{}, error) {
+			return c.RegisterTournament(ctx, "t1", RegistrationRequest{UID: "u1"})
+		},
+		"submitTournamentScore": func() (interface{}, error) {
+			return c.SubmitTournamentScore(ctx, "t1", TournamentScoreRequest{UID: "u1", Amount: 5})
+		},
+		"getTournamentEntries": func() (interface{}, error) {
+			return c.GetTournamentEntries(ctx, "t1", GetTournamentEntriesParams{Limit: 5})
+		},
+		"getTournamentEntry": func() (interface{}, error) { return c.GetTournamentEntry(ctx, "t1", "u1") },
+		"getWebhooks":        func() (interface{}, error) { return c.GetWebhooks(ctx) },
+		"createWebhook": func() (interface{}, error) {
+			return c.CreateWebhook(ctx, WebhookRequest{URL: "https://example.com/hook", Kinds: []string{"board.reset"}, Secret: "0123456789abcdef"})
+		},
+		"getWebhook":    func() (interface{}, error) { return c.GetWebhook(ctx, "w1") },
+		"deleteWebhook": func() (interface{}, error) { return c.DeleteWebhook(ctx, "w1") },
+		"getWebhookDeliveries": func() (interface{}, error) {
+			return c.GetWebhookDeliveries(ctx, "w1", GetWebhookDeliveriesParams{Limit: 5})
+		},
+		"saveGamePlayRanking": func() (interface{}, error) {
+			return nil, c.SaveGamePlayRanking(ctx, UserBody{UID: "u1", EventType: "1", Amount: "10"})
+		},
+		"getRankingByEvent": func() (interface{}, error) {
+			return c.GetRankingByEvent(ctx, GetRankingByEventParams{
+				UID:             "u1",
+				EventType:       "1",
+				GameMode:        "1",
+				SubTitle:        "1",
+				RankingDuration: "ScoreKey",
+				IsServerRequest: "0",
+			})
+		},
+		"clearRankingByKey": func() (interface{}, error) {
+			return nil, c.ClearRankingByKey(ctx, ClearRankingByKeyParams{RankingKey: "ScoreKey"})
+		},
+		"healthz": func() (interface{}, error) { return c.Healthz(ctx) },
+		"readyz":  func() (interface{}, error) { return c.Readyz(ctx) },
+		"status":  func() (interface{}, error) { return c.Status(ctx) },
+		"metrics": func() (interface{}, error) { return c.Metrics(ctx) },
+		"openapi": func() (interface{}, error) { return c.OpenAPI(ctx) },
+	}
+
+	for _, operation := range contract.doc.Operations() {
+		operation := operation
+		t.Run(operation.OperationID, func(t *testing.T) {
+			call, ok := calls[operation.OperationID]
+			if !ok {
+				t.Fatalf("no call of %s %s, add it to this test", operation.Method, operation.Path)
+			}
+			delete(calls, operation.OperationID)
+			result, err := call()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if called := contract.lastCalled(); called != operation.OperationID {
+				t.Fatalf("request matched %s", called)
+			}
+			schema := spec.JSONSchema(operation.Responses["200"].Content)
+			if schema == nil {
+				return
+			}
+			// decoded response must encode back to the sample, so no property is lost or renamed by models
+			encoded, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got interface{}
+			if err := json.Unmarshal(encoded, &got); err != nil {
+				t.Fatal(err)
+			}
+			want := contract.doc.Sample(schema)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("response round trip\n got: %s\nwant: %s", encoded, mustJSON(t, want))
+			}
+			if err := contract.doc.Validate(schema, got); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	for operationID := range calls {
+		t.Errorf("call of %s is not an operation of openapi.yaml", operationID)
+	}
+}
+
+func TestContractServerRejectsInvalidRequest(t *testing.T) {
+	_, c := newContractServer(t)
+	ctx := context.Background()
+
+	_, err := c.SubmitScore(ctx, "1", ScoreRequest{Amount: 10})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || !strings.Contains(apiErr.Message, "uid") {
+		t.Errorf("score without uid: %v", err)
+	}
+	_, err = c.GetTournaments(ctx, GetTournamentsParams{Status: "running"})
+	if !errors.As(err, &apiErr) || !strings.Contains(apiErr.Message, "status") {
+		t.Errorf("unknown tournament status: %v", err)
+	}
+	_, err = c.SubmitMatch(ctx, "1", MatchRequest{Placements: []string{"u1"}})
+	if !errors.As(err, &apiErr) || !strings.Contains(apiErr.Message, "placements") {
+		t.Errorf("match of one player: %v", err)
+	}
+}
+
+func TestClientDecodeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/leaderboards/1/entries/u1":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"not_found","message":"uid u1 not found"}}`))
+		case "/readyz":
+			http.Error(w, "store: ping failed", http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL + "/")
+	ctx := context.Background()
+
+	_, err := c.GetEntry(ctx, "1", "u1", GetEntryParams{})
+	want := &Error{StatusCode: http.StatusNotFound, Code: "not_found", Message: "uid u1 not found"}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("json error = %#v, want %#v", err, want)
+	}
+	_, err = c.Readyz(ctx)
+	want = &Error{StatusCode: http.StatusServiceUnavailable, Message: "store: ping failed"}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("plain error = %#v, want %#v", err, want)
+	}
+	err = c.ClearRankingByKey(ctx, ClearRankingByKeyParams{})
+	want = &Error{StatusCode: http.StatusNoContent, Message: "invalid param"}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("no content = %#v, want %#v", err, want)
+	}
+}
+
+func mustJSON(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
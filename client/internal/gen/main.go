@@ -0,0 +1,526 @@
+// Command gen generate models.go and operations.go of package client from openapi/openapi.yaml, run by go generate in client
+//
+//	go run ./internal/gen -out .
+//
+// Schemas of objects become structs, refs to other schemas are inlined. Every operation become method of Client named
+// after operationId with path parameters as arguments, then request body, then <Method>Params with query parameters.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"rangkingserver/client/internal/spec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// handWritten are schemas written by hand in client.go
+const handWritten = "Error,ErrorBody"
+
+const header = "// Code generated by client/internal/gen from openapi/openapi.yaml. DO NOT EDIT.\n\npackage client\n\n"
+
+// initialisms are words written in upper case in Go names
+var initialisms = map[string]string{
+	"db":      "DB",
+	"http":    "HTTP",
+	"id":      "ID",
+	"ms":      "MS",
+	"openapi": "OpenAPI",
+	"uid":     "UID",
+	"uids":    "UIDs",
+	"url":     "URL",
+}
+
+func main() {
+	out := flag.String("out", ".", "directory of package client")
+	skip := flag.String("skip", handWritten, "comma separated schemas written by hand in package client")
+	flag.Parse()
+
+	doc, err := spec.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	files, err := Generate(doc, strings.Split(*skip, ","))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(*out, name), content, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// Generate source of models.go and operations.go by file name, skip are schemas not generated
+func Generate(doc *spec.Document, skip []string) (map[string][]byte, error) {
+	g := &generator{doc: doc, skip: make(map[string]bool), methods: make(map[string]string)}
+	for _, name := range skip {
+		g.skip[name] = true
+	}
+	for _, operation := range doc.Operations() {
+		g.methods[operation.OperationID] = goName(operation.OperationID)
+	}
+
+	models, err := g.models()
+	if err != nil {
+		return nil, err
+	}
+	operations, err := g.operations()
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{"models.go": models, "operations.go": operations}, nil
+}
+
+type generator struct {
+	doc  *spec.Document
+	skip map[string]bool
+	// methods is Go method name of operationId
+	methods map[string]string
+}
+
+// models write struct of every object schema
+func (g *generator) models() ([]byte, error) {
+	var body bytes.Buffer
+	for _, name := range g.doc.Components.Schemas.Keys {
+		schema := g.doc.Components.Schemas.Values[name]
+		if g.skip[name] || !isStruct(schema) {
+			continue
+		}
+		g.comment(&body, name, "is", schema.Description)
+		fmt.Fprintf(&body, "type %s struct {\n", name)
+		if err := g.fields(&body, name, schema); err != nil {
+			return nil, err
+		}
+		body.WriteString("}\n\n")
+	}
+	return source(body.Bytes(), map[string]string{"time": "time", "json": "encoding/json"})
+}
+
+// fields write fields of properties, allOf refs are embedded
+func (g *generator) fields(body *bytes.Buffer, name string, schema *spec.Schema) error {
+	for _, part := range schema.AllOf {
+		if part.Ref != "" {
+			fmt.Fprintf(body, "%s\n", spec.RefName(part.Ref))
+			continue
+		}
+		if err := g.fields(body, name, part); err != nil {
+			return err
+		}
+	}
+	required := make(map[string]bool)
+	for _, property := range schema.Required {
+		required[property] = true
+	}
+	for _, property := range schema.Properties.Keys {
+		propertySchema := schema.Properties.Values[property]
+		goType, err := g.goType(propertySchema, required[property])
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", name, property, err)
+		}
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+		}
+		field := goName(property)
+		g.comment(body, field, "is", propertySchema.Description)
+		fmt.Fprintf(body, "%s %s `json:\"%s\"`\n", field, goType, tag)
+	}
+	return nil
+}
+
+// operations write method of Client and params of every operation
+func (g *generator) operations() ([]byte, error) {
+	var body bytes.Buffer
+	for _, operation := range g.doc.Operations() {
+		if err := g.operation(&body, operation); err != nil {
+			return nil, fmt.Errorf("%s: %v", operation.OperationID, err)
+		}
+	}
+	return source(body.Bytes(), map[string]string{
+		"context": "context",
+		"fmt":     "fmt",
+		"http":    "net/http",
+		"url":     "net/url",
+		"strconv": "strconv",
+		"strings": "strings",
+		"time":    "time",
+	})
+}
+
+func (g *generator) operation(body *bytes.Buffer, operation *spec.Operation) error {
+	name := g.methods[operation.OperationID]
+	args := []string{"ctx context.Context"}
+	pathArgs := make(map[string]string)
+	var query []*spec.Parameter
+	for _, parameter := range operation.Parameters {
+		switch parameter.In {
+		case "path":
+			goType, err := g.goType(parameter.Schema, true)
+			if err != nil {
+				return err
+			}
+			arg := argName(parameterName(parameter))
+			args = append(args, arg+" "+goType)
+			pathArgs[parameter.Name] = pathValue(arg, goType)
+		case "query":
+			query = append(query, parameter)
+		default:
+			return fmt.Errorf("parameter %s in %s is not supported", parameter.Name, parameter.In)
+		}
+	}
+	bodyArg := "nil"
+	if operation.RequestBody != nil {
+		schema := spec.JSONSchema(operation.RequestBody.Content)
+		if schema == nil {
+			return fmt.Errorf("request body is not application/json")
+		}
+		goType, err := g.goType(schema, true)
+		if err != nil {
+			return err
+		}
+		args = append(args, "body "+goType)
+		bodyArg = "body"
+	}
+	queryArg := "nil"
+	if len(query) > 0 {
+		paramsType := name + "Params"
+		if err := g.params(body, paramsType, name, query); err != nil {
+			return err
+		}
+		args = append(args, "params "+paramsType)
+		queryArg = "query"
+	}
+
+	result := ""
+	if response, ok := operation.Responses["200"]; ok && len(response.Content) > 0 {
+		result = "string"
+		if schema := spec.JSONSchema(response.Content); schema != nil {
+			goType, err := g.goType(schema, true)
+			if err != nil {
+				return err
+			}
+			result = goType
+		}
+	}
+
+	path, err := pathExpression(operation.Path, pathArgs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(body, "// %s call %s %s, %s\n", name, operation.Method, operation.Path, lowerFirst(g.text(operation.Summary)))
+	if operation.Deprecated {
+		fmt.Fprintf(body, "//\n// Deprecated: %s\n", g.text(deprecation(operation.Summary)))
+	}
+	if result == "" {
+		fmt.Fprintf(body, "func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(body, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+	}
+	if len(query) > 0 {
+		body.WriteString("query := url.Values{}\n")
+		for _, parameter := range query {
+			if err := g.setQuery(body, parameter); err != nil {
+				return err
+			}
+		}
+	}
+	method := "http.Method" + operation.Method[:1] + strings.ToLower(operation.Method[1:])
+	if result == "" {
+		fmt.Fprintf(body, "return c.do(ctx, %s, %s, %s, %s, nil)\n}\n\n", method, path, queryArg, bodyArg)
+		return nil
+	}
+	fmt.Fprintf(body, "var out %s\n", result)
+	fmt.Fprintf(body, "err := c.do(ctx, %s, %s, %s, %s, &out)\n", method, path, queryArg, bodyArg)
+	body.WriteString("return out, err\n}\n\n")
+	return nil
+}
+
+// params write struct of query parameters of method
+func (g *generator) params(body *bytes.Buffer, paramsType string, method string, query []*spec.Parameter) error {
+	optional := true
+	for _, parameter := range query {
+		optional = optional && !parameter.Required
+	}
+	if optional {
+		fmt.Fprintf(body, "// %s is query of %s, zero value use server default\n", paramsType, method)
+	} else {
+		fmt.Fprintf(body, "// %s is query of %s\n", paramsType, method)
+	}
+	fmt.Fprintf(body, "type %s struct {\n", paramsType)
+	for _, parameter := range query {
+		goType, err := g.goType(parameter.Schema, true)
+		if err != nil {
+			return err
+		}
+		field := parameterName(parameter)
+		g.comment(body, field, "is", parameter.Description)
+		fmt.Fprintf(body, "%s %s\n", field, goType)
+	}
+	body.WriteString("}\n\n")
+	return nil
+}
+
+// setQuery write code that put field of params to query, optional parameters are left out when zero
+func (g *generator) setQuery(body *bytes.Buffer, parameter *spec.Parameter) error {
+	field := "params." + parameterName(parameter)
+	schema := g.doc.Resolve(parameter.Schema)
+	explode := parameter.Explode == nil || *parameter.Explode
+	switch schema.Type {
+	case "object":
+		if !explode {
+			return fmt.Errorf("query object %s must be exploded", parameter.Name)
+		}
+		fmt.Fprintf(body, "for name, value := range %s {\nquery.Set(name, value)\n}\n", field)
+		return nil
+	case "array":
+		if explode {
+			fmt.Fprintf(body, "for _, value := range %s {\nquery.Add(%q, value)\n}\n", field, parameter.Name)
+			return nil
+		}
+		set := fmt.Sprintf("query.Set(%q, strings.Join(%s, \",\"))\n", parameter.Name, field)
+		if parameter.Required {
+			body.WriteString(set)
+		} else {
+			fmt.Fprintf(body, "if %s != nil {\n%s}\n", field, set)
+		}
+		return nil
+	}
+	goType, err := g.goType(schema, true)
+	if err != nil {
+		return err
+	}
+	set := fmt.Sprintf("query.Set(%q, %s)\n", parameter.Name, pathValue(field, goType))
+	if parameter.Required {
+		body.WriteString(set)
+		return nil
+	}
+	zero := "0"
+	if goType == "string" {
+		zero = `""`
+	}
+	fmt.Fprintf(body, "if %s != %s {\n%s}\n", field, zero, set)
+	return nil
+}
+
+// goType is Go type of schema, optional date-time and objects are pointers
+func (g *generator) goType(schema *spec.Schema, required bool) (string, error) {
+	if schema == nil {
+		return "", fmt.Errorf("missing schema")
+	}
+	if schema.Ref != "" {
+		name := spec.RefName(schema.Ref)
+		resolved := g.doc.Resolve(schema)
+		if resolved == nil {
+			return "", fmt.Errorf("unknown schema %s", schema.Ref)
+		}
+		if !isStruct(resolved) {
+			return g.goType(resolved, required)
+		}
+		if !required {
+			return "*" + name, nil
+		}
+		return name, nil
+	}
+	if len(schema.OneOf) > 0 {
+		return "json.RawMessage", nil
+	}
+	switch schema.Type {
+	case "string":
+		if schema.Format != "date-time" {
+			return "string", nil
+		}
+		if !required {
+			return "*time.Time", nil
+		}
+		return "time.Time", nil
+	case "integer":
+		switch schema.Format {
+		case "int64", "uint64", "uint32":
+			return schema.Format, nil
+		case "":
+			return "int", nil
+		}
+		return "", fmt.Errorf("integer format %s is not supported", schema.Format)
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		item, err := g.goType(schema.Items, true)
+		return "[]" + item, err
+	case "object":
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil && len(schema.Properties.Keys) == 0 {
+			value, err := g.goType(schema.AdditionalProperties.Schema, true)
+			return "map[string]" + value, err
+		}
+	}
+	return "", fmt.Errorf("schema of type %q is not supported inline", schema.Type)
+}
+
+// comment write doc comment "name verb description", nothing when description is empty
+func (g *generator) comment(body *bytes.Buffer, name string, verb string, description string) {
+	if description == "" {
+		return
+	}
+	words := []string{name}
+	if verb != "" {
+		words = append(words, verb)
+	}
+	words = append(words, lowerFirst(g.text(description)))
+	fmt.Fprintf(body, "// %s\n", strings.Join(words, " "))
+}
+
+// operationIDPattern match camelCase words, single lower case words such as status are left as they are
+var operationIDPattern = regexp.MustCompile(`\b[a-z]+[A-Z][A-Za-z]*\b`)
+
+// text replace operationIds in description with Go method names
+func (g *generator) text(description string) string {
+	return operationIDPattern.ReplaceAllStringFunc(strings.TrimSpace(description), func(word string) string {
+		if method, ok := g.methods[word]; ok {
+			return method
+		}
+		return word
+	})
+}
+
+// deprecation is advice of deprecated operation, part of summary from its last "use"
+func deprecation(summary string) string {
+	if index := strings.LastIndex(summary, "use "); index >= 0 {
+		return summary[index:]
+	}
+	return "kept for old clients"
+}
+
+// isStruct report whether schema is generated as struct
+func isStruct(schema *spec.Schema) bool {
+	return len(schema.AllOf) > 0 || (schema.Type == "object" && len(schema.Properties.Keys) > 0)
+}
+
+// parameterName is Go name of parameter, x-go-name when set
+func parameterName(parameter *spec.Parameter) string {
+	if parameter.GoName != "" {
+		return parameter.GoName
+	}
+	return goName(parameter.Name)
+}
+
+// pathValue is string expression of value of Go type
+func pathValue(value string, goType string) string {
+	switch goType {
+	case "string":
+		return value
+	case "int64":
+		return "strconv.FormatInt(" + value + ", 10)"
+	case "int":
+		return "strconv.Itoa(" + value + ")"
+	}
+	return "fmt.Sprint(" + value + ")"
+}
+
+// pathExpression is Go expression of path template with escaped arguments
+func pathExpression(template string, args map[string]string) (string, error) {
+	var parts []string
+	rest := template
+	for rest != "" {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest))
+			break
+		}
+		end := strings.Index(rest, "}")
+		if end < start {
+			return "", fmt.Errorf("bad path %s", template)
+		}
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest[:start]))
+		}
+		name := rest[start+1 : end]
+		value, ok := args[name]
+		if !ok {
+			return "", fmt.Errorf("path parameter %s is not declared", name)
+		}
+		if strings.HasPrefix(value, "strconv.") {
+			parts = append(parts, value)
+		} else {
+			parts = append(parts, "url.PathEscape("+value+")")
+		}
+		rest = rest[end+1:]
+	}
+	return strings.Join(parts, "+"), nil
+}
+
+// goName is exported Go name of snake_case or camelCase name
+func goName(name string) string {
+	var words []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		start := 0
+		for index := 1; index < len(part); index++ {
+			if part[index] >= 'A' && part[index] <= 'Z' && part[index-1] >= 'a' && part[index-1] <= 'z' {
+				words = append(words, part[start:index])
+				start = index
+			}
+		}
+		words = append(words, part[start:])
+	}
+	for index, word := range words {
+		if initialism, ok := initialisms[strings.ToLower(word)]; ok {
+			words[index] = initialism
+			continue
+		}
+		words[index] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, "")
+}
+
+// argName is unexported form of Go name, leading initialism is lower cased, LeaderboardID is leaderboardID and UID is uid
+func argName(name string) string {
+	upper := 0
+	for upper < len(name) && name[upper] >= 'A' && name[upper] <= 'Z' {
+		upper++
+	}
+	if upper > 1 && upper < len(name) {
+		upper--
+	}
+	return strings.ToLower(name[:upper]) + name[upper:]
+}
+
+// lowerFirst lower case first letter of sentence unless it start word in upper case such as DB
+func lowerFirst(text string) string {
+	if len(text) > 1 && text[1] >= 'A' && text[1] <= 'Z' {
+		return text
+	}
+	return strings.ToLower(text[:1]) + text[1:]
+}
+
+// source add header and imports of packages by name that body use and format it
+func source(body []byte, packages map[string]string) ([]byte, error) {
+	var imports []string
+	for name, path := range packages {
+		if regexp.MustCompile(`(^|[^\w.])` + name + `\.[A-Z]`).Match(body) {
+			imports = append(imports, path)
+		}
+	}
+	sort.Strings(imports)
+	var file bytes.Buffer
+	file.WriteString(header)
+	if len(imports) > 0 {
+		file.WriteString("import (\n")
+		for _, path := range imports {
+			fmt.Fprintf(&file, "%q\n", path)
+		}
+		file.WriteString(")\n\n")
+	}
+	file.Write(body)
+	formatted, err := format.Source(file.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%v\n%s", err, file.Bytes())
+	}
+	return formatted, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"rangkingserver/client/internal/spec"
+	"strings"
+	"testing"
+)
+
+// TestGeneratedFilesUpToDate fail when openapi.yaml or generator changed without go generate ./client
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	doc, err := spec.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(doc, strings.Split(handWritten, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		current, err := ioutil.ReadFile(filepath.Join("..", "..", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(current, content) {
+			t.Errorf("client/%s is not generated from openapi/openapi.yaml, run go generate ./client", name)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"leaderboard_id":    "LeaderboardID",
+		"uid":               "UID",
+		"uids":              "UIDs",
+		"latency_ms":        "LatencyMS",
+		"isServerRequest":   "IsServerRequest",
+		"getRankingByEvent": "GetRankingByEvent",
+		"openapi":           "OpenAPI",
+	} {
+		if got := goName(name); got != want {
+			t.Errorf("goName(%q) = %q, want %q", name, got, want)
+		}
+	}
+	for name, want := range map[string]string{
+		"LeaderboardID": "leaderboardID",
+		"UID":           "uid",
+		"Season":        "season",
+	} {
+		if got := argName(name); got != want {
+			t.Errorf("argName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPathExpression(t *testing.T) {
+	got, err := pathExpression("/v1/leaderboards/{id}/seasons/{season}/entries", map[string]string{
+		"id":     "leaderboardID",
+		"season": "strconv.FormatInt(season, 10)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/seasons/"+strconv.FormatInt(season, 10)+"/entries"`
+	if got != want {
+		t.Errorf("pathExpression = %s, want %s", got, want)
+	}
+	if _, err := pathExpression("/v1/users/{uid}", nil); err == nil {
+		t.Error("undeclared path parameter is accepted")
+	}
+}
@@ -0,0 +1,282 @@
+// Package spec read openapi/openapi.yaml for client generator and contract tests of package client
+package spec
+
+import (
+	"fmt"
+	"rangkingserver/openapi"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Document is OpenAPI 3 document, refs of parameters and responses are resolved, refs of schemas are kept
+type Document struct {
+	Paths      Paths      `yaml:"paths"`
+	Components Components `yaml:"components"`
+}
+
+// Components are reusable objects of document
+type Components struct {
+	Parameters map[string]*Parameter `yaml:"parameters"`
+	Responses  map[string]*Response  `yaml:"responses"`
+	Schemas    Schemas               `yaml:"schemas"`
+}
+
+// Paths are path items in document order
+type Paths struct {
+	Keys   []string
+	Values map[string]*PathItem
+}
+
+// UnmarshalYAML read paths in document order
+func (p *Paths) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := mappingKeys(unmarshal)
+	p.Keys = keys
+	if err != nil {
+		return err
+	}
+	return unmarshal(&p.Values)
+}
+
+// PathItem is operations of path by lowercase http method in document order
+type PathItem struct {
+	Keys   []string
+	Values map[string]*Operation
+}
+
+// UnmarshalYAML read operations in document order
+func (p *PathItem) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := mappingKeys(unmarshal)
+	p.Keys = keys
+	if err != nil {
+		return err
+	}
+	return unmarshal(&p.Values)
+}
+
+// Operation is one http method of path, Method and Path are set by Parse
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Description string               `yaml:"description"`
+	Deprecated  bool                 `yaml:"deprecated"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+
+	Method string `yaml:"-"`
+	Path   string `yaml:"-"`
+}
+
+// Parameter is path or query parameter, GoName is x-go-name when name is not a good Go name
+type Parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	GoName      string  `yaml:"x-go-name"`
+	In          string  `yaml:"in"`
+	Required    bool    `yaml:"required"`
+	Description string  `yaml:"description"`
+	Style       string  `yaml:"style"`
+	Explode     *bool   `yaml:"explode"`
+	Schema      *Schema `yaml:"schema"`
+}
+
+// RequestBody is body of operation by media type
+type RequestBody struct {
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+// Response is response of operation by media type
+type Response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+// MediaType is schema of body
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema is subset of JSON schema used by document
+type Schema struct {
+	Ref                  string      `yaml:"$ref"`
+	Type                 string      `yaml:"type"`
+	Format               string      `yaml:"format"`
+	Description          string      `yaml:"description"`
+	Enum                 []string    `yaml:"enum"`
+	Required             []string    `yaml:"required"`
+	Properties           Schemas     `yaml:"properties"`
+	AdditionalProperties *Additional `yaml:"additionalProperties"`
+	Items                *Schema     `yaml:"items"`
+	AllOf                []*Schema   `yaml:"allOf"`
+	OneOf                []*Schema   `yaml:"oneOf"`
+	Nullable             bool        `yaml:"nullable"`
+	Pattern              string      `yaml:"pattern"`
+	MinLength            *int        `yaml:"minLength"`
+	MaxLength            *int        `yaml:"maxLength"`
+	MinItems             *int        `yaml:"minItems"`
+	MaxItems             *int        `yaml:"maxItems"`
+	Minimum              *float64    `yaml:"minimum"`
+	Maximum              *float64    `yaml:"maximum"`
+}
+
+// Additional is additionalProperties of object, Schema of values or Forbidden for false
+type Additional struct {
+	Forbidden bool
+	Schema    *Schema
+}
+
+// UnmarshalYAML accept boolean or schema
+func (a *Additional) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var allowed bool
+	if err := unmarshal(&allowed); err == nil {
+		a.Forbidden = !allowed
+		return nil
+	}
+	return unmarshal(&a.Schema)
+}
+
+// Schemas are schemas by name in document order
+type Schemas struct {
+	Keys   []string
+	Values map[string]*Schema
+}
+
+// UnmarshalYAML read schemas in document order
+func (s *Schemas) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := mappingKeys(unmarshal)
+	s.Keys = keys
+	if err != nil {
+		return err
+	}
+	return unmarshal(&s.Values)
+}
+
+// mappingKeys get keys of yaml mapping in document order
+func mappingKeys(unmarshal func(interface{}) error) ([]string, error) {
+	var slice yaml.MapSlice
+	if err := unmarshal(&slice); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(slice))
+	for _, item := range slice {
+		keys = append(keys, fmt.Sprint(item.Key))
+	}
+	return keys, nil
+}
+
+// Load parse openapi.Spec
+func Load() (*Document, error) {
+	return Parse(openapi.Spec)
+}
+
+// Parse parse document and resolve refs of parameters and responses
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for _, path := range doc.Paths.Keys {
+		item := doc.Paths.Values[path]
+		for _, method := range item.Keys {
+			operation := item.Values[method]
+			operation.Method = strings.ToUpper(method)
+			operation.Path = path
+			for index, parameter := range operation.Parameters {
+				if parameter.Ref == "" {
+					continue
+				}
+				resolved, ok := doc.Components.Parameters[RefName(parameter.Ref)]
+				if !ok {
+					return nil, fmt.Errorf("%s: unknown parameter %s", operation.OperationID, parameter.Ref)
+				}
+				operation.Parameters[index] = resolved
+			}
+			for status, response := range operation.Responses {
+				if response.Ref == "" {
+					continue
+				}
+				resolved, ok := doc.Components.Responses[RefName(response.Ref)]
+				if !ok {
+					return nil, fmt.Errorf("%s: unknown response %s", operation.OperationID, response.Ref)
+				}
+				operation.Responses[status] = resolved
+			}
+		}
+	}
+	return &doc, nil
+}
+
+// RefName is name of component of ref, #/components/schemas/Entry is Entry
+func RefName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// Operations of every path in document order
+func (d *Document) Operations() []*Operation {
+	var operations []*Operation
+	for _, path := range d.Paths.Keys {
+		item := d.Paths.Values[path]
+		for _, method := range item.Keys {
+			operations = append(operations, item.Values[method])
+		}
+	}
+	return operations
+}
+
+// Resolve follow ref of schema to component schema
+func (d *Document) Resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas.Values[RefName(schema.Ref)]
+	}
+	return schema
+}
+
+// Find operation of method and request path, path parameters are returned by name, literal segments win over parameters
+func (d *Document) Find(method string, path string) (*Operation, map[string]string) {
+	var found *Operation
+	var foundParams map[string]string
+	for _, operation := range d.Operations() {
+		if operation.Method != method {
+			continue
+		}
+		params, ok := MatchPath(operation.Path, path)
+		if ok && (found == nil || len(params) < len(foundParams)) {
+			found, foundParams = operation, params
+		}
+	}
+	return found, foundParams
+}
+
+// MatchPath match path with template such as /v1/leaderboards/{id}/scores, path is not escaped
+func MatchPath(template string, path string) (map[string]string, bool) {
+	templateSegments := strings.Split(template, "/")
+	segments := strings.Split(path, "/")
+	if len(templateSegments) != len(segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for index, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[index] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[index]
+			continue
+		}
+		if segment != segments[index] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// JSONSchema is schema of application/json content, nil when content has none
+func JSONSchema(content map[string]*MediaType) *Schema {
+	if media, ok := content["application/json"]; ok {
+		return media.Schema
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package spec
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate check decoded JSON value against schema, error name the path of first invalid value
+func (d *Document) Validate(schema *Schema, value interface{}) error {
+	return d.validate("$", schema, value)
+}
+
+func (d *Document) validate(at string, schema *Schema, value interface{}) error {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: is null", at)
+	}
+	for _, part := range schema.AllOf {
+		if err := d.validate(at, part, value); err != nil {
+			return err
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		var errs []string
+		for _, option := range schema.OneOf {
+			err := d.validate(at, option, value)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err.Error())
+		}
+		if errs != nil {
+			return fmt.Errorf("%s: match no schema of oneOf: %s", at, strings.Join(errs, "; "))
+		}
+	}
+	if len(schema.Enum) > 0 {
+		text := fmt.Sprint(value)
+		found := false
+		for _, option := range schema.Enum {
+			found = found || option == text
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %s", at, value, strings.Join(schema.Enum, ", "))
+		}
+	}
+
+	switch schema.Type {
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: %v is not string", at, value)
+		}
+		return checkString(at, schema, text)
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Errorf("%s: %v is not integer", at, value)
+		}
+		if schema.Format == "uint32" || schema.Format == "uint64" {
+			if number < 0 {
+				return fmt.Errorf("%s: %v is negative", at, value)
+			}
+		}
+		return checkNumber(at, schema, number)
+	case "number":
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: %v is not number", at, value)
+		}
+		return checkNumber(at, schema, number)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: %v is not boolean", at, value)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not array", at, value)
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			return fmt.Errorf("%s: has %d items, want at least %d", at, len(items), *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			return fmt.Errorf("%s: has %d items, want at most %d", at, len(items), *schema.MaxItems)
+		}
+		for index, item := range items {
+			if err := d.validate(fmt.Sprintf("%s[%d]", at, index), schema.Items, item); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not object", at, value)
+		}
+		return d.validateObject(at, schema, object)
+	}
+	return nil
+}
+
+func (d *Document) validateObject(at string, schema *Schema, object map[string]interface{}) error {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: required property %s is missing", at, name)
+		}
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := schema.Properties.Values[name]; ok {
+			if err := d.validate(at+"."+name, property, object[name]); err != nil {
+				return err
+			}
+			continue
+		}
+		if schema.AdditionalProperties == nil {
+			continue
+		}
+		if schema.AdditionalProperties.Forbidden {
+			return fmt.Errorf("%s: unknown property %s", at, name)
+		}
+		if err := d.validate(at+"."+name, schema.AdditionalProperties.Schema, object[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkString(at string, schema *Schema, text string) error {
+	length := len([]rune(text))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		return fmt.Errorf("%s: %q is shorter than %d", at, text, *schema.MinLength)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return fmt.Errorf("%s: %q is longer than %d", at, text, *schema.MaxLength)
+	}
+	if schema.Pattern != "" {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: pattern %s: %v", at, schema.Pattern, err)
+		}
+		if !pattern.MatchString(text) {
+			return fmt.Errorf("%s: %q does not match %s", at, text, schema.Pattern)
+		}
+	}
+	switch schema.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+			return fmt.Errorf("%s: %q is not RFC 3339 time", at, text)
+		}
+	case "uri":
+		if parsed, err := url.Parse(text); err != nil || !parsed.IsAbs() {
+			return fmt.Errorf("%s: %q is not absolute uri", at, text)
+		}
+	}
+	return nil
+}
+
+func checkNumber(at string, schema *Schema, number float64) error {
+	if schema.Minimum != nil && number < *schema.Minimum {
+		return fmt.Errorf("%s: %v is below %v", at, number, *schema.Minimum)
+	}
+	if schema.Maximum != nil && number > *schema.Maximum {
+		return fmt.Errorf("%s: %v is above %v", at, number, *schema.Maximum)
+	}
+	return nil
+}
+
+// ValidateQuery check query of request against query parameters of operation,
+// names that are not parameters are only allowed when operation take exploded object such as dimensions
+func (d *Document) ValidateQuery(operation *Operation, query url.Values) error {
+	known := make(map[string]bool)
+	exploded := false
+	for _, parameter := range operation.Parameters {
+		if parameter.In != "query" {
+			continue
+		}
+		schema := d.Resolve(parameter.Schema)
+		if schema.Type == "object" {
+			exploded = true
+			continue
+		}
+		known[parameter.Name] = true
+		values, ok := query[parameter.Name]
+		if !ok {
+			if parameter.Required {
+				return fmt.Errorf("query: required parameter %s is missing", parameter.Name)
+			}
+			continue
+		}
+		if len(values) != 1 {
+			return fmt.Errorf("query: parameter %s is repeated", parameter.Name)
+		}
+		if err := d.validate("query."+parameter.Name, schema, queryValue(schema, values[0])); err != nil {
+			return err
+		}
+	}
+	for name := range query {
+		if !known[name] && !exploded {
+			return fmt.Errorf("query: unknown parameter %s", name)
+		}
+	}
+	return nil
+}
+
+// ValidatePath check path parameters of request against path parameters of operation
+func (d *Document) ValidatePath(operation *Operation, params map[string]string) error {
+	for _, parameter := range operation.Parameters {
+		if parameter.In != "path" {
+			continue
+		}
+		value, ok := params[parameter.Name]
+		if !ok {
+			return fmt.Errorf("path: parameter %s is not in %s", parameter.Name, operation.Path)
+		}
+		schema := d.Resolve(parameter.Schema)
+		if err := d.validate("path."+parameter.Name, schema, queryValue(schema, value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryValue convert text of parameter to JSON value of its schema, text stay string when it is not valid for schema
+func queryValue(schema *Schema, text string) interface{} {
+	switch schema.Type {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(text, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if value, err := strconv.ParseBool(text); err == nil {
+			return value
+		}
+	case "array":
+		items := make([]interface{}, 0)
+		for _, item := range strings.Split(text, ",") {
+			items = append(items, item)
+		}
+		return items
+	}
+	return text
+}
+
+// Sample build JSON value of schema with every property set to non zero value, for fake responses of contract tests
+func (d *Document) Sample(schema *Schema) interface{} {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, part := range schema.AllOf {
+			for name, value := range d.Sample(part).(map[string]interface{}) {
+				merged[name] = value
+			}
+		}
+		return merged
+	}
+	if len(schema.OneOf) > 0 {
+		return d.Sample(schema.OneOf[0])
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	switch schema.Type {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "2024-01-02T03:04:05Z"
+		case "uri":
+			return "https://example.com/hook"
+		}
+		return "s"
+	case "integer":
+		if schema.Minimum != nil && *schema.Minimum > 2 {
+			return *schema.Minimum
+		}
+		return float64(2)
+	case "number":
+		return 2.5
+	case "boolean":
+		return true
+	case "array":
+		return []interface{}{d.Sample(schema.Items)}
+	}
+	object := make(map[string]interface{})
+	for _, name := range schema.Properties.Keys {
+		object[name] = d.Sample(schema.Properties.Values[name])
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		object["key"] = d.Sample(schema.AdditionalProperties.Schema)
+	}
+	return object
+}
@@ -0,0 +1,488 @@
+// Code generated by client/internal/gen from openapi/openapi.yaml. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ClearLeaderboardsParams is query of ClearLeaderboards
+type ClearLeaderboardsParams struct {
+	// Period is event_ranking_key or world_ranking_key of config
+	Period string
+}
+
+// ClearLeaderboards call DELETE /v1/leaderboards, clear every leaderboard of period
+func (c *Client) ClearLeaderboards(ctx context.Context, params ClearLeaderboardsParams) (ClearResult, error) {
+	query := url.Values{}
+	query.Set("period", params.Period)
+	var out ClearResult
+	err := c.do(ctx, http.MethodDelete, "/v1/leaderboards", query, nil, &out)
+	return out, err
+}
+
+// GetSeasons call GET /v1/leaderboards/seasons, current season of event period and archived seasons that can be read
+func (c *Client) GetSeasons(ctx context.Context) (SeasonList, error) {
+	var out SeasonList
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/seasons", nil, nil, &out)
+	return out, err
+}
+
+// EndSeason call POST /v1/leaderboards/seasons, end season, archive every leaderboard of event period and start new season with carried over scores
+func (c *Client) EndSeason(ctx context.Context) (SeasonResult, error) {
+	var out SeasonResult
+	err := c.do(ctx, http.MethodPost, "/v1/leaderboards/seasons", nil, nil, &out)
+	return out, err
+}
+
+// GetSeasonEntriesParams is query of GetSeasonEntries, zero value use server default
+type GetSeasonEntriesParams struct {
+	// Limit is number of entries, default is leaderboard limit of config
+	Limit int64
+	// Dimensions is dimension values of configured slice such as game_mode=1&sub_title=2, none is whole leaderboard
+	Dimensions map[string]string
+}
+
+// GetSeasonEntries call GET /v1/leaderboards/{id}/seasons/{season}/entries, top entries of leaderboard archived at end of season
+func (c *Client) GetSeasonEntries(ctx context.Context, leaderboardID string, season int64, params GetSeasonEntriesParams) (EntryList, error) {
+	query := url.Values{}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(params.Limit, 10))
+	}
+	for name, value := range params.Dimensions {
+		query.Set(name, value)
+	}
+	var out EntryList
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/seasons/"+strconv.FormatInt(season, 10)+"/entries", query, nil, &out)
+	return out, err
+}
+
+// SubmitScore call POST /v1/leaderboards/{id}/scores, add amount to score of uid in event period of leaderboard
+func (c *Client) SubmitScore(ctx context.Context, leaderboardID string, body ScoreRequest) (Entry, error) {
+	var out Entry
+	err := c.do(ctx, http.MethodPost, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/scores", nil, body, &out)
+	return out, err
+}
+
+// SubmitMatch call POST /v1/leaderboards/{id}/matches, update elo or glicko-2 ratings of players of match in event period of rating leaderboard and publish them as scores
+func (c *Client) SubmitMatch(ctx context.Context, leaderboardID string, body MatchRequest) (MatchResult, error) {
+	var out MatchResult
+	err := c.do(ctx, http.MethodPost, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/matches", nil, body, &out)
+	return out, err
+}
+
+// GetRatingParams is query of GetRating, zero value use server default
+type GetRatingParams struct {
+	// Period is event_ranking_key or world_ranking_key of config, default event_ranking_key. Entries and friends also accept name of rolling window of config
+	Period string
+	// Dimensions is dimension values of configured slice such as game_mode=1&sub_title=2, none is whole leaderboard
+	Dimensions map[string]string
+}
+
+// GetRating call GET /v1/leaderboards/{id}/ratings/{uid}, rating, deviation and number of matches of uid, 404 when uid has no match or leaderboard has no rating
+func (c *Client) GetRating(ctx context.Context, leaderboardID string, uid string, params GetRatingParams) (PlayerRating, error) {
+	query := url.Values{}
+	if params.Period != "" {
+		query.Set("period", params.Period)
+	}
+	for name, value := range params.Dimensions {
+		query.Set(name, value)
+	}
+	var out PlayerRating
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/ratings/"+url.PathEscape(uid), query, nil, &out)
+	return out, err
+}
+
+// GetMilestones call GET /v1/leaderboards/{id}/milestones/{uid}, milestones of leaderboard and whether uid reached them in current season
+func (c *Client) GetMilestones(ctx context.Context, leaderboardID string, uid string) (MilestoneList, error) {
+	var out MilestoneList
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/milestones/"+url.PathEscape(uid), nil, nil, &out)
+	return out, err
+}
+
+// GetEntriesParams is query of GetEntries, zero value use server default
+type GetEntriesParams struct {
+	// Period is event_ranking_key or world_ranking_key of config, default event_ranking_key. Entries and friends also accept name of rolling window of config
+	Period string
+	// Limit is number of entries, default is leaderboard limit of config
+	Limit int64
+	// Dimensions is dimension values of configured slice such as game_mode=1&sub_title=2, none is whole leaderboard
+	Dimensions map[string]string
+}
+
+// GetEntries call GET /v1/leaderboards/{id}/entries, top entries of leaderboard, highest score first
+func (c *Client) GetEntries(ctx context.Context, leaderboardID string, params GetEntriesParams) (EntryList, error) {
+	query := url.Values{}
+	if params.Period != "" {
+		query.Set("period", params.Period)
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(params.Limit, 10))
+	}
+	for name, value := range params.Dimensions {
+		query.Set(name, value)
+	}
+	var out EntryList
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/entries", query, nil, &out)
+	return out, err
+}
+
+// GetEntryParams is query of GetEntry, zero value use server default
+type GetEntryParams struct {
+	// Period is event_ranking_key or world_ranking_key of config, default event_ranking_key. Entries and friends also accept name of rolling window of config
+	Period string
+	// Dimensions is dimension values of configured slice such as game_mode=1&sub_title=2, none is whole leaderboard
+	Dimensions map[string]string
+}
+
+// GetEntry call GET /v1/leaderboards/{id}/entries/{uid}, rank and score of uid
+func (c *Client) GetEntry(ctx context.Context, leaderboardID string, uid string, params GetEntryParams) (Entry, error) {
+	query := url.Values{}
+	if params.Period != "" {
+		query.Set("period", params.Period)
+	}
+	for name, value := range params.Dimensions {
+		query.Set(name, value)
+	}
+	var out Entry
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/entries/"+url.PathEscape(uid), query, nil, &out)
+	return out, err
+}
+
+// GetFriendEntriesParams is query of GetFriendEntries, zero value use server default
+type GetFriendEntriesParams struct {
+	// Period is event_ranking_key or world_ranking_key of config, default event_ranking_key. Entries and friends also accept name of rolling window of config
+	Period string
+	// Friends is comma separated uids to rank uid among, friend set of uid when absent
+	Friends []string
+	// Dimensions is dimension values of configured slice such as game_mode=1&sub_title=2, none is whole leaderboard
+	Dimensions map[string]string
+}
+
+// GetFriendEntries call GET /v1/leaderboards/{id}/friends/{uid}, rank uid and friends by score, uid without score are left out
+func (c *Client) GetFriendEntries(ctx context.Context, leaderboardID string, uid string, params GetFriendEntriesParams) (EntryList, error) {
+	query := url.Values{}
+	if params.Period != "" {
+		query.Set("period", params.Period)
+	}
+	if params.Friends != nil {
+		query.Set("friends", strings.Join(params.Friends, ","))
+	}
+	for name, value := range params.Dimensions {
+		query.Set(name, value)
+	}
+	var out EntryList
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/friends/"+url.PathEscape(uid), query, nil, &out)
+	return out, err
+}
+
+// GetTeamsParams is query of GetTeams, zero value use server default
+type GetTeamsParams struct {
+	// Period is event_ranking_key or world_ranking_key of config, default event_ranking_key. Entries and friends also accept name of rolling window of config
+	Period string
+	// Limit is number of teams, default is leaderboard limit of config
+	Limit int64
+	// Dimensions is dimension values of configured slice such as game_mode=1&sub_title=2, none is whole leaderboard
+	Dimensions map[string]string
+}
+
+// GetTeams call GET /v1/leaderboards/{id}/teams, top teams of team leaderboard, enabled by leaderboard.teams.enabled
+func (c *Client) GetTeams(ctx context.Context, leaderboardID string, params GetTeamsParams) (TeamEntryList, error) {
+	query := url.Values{}
+	if params.Period != "" {
+		query.Set("period", params.Period)
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(params.Limit, 10))
+	}
+	for name, value := range params.Dimensions {
+		query.Set(name, value)
+	}
+	var out TeamEntryList
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/teams", query, nil, &out)
+	return out, err
+}
+
+// GetTeamParams is query of GetTeam, zero value use server default
+type GetTeamParams struct {
+	// Period is event_ranking_key or world_ranking_key of config, default event_ranking_key. Entries and friends also accept name of rolling window of config
+	Period string
+	// Dimensions is dimension values of configured slice such as game_mode=1&sub_title=2, none is whole leaderboard
+	Dimensions map[string]string
+}
+
+// GetTeam call GET /v1/leaderboards/{id}/teams/{team}, rank and score of team with contribution of members, highest contribution first
+func (c *Client) GetTeam(ctx context.Context, leaderboardID string, team string, params GetTeamParams) (TeamBreakdown, error) {
+	query := url.Values{}
+	if params.Period != "" {
+		query.Set("period", params.Period)
+	}
+	for name, value := range params.Dimensions {
+		query.Set(name, value)
+	}
+	var out TeamBreakdown
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/teams/"+url.PathEscape(team), query, nil, &out)
+	return out, err
+}
+
+// GetLeague call GET /v1/leaderboards/{id}/leagues/{uid}, group of uid in current league season ranked by score, 404 when uid has no score in season or leagues are disabled
+func (c *Client) GetLeague(ctx context.Context, leaderboardID string, uid string) (League, error) {
+	var out League
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/leagues/"+url.PathEscape(uid), nil, nil, &out)
+	return out, err
+}
+
+// GetLeagueHistory call GET /v1/leaderboards/{id}/leagues/{uid}/history, results of uid in ended league seasons, newest first
+func (c *Client) GetLeagueHistory(ctx context.Context, leaderboardID string, uid string) (LeagueHistory, error) {
+	var out LeagueHistory
+	err := c.do(ctx, http.MethodGet, "/v1/leaderboards/"+url.PathEscape(leaderboardID)+"/leagues/"+url.PathEscape(uid)+"/history", nil, nil, &out)
+	return out, err
+}
+
+// GetUserTeam call GET /v1/users/{uid}/team, team of uid
+func (c *Client) GetUserTeam(ctx context.Context, uid string) (TeamMembership, error) {
+	var out TeamMembership
+	err := c.do(ctx, http.MethodGet, "/v1/users/"+url.PathEscape(uid)+"/team", nil, nil, &out)
+	return out, err
+}
+
+// JoinTeam call PUT /v1/users/{uid}/team, move uid to team, score earned before stay with old team
+func (c *Client) JoinTeam(ctx context.Context, uid string, body TeamRequest) (TeamMembership, error) {
+	var out TeamMembership
+	err := c.do(ctx, http.MethodPut, "/v1/users/"+url.PathEscape(uid)+"/team", nil, body, &out)
+	return out, err
+}
+
+// LeaveTeam call DELETE /v1/users/{uid}/team, remove uid from its team
+func (c *Client) LeaveTeam(ctx context.Context, uid string) (TeamMembership, error) {
+	var out TeamMembership
+	err := c.do(ctx, http.MethodDelete, "/v1/users/"+url.PathEscape(uid)+"/team", nil, nil, &out)
+	return out, err
+}
+
+// GetFriends call GET /v1/users/{uid}/friends, friend set of uid
+func (c *Client) GetFriends(ctx context.Context, uid string) (FriendList, error) {
+	var out FriendList
+	err := c.do(ctx, http.MethodGet, "/v1/users/"+url.PathEscape(uid)+"/friends", nil, nil, &out)
+	return out, err
+}
+
+// AddFriends call POST /v1/users/{uid}/friends, add uids to friend set of uid, friend set is one way and has at most 1000 uids
+func (c *Client) AddFriends(ctx context.Context, uid string, body FriendsRequest) (FriendList, error) {
+	var out FriendList
+	err := c.do(ctx, http.MethodPost, "/v1/users/"+url.PathEscape(uid)+"/friends", nil, body, &out)
+	return out, err
+}
+
+// RemoveFriend call DELETE /v1/users/{uid}/friends/{friend}, remove friend from friend set of uid
+func (c *Client) RemoveFriend(ctx context.Context, uid string, friend string) (FriendList, error) {
+	var out FriendList
+	err := c.do(ctx, http.MethodDelete, "/v1/users/"+url.PathEscape(uid)+"/friends/"+url.PathEscape(friend), nil, nil, &out)
+	return out, err
+}
+
+// GetTournamentsParams is query of GetTournaments, zero value use server default
+type GetTournamentsParams struct {
+	Status string
+	Limit  int64
+}
+
+// GetTournaments call GET /v1/tournaments, tournaments, latest start first
+func (c *Client) GetTournaments(ctx context.Context, params GetTournamentsParams) (TournamentList, error) {
+	query := url.Values{}
+	if params.Status != "" {
+		query.Set("status", params.Status)
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(params.Limit, 10))
+	}
+	var out TournamentList
+	err := c.do(ctx, http.MethodGet, "/v1/tournaments", query, nil, &out)
+	return out, err
+}
+
+// CreateTournament call POST /v1/tournaments, create tournament, 409 when id is taken
+func (c *Client) CreateTournament(ctx context.Context, body TournamentRequest) (Tournament, error) {
+	var out Tournament
+	err := c.do(ctx, http.MethodPost, "/v1/tournaments", nil, body, &out)
+	return out, err
+}
+
+// GetTournament call GET /v1/tournaments/{tournament}, tournament with status and number of participants
+func (c *Client) GetTournament(ctx context.Context, tournamentID string) (Tournament, error) {
+	var out Tournament
+	err := c.do(ctx, http.MethodGet, "/v1/tournaments/"+url.PathEscape(tournamentID), nil, nil, &out)
+	return out, err
+}
+
+// RegisterTournament call POST /v1/tournaments/{tournament}/registrations, register uid until registration_end_at, 409 when registration is closed, not required or tournament is full
+func (c *Client) RegisterTournament(ctx context.Context, tournamentID string, body RegistrationRequest) (Registration, error) {
+	var out Registration
+	err := c.do(ctx, http.MethodPost, "/v1/tournaments/"+url.PathEscape(tournamentID)+"/registrations", nil, body, &out)
+	return out, err
+}
+
+// SubmitTournamentScore call POST /v1/tournaments/{tournament}/scores, add amount to score of uid from start_at until end_at, 403 when uid is not registered, 409 outside window or when tournament is full
+func (c *Client) SubmitTournamentScore(ctx context.Context, tournamentID string, body TournamentScoreRequest) (Entry, error) {
+	var out Entry
+	err := c.do(ctx, http.MethodPost, "/v1/tournaments/"+url.PathEscape(tournamentID)+"/scores", nil, body, &out)
+	return out, err
+}
+
+// GetTournamentEntriesParams is query of GetTournamentEntries, zero value use server default
+type GetTournamentEntriesParams struct {
+	Limit int64
+}
+
+// GetTournamentEntries call GET /v1/tournaments/{tournament}/entries, top entries of tournament, final after end_at
+func (c *Client) GetTournamentEntries(ctx context.Context, tournamentID string, params GetTournamentEntriesParams) (TournamentEntryList, error) {
+	query := url.Values{}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(params.Limit, 10))
+	}
+	var out TournamentEntryList
+	err := c.do(ctx, http.MethodGet, "/v1/tournaments/"+url.PathEscape(tournamentID)+"/entries", query, nil, &out)
+	return out, err
+}
+
+// GetTournamentEntry call GET /v1/tournaments/{tournament}/entries/{uid}, rank and score of uid in tournament
+func (c *Client) GetTournamentEntry(ctx context.Context, tournamentID string, uid string) (Entry, error) {
+	var out Entry
+	err := c.do(ctx, http.MethodGet, "/v1/tournaments/"+url.PathEscape(tournamentID)+"/entries/"+url.PathEscape(uid), nil, nil, &out)
+	return out, err
+}
+
+// GetWebhooks call GET /v1/webhooks, webhook subscriptions, oldest first, 404 when webhooks are disabled
+func (c *Client) GetWebhooks(ctx context.Context) (WebhookList, error) {
+	var out WebhookList
+	err := c.do(ctx, http.MethodGet, "/v1/webhooks", nil, nil, &out)
+	return out, err
+}
+
+// CreateWebhook call POST /v1/webhooks, subscribe url to event kinds. Every event is posted as WebhookEvent with headers X-Webhook-ID, X-Webhook-Event, X-Webhook-Timestamp and X-Webhook-Signature (sha256= hex HMAC-SHA256 of timestamp, "." and body with secret), other status than 2xx is retried with exponential backoff
+func (c *Client) CreateWebhook(ctx context.Context, body WebhookRequest) (Webhook, error) {
+	var out Webhook
+	err := c.do(ctx, http.MethodPost, "/v1/webhooks", nil, body, &out)
+	return out, err
+}
+
+// GetWebhook call GET /v1/webhooks/{webhook}, webhook subscription
+func (c *Client) GetWebhook(ctx context.Context, webhookID string) (Webhook, error) {
+	var out Webhook
+	err := c.do(ctx, http.MethodGet, "/v1/webhooks/"+url.PathEscape(webhookID), nil, nil, &out)
+	return out, err
+}
+
+// DeleteWebhook call DELETE /v1/webhooks/{webhook}, delete webhook and its deliveries
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID string) (Webhook, error) {
+	var out Webhook
+	err := c.do(ctx, http.MethodDelete, "/v1/webhooks/"+url.PathEscape(webhookID), nil, nil, &out)
+	return out, err
+}
+
+// GetWebhookDeliveriesParams is query of GetWebhookDeliveries, zero value use server default
+type GetWebhookDeliveriesParams struct {
+	Limit int64
+}
+
+// GetWebhookDeliveries call GET /v1/webhooks/{webhook}/deliveries, delivery log of webhook, newest first
+func (c *Client) GetWebhookDeliveries(ctx context.Context, webhookID string, params GetWebhookDeliveriesParams) (WebhookDeliveryList, error) {
+	query := url.Values{}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(params.Limit, 10))
+	}
+	var out WebhookDeliveryList
+	err := c.do(ctx, http.MethodGet, "/v1/webhooks/"+url.PathEscape(webhookID)+"/deliveries", query, nil, &out)
+	return out, err
+}
+
+// SaveGamePlayRanking call POST /saveGamePlayRanking, add amount to score of uid, kept for old clients, use SubmitScore
+//
+// Deprecated: use SubmitScore
+func (c *Client) SaveGamePlayRanking(ctx context.Context, body UserBody) error {
+	return c.do(ctx, http.MethodPost, "/saveGamePlayRanking", nil, body, nil)
+}
+
+// GetRankingByEventParams is query of GetRankingByEvent
+type GetRankingByEventParams struct {
+	// UID is user whose rank is first element when isServerRequest is 0
+	UID       string
+	EventType string
+	GameMode  string
+	SubTitle  string
+	// RankingDuration is period, ex. ScoreKey
+	RankingDuration string
+	// IsServerRequest is 1 to return every member, 0 to return uid first then leaderboard limit members with score at least 1
+	IsServerRequest string
+}
+
+// GetRankingByEvent call GET /getRankingByEvent, ranking of event type, kept for old clients, use GetEntries and GetEntry
+//
+// Deprecated: use GetEntries and GetEntry
+func (c *Client) GetRankingByEvent(ctx context.Context, params GetRankingByEventParams) ([]UserResponseData, error) {
+	query := url.Values{}
+	if params.UID != "" {
+		query.Set("uid", params.UID)
+	}
+	query.Set("eventType", params.EventType)
+	query.Set("gameMode", params.GameMode)
+	query.Set("subTitle", params.SubTitle)
+	query.Set("rankingDuration", params.RankingDuration)
+	query.Set("isServerRequest", params.IsServerRequest)
+	var out []UserResponseData
+	err := c.do(ctx, http.MethodGet, "/getRankingByEvent", query, nil, &out)
+	return out, err
+}
+
+// ClearRankingByKeyParams is query of ClearRankingByKey
+type ClearRankingByKeyParams struct {
+	RankingKey string
+}
+
+// ClearRankingByKey call GET /clearRankingByKey, clear every ranking of key, kept for old clients, use ClearLeaderboards
+//
+// Deprecated: use ClearLeaderboards
+func (c *Client) ClearRankingByKey(ctx context.Context, params ClearRankingByKeyParams) error {
+	query := url.Values{}
+	query.Set("rankingkey", params.RankingKey)
+	return c.do(ctx, http.MethodGet, "/clearRankingByKey", query, nil, nil)
+}
+
+// Healthz call GET /healthz, process is alive
+func (c *Client) Healthz(ctx context.Context) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodGet, "/healthz", nil, nil, &out)
+	return out, err
+}
+
+// Readyz call GET /readyz, store and DB answer ping, initial data is loaded and event queue is not saturated
+func (c *Client) Readyz(ctx context.Context) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodGet, "/readyz", nil, nil, &out)
+	return out, err
+}
+
+// Status call GET /status, dependencies, event queue and boards for operator
+func (c *Client) Status(ctx context.Context) (ServerStatus, error) {
+	var out ServerStatus
+	err := c.do(ctx, http.MethodGet, "/status", nil, nil, &out)
+	return out, err
+}
+
+// Metrics call GET /metrics, prometheus metrics
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodGet, "/metrics", nil, nil, &out)
+	return out, err
+}
+
+// OpenAPI call GET /openapi.yaml, this document
+func (c *Client) OpenAPI(ctx context.Context) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodGet, "/openapi.yaml", nil, nil, &out)
+	return out, err
+}
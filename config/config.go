@@ -1,19 +1,978 @@
 package config
 
-import "rangkingserver/utils"
-
-var (
-	ServerType    = utils.GetEnv("SERVER_TYPE", "Development")
-	DBHost        = utils.GetEnv("DB_HOST", "localhost")
-	DBPort        = utils.GetEnv("DB_PORT", "3306")
-	DBName        = utils.GetEnv("DB_NAME", "test")
-	DBUser        = utils.GetEnv("DB_USERNAME", "test")
-	DBPassword    = utils.GetEnv("DB_PASSWORD", "12345")
-	RedisHost     = utils.GetEnv("REDIS_HOST", "127.0.0.1")
-	RedisPort     = utils.GetEnv("REDIS_PORT", "6379")
-	RedisPassword = utils.GetEnv("REDIS_PASSWORD", "12345")
-
-	NumLimitRankingData int64  = 100
-	WorldRankingKey     string = "WorldRanking"
-	EventRankingKey     string = "ScoreKey"
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is all settings of ranking server, loaded from yaml file then overridden by env
+type Config struct {
+	ServerType  string            `yaml:"server_type"`
+	Server      ServerConfig      `yaml:"server"`
+	TLS         TLSConfig         `yaml:"tls"`
+	Redis       RedisConfig       `yaml:"redis"`
+	DB          DBConfig          `yaml:"db"`
+	Storage     StorageConfig     `yaml:"storage"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Leaderboard LeaderboardConfig `yaml:"leaderboard"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+}
+
+// ServerConfig is http listen settings
+type ServerConfig struct {
+	ListenAddr string `yaml:"listen_addr"`
+	// GRPCListenAddr is address of gRPC Ranking service, empty disable gRPC
+	GRPCListenAddr string `yaml:"grpc_listen_addr"`
+	// ShutdownTimeout is deadline to drain requests and queued events when stop
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// EventQueueSize is capacity of event loop queue, server is not ready when queue is nearly full
+	EventQueueSize int `yaml:"event_queue_size"`
+}
+
+// TLSConfig is certificate of https listener, serve plain http when disabled
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// RedisConfig is redis connection
+type RedisConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Password string `yaml:"password"`
+}
+
+// DBConfig is event source DB connection and pool
+type DBConfig struct {
+	// Driver is mysql or postgres
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Name     string `yaml:"name"`
+	User     string `yaml:"username"`
+	Password string `yaml:"password"`
+	// SSLMode is sslmode for postgres
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// PingRetry is number of ping attempts when start, wait PingInterval between attempts
+	PingRetry    int           `yaml:"ping_retry"`
+	PingInterval time.Duration `yaml:"ping_interval"`
+	// MigrateOnStart apply pending schema migrations when start
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+}
+
+// StorageConfig is leaderboard store
+type StorageConfig struct {
+	// Backend is redis, memory or bolt
+	Backend string `yaml:"backend"`
+	// BoltPath is file of bolt store
+	BoltPath string `yaml:"bolt_path"`
+	// RebuildFromDB clear rankings and load play_event from DB when start
+	RebuildFromDB bool `yaml:"rebuild_from_db"`
+}
+
+// CORSConfig is allowed origins, "*" allow every origin
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// TracingConfig is OpenTelemetry span exporter
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// Endpoint is host:port of OTLP http collector
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+	// SampleRatio is ratio of root spans sampled, child spans follow parent
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// RateLimitConfig is token buckets checked before handlers, kept in redis when storage.backend is redis so every replica share them
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ClientHeader is request header (gRPC metadata) that identify API client
+	ClientHeader string `yaml:"client_header"`
+	// TrustForwardedFor take source IP from first X-Forwarded-For address, enable only behind proxy that set it
+	TrustForwardedFor bool          `yaml:"trust_forwarded_for"`
+	PerClient         RateLimitRule `yaml:"per_client"`
+	PerIP             RateLimitRule `yaml:"per_ip"`
+	PerUID            RateLimitRule `yaml:"per_uid"`
+	// Expensive is extra budget per client, or per IP without client, of queries that read whole leaderboard or clear leaderboards
+	Expensive RateLimitRule `yaml:"expensive"`
+}
+
+// RateLimitRule is token bucket refilled Rate tokens per second up to Burst, Rate 0 disable the rule
+type RateLimitRule struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// WebhooksConfig is outbound webhook delivery, queue is kept in redis when storage.backend is redis, else in DB
+type WebhooksConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxAttempts is number of deliveries tried before delivery fail
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is wait after first failed attempt, doubled after every next one up to MaxBackoff
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Timeout is deadline of one http request to subscriber
+	Timeout      time.Duration `yaml:"timeout"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// BatchSize is max deliveries sent at once
+	BatchSize int `yaml:"batch_size"`
+	// Retention is how long delivered and failed deliveries are kept in delivery log
+	Retention time.Duration `yaml:"retention"`
+	// OvertakenLimit is max players reported overtaken by one score
+	OvertakenLimit int `yaml:"overtaken_limit"`
+	// AllowedNetworks are CIDRs subscribers may be in although they are private, loopback or link-local, ex. 10.0.0.0/8
+	AllowedNetworks []string `yaml:"allowed_networks"`
+}
+
+// LeaderboardConfig is ranking keys, limits and leaderboard definitions
+type LeaderboardConfig struct {
+	// Limit is number of members returned to client
+	Limit           int64  `yaml:"limit"`
+	WorldRankingKey string `yaml:"world_ranking_key"`
+	EventRankingKey string `yaml:"event_ranking_key"`
+	// Definitions declare accepted event types, every event type is accepted when empty
+	Definitions []LeaderboardDefinition `yaml:"definitions"`
+	Teams       TeamsConfig             `yaml:"teams"`
+	Leagues     LeaguesConfig           `yaml:"leagues"`
+	Ratings     RatingsConfig           `yaml:"ratings"`
+	// Windows are rolling periods of every leaderboard, queried like event and world ranking keys
+	Windows []WindowConfig `yaml:"windows"`
+	Decay   DecayJobConfig `yaml:"decay"`
+	Seasons SeasonsConfig  `yaml:"seasons"`
+}
+
+// SeasonsConfig is score players start new season of event ranking key with and number of ended seasons kept
+type SeasonsConfig struct {
+	// CarryOver is part of old score kept into new season, 0 to 1
+	CarryOver float64 `yaml:"carry_over"`
+	// Floors are tiers of old score, player start with at least Score of highest tier it reached
+	Floors []SeasonFloor `yaml:"floors"`
+	// Keep is number of archived seasons kept, older archives are deleted
+	Keep int `yaml:"keep"`
+}
+
+// SeasonFloor is lowest new season score of player whose old score is at least MinScore
+type SeasonFloor struct {
+	MinScore float64 `yaml:"min_score"`
+	Score    float64 `yaml:"score"`
+}
+
+// DecayJobConfig is background job that apply decay policies of leaderboard definitions
+type DecayJobConfig struct {
+	// Interval is time between runs of job
+	Interval time.Duration `yaml:"interval"`
+	// BatchSize is number of players decayed in one event, requests are served between batches
+	BatchSize int `yaml:"batch_size"`
+}
+
+// DecayPolicy lose Percent of score per day of inactivity once player has no score for Grace, Percent 0 disable decay
+type DecayPolicy struct {
+	Percent float64       `yaml:"percent"`
+	Grace   time.Duration `yaml:"grace"`
+}
+
+// WindowConfig is rolling period that sum scores of last Buckets buckets of Bucket length, current bucket included
+type WindowConfig struct {
+	// Name is period of window, ex. 7d
+	Name    string        `yaml:"name"`
+	Bucket  time.Duration `yaml:"bucket"`
+	Buckets int           `yaml:"buckets"`
+}
+
+// rating systems of leaderboard definition
+const (
+	RatingElo     = "elo"
+	RatingGlicko2 = "glicko2"
 )
+
+// RatingsConfig is parameters of rating leaderboards, new players start at Initial rating
+type RatingsConfig struct {
+	Initial float64 `yaml:"initial"`
+	// KFactor is largest change of elo rating in one match
+	KFactor float64 `yaml:"k_factor"`
+	// Deviation and Volatility are glicko-2 rating deviation and volatility of new players
+	Deviation  float64 `yaml:"deviation"`
+	Volatility float64 `yaml:"volatility"`
+	// Tau constrain change of glicko-2 volatility, 0.3 to 1.2
+	Tau float64 `yaml:"tau"`
+}
+
+// LeaguesConfig is groups of GroupSize players within tier of every leaderboard, season end when event ranking key is cleared
+type LeaguesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Tiers are names of tiers from lowest, new players start in lowest tier
+	Tiers     []string `yaml:"tiers"`
+	GroupSize int      `yaml:"group_size"`
+	// Promote is number of highest ranks of group that move up one tier at season end
+	Promote int `yaml:"promote"`
+	// Relegate is number of lowest ranks of group that move down one tier at season end
+	Relegate int `yaml:"relegate"`
+	// BatchSize is number of groups ended in one event at season end, requests are served between batches
+	BatchSize int `yaml:"batch_size"`
+}
+
+// TeamsConfig is team leaderboard kept next to every leaderboard, score of team is sum of contributions of its members
+type TeamsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TopK count only K highest contributions of team, 0 count every contribution
+	TopK int `yaml:"top_k"`
+	// MaxMembers is largest number of members of team
+	MaxMembers int `yaml:"max_members"`
+}
+
+// LeaderboardDefinition declare one leaderboard fed by event type
+type LeaderboardDefinition struct {
+	EventType string `yaml:"event_type"`
+	Name      string `yaml:"name"`
+	// Dimensions are names of values score of event type can carry, ex. game_mode, sub_title, region, platform
+	Dimensions []string `yaml:"dimensions"`
+	// Slices are combinations of dimensions that have own leaderboard, leaderboard without dimension is always kept
+	Slices [][]string `yaml:"slices"`
+	// Rating is elo or glicko2 for leaderboard of skill rating fed by match results, empty sum scores
+	Rating string `yaml:"rating"`
+	// Decay is decay of scores of inactive players of event ranking key
+	Decay DecayPolicy `yaml:"decay"`
+	// Milestones are reached once per season by player of leaderboard without dimension of event ranking key
+	Milestones []MilestoneConfig `yaml:"milestones"`
+}
+
+// MilestoneConfig is badge of player whose score reach Score or whose rank reach Rank, exactly one of them is set
+type MilestoneConfig struct {
+	Name  string  `yaml:"name"`
+	Score float64 `yaml:"score"`
+	Rank  int64   `yaml:"rank"`
+}
+
+// reservedDimensions are query parameters of api that cannot be dimension name
+var reservedDimensions = map[string]bool{"period": true, "limit": true, "uid": true, "friends": true}
+
+func validDimensionName(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(Default())
+}
+
+// Current get config in use, caller must not modify it
+func Current() *Config {
+	return current.Load().(*Config)
+}
+
+// Set replace config in use
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// Default get config with default value of every setting
+func Default() *Config {
+	return &Config{
+		ServerType: "Development",
+		Server: ServerConfig{
+			ListenAddr:      "0.0.0.0:8444",
+			GRPCListenAddr:  "0.0.0.0:9444",
+			ShutdownTimeout: 30 * time.Second,
+			EventQueueSize:  1024,
+		},
+		TLS: TLSConfig{
+			Enabled: true,
+		},
+		Redis: RedisConfig{
+			Host:     "127.0.0.1",
+			Port:     "6379",
+			Password: "12345",
+		},
+		DB: DBConfig{
+			Driver:          "mysql",
+			Host:            "localhost",
+			Port:            "3306",
+			Name:            "test",
+			User:            "test",
+			Password:        "12345",
+			SSLMode:         "disable",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			PingRetry:       5,
+			PingInterval:    2 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend:       "redis",
+			BoltPath:      "data/ranking.db",
+			RebuildFromDB: true,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+		},
+		Leaderboard: LeaderboardConfig{
+			Limit:           100,
+			WorldRankingKey: "WorldRanking",
+			EventRankingKey: "ScoreKey",
+			Teams: TeamsConfig{
+				MaxMembers: 100,
+			},
+			Leagues: LeaguesConfig{
+				Tiers:     []string{"Bronze", "Silver", "Gold", "Platinum", "Diamond"},
+				GroupSize: 50,
+				Promote:   10,
+				Relegate:  10,
+				BatchSize: 100,
+			},
+			Ratings: RatingsConfig{
+				Initial:    1500,
+				KFactor:    32,
+				Deviation:  350,
+				Volatility: 0.06,
+				Tau:        0.5,
+			},
+			Decay: DecayJobConfig{
+				Interval:  time.Hour,
+				BatchSize: 500,
+			},
+			Seasons: SeasonsConfig{
+				Keep: 3,
+			},
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:      true,
+			ClientHeader: "X-API-Key",
+			PerClient:    RateLimitRule{Rate: 200, Burst: 400},
+			PerIP:        RateLimitRule{Rate: 50, Burst: 100},
+			PerUID:       RateLimitRule{Rate: 10, Burst: 20},
+			Expensive:    RateLimitRule{Rate: 1, Burst: 5},
+		},
+		Webhooks: WebhooksConfig{
+			MaxAttempts:    8,
+			Backoff:        time.Second,
+			MaxBackoff:     10 * time.Minute,
+			Timeout:        10 * time.Second,
+			PollInterval:   time.Second,
+			BatchSize:      100,
+			Retention:      7 * 24 * time.Hour,
+			OvertakenLimit: 10,
+		},
+	}
+}
+
+// Load read defaults, yaml file at path when path is not empty, then env overrides and validate
+func Load(path string) (*Config, error) {
+	c := Default()
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %v", err)
+		}
+		if err := yaml.UnmarshalStrict(data, c); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %v", path, err)
+		}
+	}
+
+	var errs []string
+	if err := c.applyEnv(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	c.defaultCertFiles()
+	if err := c.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return c, nil
+}
+
+// Reload load config from path and apply settings that do not need new connection.
+// Listen address, TLS, redis, DB, storage, tracing and webhooks.enabled keep value of current config, return names of ignored changes.
+// Ranking keys, definitions, windows, league tiers and ratings name or shape keys already in store so they keep value of current config too.
+func Reload(path string) ([]string, error) {
+	next, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	old := Current()
+	var ignored []string
+	if next.ServerType != old.ServerType {
+		ignored = append(ignored, "server_type")
+	}
+	if next.Server != old.Server {
+		ignored = append(ignored, "server")
+	}
+	if next.TLS != old.TLS {
+		ignored = append(ignored, "tls")
+	}
+	if next.Redis != old.Redis {
+		ignored = append(ignored, "redis")
+	}
+	if next.DB != old.DB {
+		ignored = append(ignored, "db")
+	}
+	if next.Storage != old.Storage {
+		ignored = append(ignored, "storage")
+	}
+	if next.Tracing != old.Tracing {
+		ignored = append(ignored, "tracing")
+	}
+	next.ServerType = old.ServerType
+	next.Server = old.Server
+	next.TLS = old.TLS
+	next.Redis = old.Redis
+	next.DB = old.DB
+	next.Storage = old.Storage
+	next.Tracing = old.Tracing
+	if next.Webhooks.Enabled != old.Webhooks.Enabled {
+		ignored = append(ignored, "webhooks.enabled")
+	}
+	next.Webhooks.Enabled = old.Webhooks.Enabled
+
+	layout := &next.Leaderboard
+	if layout.EventRankingKey != old.Leaderboard.EventRankingKey {
+		ignored = append(ignored, "leaderboard.event_ranking_key")
+	}
+	if layout.WorldRankingKey != old.Leaderboard.WorldRankingKey {
+		ignored = append(ignored, "leaderboard.world_ranking_key")
+	}
+	if !reflect.DeepEqual(layout.Definitions, old.Leaderboard.Definitions) {
+		ignored = append(ignored, "leaderboard.definitions")
+	}
+	if !reflect.DeepEqual(layout.Windows, old.Leaderboard.Windows) {
+		ignored = append(ignored, "leaderboard.windows")
+	}
+	if !reflect.DeepEqual(layout.Leagues.Tiers, old.Leaderboard.Leagues.Tiers) {
+		ignored = append(ignored, "leaderboard.leagues.tiers")
+	}
+	if layout.Ratings != old.Leaderboard.Ratings {
+		ignored = append(ignored, "leaderboard.ratings")
+	}
+	layout.EventRankingKey = old.Leaderboard.EventRankingKey
+	layout.WorldRankingKey = old.Leaderboard.WorldRankingKey
+	layout.Definitions = old.Leaderboard.Definitions
+	layout.Windows = old.Leaderboard.Windows
+	layout.Leagues.Tiers = old.Leaderboard.Leagues.Tiers
+	layout.Ratings = old.Leaderboard.Ratings
+
+	Set(next)
+	return ignored, nil
+}
+
+// Validate check every setting and report all invalid ones
+func (c *Config) Validate() error {
+	var errs []string
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	switch c.ServerType {
+	case "Production", "Development":
+	default:
+		invalid("server_type must be Production or Development, got %q", c.ServerType)
+	}
+	if c.Server.ListenAddr == "" {
+		invalid("server.listen_addr is required")
+	}
+	if c.Server.GRPCListenAddr != "" && c.Server.GRPCListenAddr == c.Server.ListenAddr {
+		invalid("server.grpc_listen_addr must differ from server.listen_addr")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		invalid("server.shutdown_timeout must be positive")
+	}
+	if c.Server.EventQueueSize < 1 {
+		invalid("server.event_queue_size must be at least 1")
+	}
+	if c.TLS.Enabled && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		invalid("tls.cert_file and tls.key_file are required when tls.enabled")
+	}
+
+	switch c.DB.Driver {
+	case "mysql", "postgres":
+	default:
+		invalid("db.driver must be mysql or postgres, got %q", c.DB.Driver)
+	}
+	if c.DB.Host == "" || c.DB.Port == "" || c.DB.Name == "" {
+		invalid("db.host, db.port and db.name are required")
+	}
+	if c.DB.MaxOpenConns < 0 || c.DB.MaxIdleConns < 0 {
+		invalid("db.max_open_conns and db.max_idle_conns must not be negative")
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		invalid("db.max_idle_conns %d is more than db.max_open_conns %d", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	}
+	if c.DB.PingRetry < 1 {
+		invalid("db.ping_retry must be at least 1")
+	}
+
+	switch c.Storage.Backend {
+	case "redis":
+		if c.Redis.Host == "" || c.Redis.Port == "" {
+			invalid("redis.host and redis.port are required when storage.backend is redis")
+		}
+	case "memory":
+	case "bolt":
+		if c.Storage.BoltPath == "" {
+			invalid("storage.bolt_path is required when storage.backend is bolt")
+		}
+	default:
+		invalid("storage.backend must be redis, memory or bolt, got %q", c.Storage.Backend)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			invalid("tracing.endpoint is required when tracing.exporter is otlp")
+		}
+	default:
+		invalid("tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio must be between 0 and 1")
+	}
+
+	rules := map[string]RateLimitRule{
+		"per_client": c.RateLimit.PerClient,
+		"per_ip":     c.RateLimit.PerIP,
+		"per_uid":    c.RateLimit.PerUID,
+		"expensive":  c.RateLimit.Expensive,
+	}
+	for _, name := range []string{"per_client", "per_ip", "per_uid", "expensive"} {
+		rule := rules[name]
+		if rule.Rate < 0 {
+			invalid("rate_limit.%s.rate must not be negative", name)
+		}
+		if rule.Rate > 0 && rule.Burst < 1 {
+			invalid("rate_limit.%s.burst must be at least 1 when rate is set", name)
+		}
+	}
+	if c.RateLimit.Enabled && c.RateLimit.PerClient.Rate > 0 && c.RateLimit.ClientHeader == "" {
+		invalid("rate_limit.client_header is required when rate_limit.per_client is set")
+	}
+
+	if c.Webhooks.MaxAttempts < 1 {
+		invalid("webhooks.max_attempts must be at least 1")
+	}
+	if c.Webhooks.Backoff < time.Millisecond || c.Webhooks.MaxBackoff < c.Webhooks.Backoff {
+		invalid("webhooks.backoff must be at least 1ms and webhooks.max_backoff must not be less than it")
+	}
+	if c.Webhooks.Timeout < time.Millisecond {
+		invalid("webhooks.timeout must be at least 1ms")
+	}
+	if c.Webhooks.PollInterval < time.Millisecond {
+		invalid("webhooks.poll_interval must be at least 1ms")
+	}
+	if c.Webhooks.BatchSize < 1 {
+		invalid("webhooks.batch_size must be at least 1")
+	}
+	if c.Webhooks.Retention < time.Minute {
+		invalid("webhooks.retention must be at least 1m")
+	}
+	if c.Webhooks.OvertakenLimit < 0 {
+		invalid("webhooks.overtaken_limit must not be negative")
+	}
+	for _, cidr := range c.Webhooks.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			invalid("webhooks.allowed_networks %q must be CIDR, ex. 10.0.0.0/8", cidr)
+		}
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		invalid("cors.allowed_origins is required, use \"*\" to allow every origin")
+	}
+
+	if c.Leaderboard.Limit < 1 {
+		invalid("leaderboard.limit must be at least 1")
+	}
+	if c.Leaderboard.EventRankingKey == "" || c.Leaderboard.WorldRankingKey == "" {
+		invalid("leaderboard.event_ranking_key and leaderboard.world_ranking_key are required")
+	}
+	if c.Leaderboard.Teams.TopK < 0 {
+		invalid("leaderboard.teams.top_k must not be negative")
+	}
+	if c.Leaderboard.Teams.MaxMembers < 1 {
+		invalid("leaderboard.teams.max_members must be at least 1")
+	}
+	leagues := c.Leaderboard.Leagues
+	if len(leagues.Tiers) == 0 {
+		invalid("leaderboard.leagues.tiers is required")
+	}
+	tiers := make(map[string]bool)
+	for _, tier := range leagues.Tiers {
+		if tier == "" || tiers[tier] {
+			invalid("leaderboard.leagues.tiers %q must not be empty or duplicated", tier)
+		}
+		tiers[tier] = true
+	}
+	if leagues.GroupSize < 2 {
+		invalid("leaderboard.leagues.group_size must be at least 2")
+	}
+	if leagues.Promote < 0 || leagues.Relegate < 0 || leagues.Promote+leagues.Relegate > leagues.GroupSize {
+		invalid("leaderboard.leagues.promote and relegate must not be negative and their sum must not exceed group_size")
+	}
+	if leagues.BatchSize < 1 {
+		invalid("leaderboard.leagues.batch_size must be at least 1")
+	}
+	windows := make(map[string]bool)
+	for i, window := range c.Leaderboard.Windows {
+		switch {
+		case window.Name == "" || strings.ContainsAny(window.Name, ":{} "):
+			invalid("leaderboard.windows[%d].name must not be empty or have colon, brace or space", i)
+		case windows[window.Name] || window.Name == c.Leaderboard.EventRankingKey || window.Name == c.Leaderboard.WorldRankingKey:
+			invalid("leaderboard.windows[%d].name %q is duplicated or is ranking key", i, window.Name)
+		}
+		windows[window.Name] = true
+		if window.Bucket < time.Minute || window.Bucket%time.Minute != 0 {
+			invalid("leaderboard.windows[%d].bucket must be whole minutes, at least 1m", i)
+		}
+		if window.Buckets < 1 {
+			invalid("leaderboard.windows[%d].buckets must be at least 1", i)
+		}
+	}
+	if c.Leaderboard.Decay.Interval < time.Second {
+		invalid("leaderboard.decay.interval must be at least 1s")
+	}
+	if c.Leaderboard.Decay.BatchSize < 1 {
+		invalid("leaderboard.decay.batch_size must be at least 1")
+	}
+	seasons := c.Leaderboard.Seasons
+	if seasons.CarryOver < 0 || seasons.CarryOver > 1 {
+		invalid("leaderboard.seasons.carry_over must be from 0 to 1")
+	}
+	for i, floor := range seasons.Floors {
+		if floor.Score < 0 || floor.Score > floor.MinScore {
+			invalid("leaderboard.seasons.floors[%d].score must be from 0 to min_score", i)
+		}
+	}
+	if seasons.Keep < 1 {
+		invalid("leaderboard.seasons.keep must be at least 1")
+	}
+	ratings := c.Leaderboard.Ratings
+	if ratings.Initial <= 0 {
+		invalid("leaderboard.ratings.initial must be positive")
+	}
+	if ratings.KFactor <= 0 {
+		invalid("leaderboard.ratings.k_factor must be positive")
+	}
+	if ratings.Deviation <= 0 || ratings.Volatility <= 0 || ratings.Tau <= 0 {
+		invalid("leaderboard.ratings.deviation, volatility and tau must be positive")
+	}
+	eventTypes := make(map[string]bool)
+	for i, definition := range c.Leaderboard.Definitions {
+		if definition.EventType == "" {
+			invalid("leaderboard.definitions[%d].event_type is required", i)
+		}
+		if eventTypes[definition.EventType] {
+			invalid("leaderboard.definitions[%d].event_type %q is duplicated", i, definition.EventType)
+		}
+		eventTypes[definition.EventType] = true
+		switch definition.Rating {
+		case "", RatingElo, RatingGlicko2:
+		default:
+			invalid("leaderboard.definitions[%d].rating must be empty, %s or %s, got %q", i, RatingElo, RatingGlicko2, definition.Rating)
+		}
+		if definition.Decay.Percent < 0 || definition.Decay.Percent >= 100 || definition.Decay.Grace < 0 {
+			invalid("leaderboard.definitions[%d].decay.percent must be from 0 to below 100 and grace must not be negative", i)
+		}
+		if definition.Decay.Percent > 0 && definition.Rating != "" {
+			invalid("leaderboard.definitions[%d].decay is not supported for rating leaderboard", i)
+		}
+		milestones := make(map[string]bool)
+		for j, milestone := range definition.Milestones {
+			if milestone.Name == "" || strings.ContainsAny(milestone.Name, ":{} ") || milestones[milestone.Name] {
+				invalid("leaderboard.definitions[%d].milestones[%d].name must not be empty, duplicated or have colon, brace or space", i, j)
+			}
+			milestones[milestone.Name] = true
+			if (milestone.Score > 0) == (milestone.Rank > 0) || milestone.Score < 0 || milestone.Rank < 0 {
+				invalid("leaderboard.definitions[%d].milestones[%d] must have positive score or positive rank, not both", i, j)
+			}
+		}
+
+		dimensions := make(map[string]bool)
+		for _, name := range definition.Dimensions {
+			if !validDimensionName(name) || reservedDimensions[name] {
+				invalid("leaderboard.definitions[%d].dimensions %q must be lower case letters, digits and _, and not period, limit, uid or friends", i, name)
+			}
+			if dimensions[name] {
+				invalid("leaderboard.definitions[%d].dimensions %q is duplicated", i, name)
+			}
+			dimensions[name] = true
+		}
+		slices := make(map[string]bool)
+		for j, slice := range definition.Slices {
+			if len(slice) == 0 {
+				invalid("leaderboard.definitions[%d].slices[%d] is empty, leaderboard without dimension is always kept", i, j)
+			}
+			inSlice := make(map[string]bool)
+			for _, name := range slice {
+				if !dimensions[name] {
+					invalid("leaderboard.definitions[%d].slices[%d] dimension %q is not declared in dimensions", i, j, name)
+				}
+				if inSlice[name] {
+					invalid("leaderboard.definitions[%d].slices[%d] dimension %q is duplicated", i, j, name)
+				}
+				inSlice[name] = true
+			}
+			key := strings.Join(definition.OrderDimensions(slice), ",")
+			if slices[key] {
+				invalid("leaderboard.definitions[%d].slices[%d] is duplicated", i, j)
+			}
+			slices[key] = true
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// DBRequired report whether server need DB pool, to rebuild rankings from play_event or to keep webhook queue
+// when storage backend is not redis. Migrations open their own connection.
+func (c *Config) DBRequired() bool {
+	return c.Storage.RebuildFromDB || (c.Webhooks.Enabled && c.Storage.Backend != "redis")
+}
+
+// LeaderboardDefined report whether event type is accepted by leaderboard definitions
+func (c *Config) LeaderboardDefined(eventType string) bool {
+	if len(c.Leaderboard.Definitions) == 0 {
+		return true
+	}
+	for _, definition := range c.Leaderboard.Definitions {
+		if definition.EventType == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Definition get leaderboard definition of event type, zero definition without dimension when definitions are empty
+func (c *Config) Definition(eventType string) (LeaderboardDefinition, bool) {
+	for _, definition := range c.Leaderboard.Definitions {
+		if definition.EventType == eventType {
+			return definition, true
+		}
+	}
+	return LeaderboardDefinition{EventType: eventType}, len(c.Leaderboard.Definitions) == 0
+}
+
+// Window get rolling window named period
+func (c *Config) Window(period string) (WindowConfig, bool) {
+	for _, window := range c.Leaderboard.Windows {
+		if window.Name == period {
+			return window, true
+		}
+	}
+	return WindowConfig{}, false
+}
+
+// HasDimension report whether definition declare dimension name
+func (d LeaderboardDefinition) HasDimension(name string) bool {
+	for _, dimension := range d.Dimensions {
+		if dimension == name {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderDimensions get declared names in order of Dimensions, undeclared names are dropped
+func (d LeaderboardDefinition) OrderDimensions(names []string) []string {
+	var ordered []string
+	for _, dimension := range d.Dimensions {
+		for _, name := range names {
+			if name == dimension {
+				ordered = append(ordered, name)
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+// AllowOrigin get value of Access-Control-Allow-Origin for request origin, empty when not allowed
+func (c *Config) AllowOrigin(origin string) string {
+	for _, allowed := range c.CORS.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if allowed == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// applyEnv override config by env, keep name of env from before config file
+func (c *Config) applyEnv() error {
+	var errs []string
+	envString := func(key string, value *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*value = v
+		}
+	}
+	envInt := func(key string, value *int) {
+		if v, ok := os.LookupEnv(key); ok {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("env %s: %v", key, err))
+				return
+			}
+			*value = i
+		}
+	}
+	envDuration := func(key string, value *time.Duration) {
+		if v, ok := os.LookupEnv(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("env %s: %v", key, err))
+				return
+			}
+			*value = d
+		}
+	}
+	envFloat := func(key string, value *float64) {
+		if v, ok := os.LookupEnv(key); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("env %s: %v", key, err))
+				return
+			}
+			*value = f
+		}
+	}
+	envBool := func(key string, value *bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("env %s: %v", key, err))
+				return
+			}
+			*value = b
+		}
+	}
+
+	envString("SERVER_TYPE", &c.ServerType)
+	envString("LISTEN_ADDR", &c.Server.ListenAddr)
+	envString("GRPC_LISTEN_ADDR", &c.Server.GRPCListenAddr)
+	envDuration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	envInt("EVENT_QUEUE_SIZE", &c.Server.EventQueueSize)
+	envBool("TLS_ENABLED", &c.TLS.Enabled)
+	envString("TLS_CERT_FILE", &c.TLS.CertFile)
+	envString("TLS_KEY_FILE", &c.TLS.KeyFile)
+	envString("REDIS_HOST", &c.Redis.Host)
+	envString("REDIS_PORT", &c.Redis.Port)
+	envString("REDIS_PASSWORD", &c.Redis.Password)
+	envString("DB_DRIVER", &c.DB.Driver)
+	envString("DB_HOST", &c.DB.Host)
+	envString("DB_PORT", &c.DB.Port)
+	envString("DB_NAME", &c.DB.Name)
+	envString("DB_USERNAME", &c.DB.User)
+	envString("DB_PASSWORD", &c.DB.Password)
+	envString("DB_SSLMODE", &c.DB.SSLMode)
+	envInt("DB_MAX_OPEN_CONNS", &c.DB.MaxOpenConns)
+	envInt("DB_MAX_IDLE_CONNS", &c.DB.MaxIdleConns)
+	envDuration("DB_CONN_MAX_LIFETIME", &c.DB.ConnMaxLifetime)
+	envDuration("DB_CONN_MAX_IDLE_TIME", &c.DB.ConnMaxIdleTime)
+	envInt("DB_PING_RETRY", &c.DB.PingRetry)
+	envDuration("DB_PING_INTERVAL", &c.DB.PingInterval)
+	envBool("MIGRATE_ON_START", &c.DB.MigrateOnStart)
+	envString("STORAGE_BACKEND", &c.Storage.Backend)
+	envString("BOLT_PATH", &c.Storage.BoltPath)
+	envBool("REBUILD_FROM_DB", &c.Storage.RebuildFromDB)
+	envString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	envString("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	envBool("TRACING_INSECURE", &c.Tracing.Insecure)
+	envFloat("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+	envBool("TEAMS_ENABLED", &c.Leaderboard.Teams.Enabled)
+	envInt("TEAMS_TOP_K", &c.Leaderboard.Teams.TopK)
+	envInt("TEAMS_MAX_MEMBERS", &c.Leaderboard.Teams.MaxMembers)
+	envBool("LEAGUES_ENABLED", &c.Leaderboard.Leagues.Enabled)
+	envInt("LEAGUES_GROUP_SIZE", &c.Leaderboard.Leagues.GroupSize)
+	envInt("LEAGUES_PROMOTE", &c.Leaderboard.Leagues.Promote)
+	envInt("LEAGUES_RELEGATE", &c.Leaderboard.Leagues.Relegate)
+	envInt("LEAGUES_BATCH_SIZE", &c.Leaderboard.Leagues.BatchSize)
+	if v, ok := os.LookupEnv("LEAGUES_TIERS"); ok {
+		c.Leaderboard.Leagues.Tiers = strings.Split(v, ",")
+	}
+	envFloat("RATINGS_INITIAL", &c.Leaderboard.Ratings.Initial)
+	envFloat("RATINGS_K_FACTOR", &c.Leaderboard.Ratings.KFactor)
+	envFloat("RATINGS_TAU", &c.Leaderboard.Ratings.Tau)
+	envDuration("DECAY_INTERVAL", &c.Leaderboard.Decay.Interval)
+	envInt("DECAY_BATCH_SIZE", &c.Leaderboard.Decay.BatchSize)
+	envFloat("SEASONS_CARRY_OVER", &c.Leaderboard.Seasons.CarryOver)
+	envInt("SEASONS_KEEP", &c.Leaderboard.Seasons.Keep)
+	envBool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	envString("RATE_LIMIT_CLIENT_HEADER", &c.RateLimit.ClientHeader)
+	envBool("RATE_LIMIT_TRUST_FORWARDED_FOR", &c.RateLimit.TrustForwardedFor)
+	envBool("WEBHOOKS_ENABLED", &c.Webhooks.Enabled)
+	envInt("WEBHOOKS_MAX_ATTEMPTS", &c.Webhooks.MaxAttempts)
+	envDuration("WEBHOOKS_BACKOFF", &c.Webhooks.Backoff)
+	envDuration("WEBHOOKS_MAX_BACKOFF", &c.Webhooks.MaxBackoff)
+	envDuration("WEBHOOKS_TIMEOUT", &c.Webhooks.Timeout)
+	envDuration("WEBHOOKS_POLL_INTERVAL", &c.Webhooks.PollInterval)
+	envInt("WEBHOOKS_BATCH_SIZE", &c.Webhooks.BatchSize)
+	envDuration("WEBHOOKS_RETENTION", &c.Webhooks.Retention)
+	envInt("WEBHOOKS_OVERTAKEN_LIMIT", &c.Webhooks.OvertakenLimit)
+	if v, ok := os.LookupEnv("WEBHOOKS_ALLOWED_NETWORKS"); ok {
+		c.Webhooks.AllowedNetworks = nil
+		if v != "" {
+			c.Webhooks.AllowedNetworks = strings.Split(v, ",")
+		}
+	}
+	if v, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		c.CORS.AllowedOrigins = strings.Split(v, ",")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// defaultCertFiles set cert files by server type when not set
+func (c *Config) defaultCertFiles() {
+	if c.TLS.CertFile != "" || c.TLS.KeyFile != "" {
+		return
+	}
+	switch c.ServerType {
+	case "Production":
+		c.TLS.CertFile, c.TLS.KeyFile = "certs/fullchain.pem", "certs/privkey.pem"
+	case "Development":
+		c.TLS.CertFile, c.TLS.KeyFile = "certs/fullchain_ds.pem", "certs/privkey_ds.pem"
+	}
+}
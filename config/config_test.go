@@ -0,0 +1,151 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const reloadBase = `
+storage:
+  backend: memory
+cors:
+  allowed_origins: ["https://a.example"]
+leaderboard:
+  limit: 100
+  event_ranking_key: ScoreKey
+  world_ranking_key: WorldRanking
+  definitions:
+    - event_type: "1"
+      name: play_count
+      dimensions: [region]
+  windows:
+    - name: 7d
+      bucket: 24h
+      buckets: 7
+  leagues:
+    tiers: [bronze, silver]
+  ratings:
+    initial: 1500
+`
+
+const reloadNext = `
+storage:
+  backend: bolt
+cors:
+  allowed_origins: ["https://b.example"]
+leaderboard:
+  limit: 50
+  event_ranking_key: Season2
+  world_ranking_key: World2
+  definitions:
+    - event_type: "1"
+      name: play_count
+      dimensions: [region, platform]
+  windows:
+    - name: 30d
+      bucket: 24h
+      buckets: 30
+  leagues:
+    tiers: [bronze, silver, gold]
+  ratings:
+    initial: 1200
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReloadKeepRestartOnlySettings(t *testing.T) {
+	defer Set(Current())
+	old, err := Load(writeConfig(t, reloadBase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(old)
+
+	ignored, err := Reload(writeConfig(t, reloadNext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"storage",
+		"leaderboard.event_ranking_key",
+		"leaderboard.world_ranking_key",
+		"leaderboard.definitions",
+		"leaderboard.windows",
+		"leaderboard.leagues.tiers",
+		"leaderboard.ratings",
+	}
+	if !reflect.DeepEqual(ignored, want) {
+		t.Errorf("ignored = %v, want %v", ignored, want)
+	}
+
+	c := Current()
+	if c.Storage != old.Storage {
+		t.Errorf("storage = %+v, want %+v", c.Storage, old.Storage)
+	}
+	if c.Leaderboard.EventRankingKey != "ScoreKey" || c.Leaderboard.WorldRankingKey != "WorldRanking" {
+		t.Errorf("ranking keys = %s, %s, want ScoreKey, WorldRanking", c.Leaderboard.EventRankingKey, c.Leaderboard.WorldRankingKey)
+	}
+	if !reflect.DeepEqual(c.Leaderboard.Definitions, old.Leaderboard.Definitions) {
+		t.Errorf("definitions = %+v, want %+v", c.Leaderboard.Definitions, old.Leaderboard.Definitions)
+	}
+	if !reflect.DeepEqual(c.Leaderboard.Windows, []WindowConfig{{Name: "7d", Bucket: 24 * time.Hour, Buckets: 7}}) {
+		t.Errorf("windows = %+v", c.Leaderboard.Windows)
+	}
+	if !reflect.DeepEqual(c.Leaderboard.Leagues.Tiers, []string{"bronze", "silver"}) {
+		t.Errorf("league tiers = %v", c.Leaderboard.Leagues.Tiers)
+	}
+	if c.Leaderboard.Ratings != old.Leaderboard.Ratings {
+		t.Errorf("ratings = %+v, want %+v", c.Leaderboard.Ratings, old.Leaderboard.Ratings)
+	}
+
+	if c.Leaderboard.Limit != 50 {
+		t.Errorf("limit = %d, want 50", c.Leaderboard.Limit)
+	}
+	if !reflect.DeepEqual(c.CORS.AllowedOrigins, []string{"https://b.example"}) {
+		t.Errorf("allowed origins = %v, want reloaded value", c.CORS.AllowedOrigins)
+	}
+}
+
+func TestReloadUnchanged(t *testing.T) {
+	defer Set(Current())
+	path := writeConfig(t, reloadBase)
+	old, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(old)
+
+	ignored, err := Reload(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ignored) != 0 {
+		t.Errorf("ignored = %v, want none", ignored)
+	}
+}
+
+func TestReloadInvalidKeepCurrent(t *testing.T) {
+	defer Set(Current())
+	old, err := Load(writeConfig(t, reloadBase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(old)
+
+	if _, err := Reload(writeConfig(t, "leaderboard:\n  limit: -1\n")); err == nil {
+		t.Fatal("invalid config is reloaded")
+	}
+	if Current() != old {
+		t.Error("current config is replaced by invalid config")
+	}
+}
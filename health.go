@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/ranking"
+	"rangkingserver/storage"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// version is build version, set by -ldflags "-X main.version=..."
+var version = "dev"
+
+var startTime = time.Now()
+
+type dependencyStatus struct {
+	OK        bool    `json:"ok"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type dbStatus struct {
+	dependencyStatus
+	// Disabled is set when config does not require DB and no pool is open
+	Disabled        bool    `json:"disabled,omitempty"`
+	OpenConnections int     `json:"open_connections"`
+	InUse           int     `json:"in_use"`
+	Idle            int     `json:"idle"`
+	WaitCount       int64   `json:"wait_count"`
+	WaitMS          float64 `json:"wait_ms"`
+}
+
+type queueStatus struct {
+	Depth     int  `json:"depth"`
+	Capacity  int  `json:"capacity"`
+	Saturated bool `json:"saturated"`
+}
+
+type serverStatus struct {
+	Version       string           `json:"version"`
+	ServerType    string           `json:"server_type"`
+	UptimeSeconds float64          `json:"uptime_seconds"`
+	Ready         bool             `json:"ready"`
+	RebuildDone   bool             `json:"rebuild_done"`
+	Queue         queueStatus      `json:"queue"`
+	Store         dependencyStatus `json:"store"`
+	StoreBackend  string           `json:"store_backend"`
+	DB            dbStatus         `json:"db"`
+	Boards        map[string]int   `json:"boards"`
+}
+
+// healthz process is alive
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// readyz server can take traffic, store and DB, when pool is open, answer ping, initial data is loaded and event queue is not saturated
+func readyz(w http.ResponseWriter, r *http.Request) {
+	var notReady []string
+	if store := checkStore(r.Context()); !store.OK {
+		notReady = append(notReady, "store: "+store.Error)
+	}
+	if db := checkDB(); !db.Disabled && !db.OK {
+		notReady = append(notReady, "db: "+db.Error)
+	}
+	if !ranking.RankingSystemDataReady() {
+		notReady = append(notReady, "ranking data rebuild is not done")
+	}
+	if queue := checkQueue(); queue.Saturated {
+		notReady = append(notReady, fmt.Sprintf("event queue saturated %d/%d", queue.Depth, queue.Capacity))
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	if len(notReady) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, reason := range notReady {
+			fmt.Fprintln(w, reason)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// status detail of dependencies, event queue and boards for operator
+func status(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Current()
+	s := serverStatus{
+		Version:       version,
+		ServerType:    cfg.ServerType,
+		UptimeSeconds: time.Since(startTime).Seconds(),
+		RebuildDone:   ranking.RankingSystemDataReady(),
+		Queue:         checkQueue(),
+		Store:         checkStore(r.Context()),
+		StoreBackend:  cfg.Storage.Backend,
+		DB:            checkDB(),
+		Boards:        make(map[string]int),
+	}
+	s.Ready = s.Store.OK && s.DB.OK && s.RebuildDone && !s.Queue.Saturated
+
+	for _, listKey := range []string{cfg.Leaderboard.EventRankingKey, cfg.Leaderboard.WorldRankingKey} {
+		boards, err := storage.DataSources.Store.ListRankings(r.Context(), listKey)
+		if err != nil {
+			zap.L().Warn("status cannot list rankings", zap.String("key", listKey), zap.Error(err))
+			continue
+		}
+		s.Boards[listKey] = len(boards)
+	}
+
+	jsonData, err := json.Marshal(s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+func checkStore(ctx context.Context) dependencyStatus {
+	start := time.Now()
+	err := storage.DataSources.Store.Ping(ctx)
+	return newDependencyStatus(time.Since(start), err)
+}
+
+func checkDB() dbStatus {
+	if storage.DataSources.DB == nil {
+		return dbStatus{dependencyStatus: dependencyStatus{OK: true}, Disabled: true}
+	}
+	health := storage.DataSources.DBHealth()
+	return dbStatus{
+		dependencyStatus: newDependencyStatus(health.Latency, health.Err),
+		OpenConnections:  health.OpenConnections,
+		InUse:            health.InUse,
+		Idle:             health.Idle,
+		WaitCount:        health.WaitCount,
+		WaitMS:           durationMS(health.WaitDuration),
+	}
+}
+
+// checkQueue event queue is saturated when 90% full
+func checkQueue() queueStatus {
+	depth, capacity := ranking.QueueDepth()
+	return queueStatus{
+		Depth:     depth,
+		Capacity:  capacity,
+		Saturated: depth*10 >= capacity*9,
+	}
+}
+
+func newDependencyStatus(latency time.Duration, err error) dependencyStatus {
+	s := dependencyStatus{OK: err == nil, LatencyMS: durationMS(latency)}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
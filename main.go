@@ -1,21 +1,42 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"rangkingserver/config"
+	"rangkingserver/metrics"
+	"rangkingserver/openapi"
 	"rangkingserver/ranking"
+	"rangkingserver/rankingpb"
+	"rangkingserver/ratelimit"
 	"rangkingserver/storage"
+	"rangkingserver/tracing"
+	"rangkingserver/utils"
+	"rangkingserver/webhook"
+	"syscall"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
 	var logger *zap.Logger
 	var err error
 
-	switch config.ServerType {
+	configFile := utils.GetEnv("CONFIG_FILE", "")
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		log.Fatal("cannot load config: ", err)
+	}
+	config.Set(cfg)
+
+	switch cfg.ServerType {
 	case "Production":
 		logger, err = zap.NewProduction()
 		if err != nil {
@@ -28,43 +49,269 @@ func main() {
 		}
 	}
 	zap.ReplaceGlobals(logger)
-	zap.L().Debug("start ranking server: ", zap.String("server-type", config.ServerType))
+	zap.L().Debug("start ranking server: ", zap.String("server-type", cfg.ServerType), zap.String("version", version), zap.String("config-file", configFile))
 	defer logger.Sync()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			logger.Sync()
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := serve(cfg, configFile); err != nil {
+		zap.L().Error("ranking server stopped", zap.Error(err))
+		logger.Sync()
+		os.Exit(1)
+	}
+	zap.L().Info("ranking server stopped")
+}
+
+// serve run http server until SIGINT or SIGTERM then drain requests and events before return
+func serve(cfg *config.Config, configFile string) error {
+	if cfg.DB.MigrateOnStart {
+		migrateOnStart()
+	}
+
+	shutdownTracing, err := tracing.Init(cfg.Tracing, version)
+	if err != nil {
+		return fmt.Errorf("init tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			zap.L().Warn("cannot flush spans", zap.Error(err))
+		}
+	}()
+
 	storage.DataSources = storage.NewDataSource()
 	defer storage.DataSources.Close()
+	storage.DataSources.Store = metrics.InstrumentStore(tracing.TraceStore(storage.DataSources.Store, cfg.Storage.Backend), cfg.Storage.Backend)
+	if storage.DataSources.Events != nil {
+		storage.DataSources.Events = metrics.InstrumentEvents(tracing.TraceEvents(storage.DataSources.Events, cfg.DB.Driver), cfg.DB.Driver)
+	}
+	metrics.RegisterQueueDepth(ranking.QueueDepth)
+	metrics.RegisterBoardMembers(storage.DataSources.Store, func() []string {
+		leaderboard := config.Current().Leaderboard
+		listKeys := []string{leaderboard.EventRankingKey, leaderboard.WorldRankingKey}
+		for _, window := range leaderboard.Windows {
+			listKeys = append(listKeys, window.Name)
+		}
+		return listKeys
+	})
+	ranking.AddMilestoneOutlet(func(reached ranking.MilestoneReached) {
+		zap.L().Info("milestone reached", zap.String("leaderboard", reached.LeaderboardID), zap.String("uid", reached.UID),
+			zap.String("milestone", reached.Milestone), zap.Float64("score", reached.Score), zap.Int64("rank", reached.Rank))
+	})
+	webhooksCtx, stopWebhooks := context.WithCancel(context.Background())
+	defer stopWebhooks()
+	webhooksDone := make(chan struct{})
+	if cfg.Webhooks.Enabled {
+		dispatcher := webhook.NewDispatcher(newWebhookQueue(), nil)
+		if err := dispatcher.Refresh(webhooksCtx); err != nil {
+			return fmt.Errorf("load webhook subscriptions: %v", err)
+		}
+		ranking.SetWebhooks(dispatcher)
+		ranking.AddMilestoneOutlet(func(reached ranking.MilestoneReached) {
+			dispatcher.Emit(webhook.KindMilestoneReached, reached)
+		})
+		go func() {
+			dispatcher.Run(webhooksCtx)
+			close(webhooksDone)
+		}()
+	} else {
+		close(webhooksDone)
+	}
 	// init event loop
-	ranking.InitHandler()
-	ranking.InitRankingSystemData()
+	ranking.InitHandler(storage.DataSources.Store)
+	if cfg.Storage.RebuildFromDB {
+		ranking.InitRankingSystemData()
+	} else {
+		ranking.SkipRankingSystemData()
+	}
+	go reloadConfigOnHangup(configFile)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go ranking.RunDecay(jobsCtx)
+	go ranking.FinalizeTournaments(jobsCtx)
 	// http handle
+	limiter := newLimiter()
+	limited := func(classify ratelimit.Classify, onLimited http.HandlerFunc, handler http.HandlerFunc) func(http.ResponseWriter, *http.Request) {
+		return ratelimit.Middleware(limiter, classify, onLimited, handler).ServeHTTP
+	}
 
-	http.Handle("/saveGamePlayRanking", withCors(ranking.SaveRankingByEvent))
-	http.Handle("/getRankingByEvent", withCors(ranking.GetRankingByEvent))
-	http.Handle("/clearRankingByKey", withCors(ranking.ClearRankingByKey))
-	switch config.ServerType {
-	case "Production":
-		log.Fatal(http.ListenAndServeTLS("0.0.0.0:8444", "certs/fullchain.pem", "certs/privkey.pem", nil))
-	case "Development":
-		log.Fatal(http.ListenAndServeTLS("0.0.0.0:8444", "certs/fullchain_ds.pem", "certs/privkey_ds.pem", nil))
+	mux := http.NewServeMux()
+	mux.Handle("/saveGamePlayRanking", instrument("SaveRankingByEvent", withCors(limited(ranking.RateLimitSaveRanking, ranking.WriteRateLimitedText, ranking.SaveRankingByEvent))))
+	mux.Handle("/getRankingByEvent", instrument("GetRankingByEvent", withCors(limited(ranking.RateLimitGetRanking, ranking.WriteRateLimitedText, ranking.GetRankingByEvent))))
+	mux.Handle("/clearRankingByKey", instrument("ClearRankingByKey", withCors(limited(ranking.RateLimitClearRanking, ranking.WriteRateLimitedText, ranking.ClearRankingByKey))))
+	mux.Handle(ranking.V1Prefix, instrument("LeaderboardsV1", withCors(limited(ranking.RateLimitV1, ranking.WriteRateLimited, ranking.LeaderboardsV1))))
+	mux.Handle(ranking.V1Prefix+"/", instrument("LeaderboardsV1", withCors(limited(ranking.RateLimitV1, ranking.WriteRateLimited, ranking.LeaderboardsV1))))
+	mux.Handle(ranking.V1UsersPrefix+"/", instrument("UsersV1", withCors(limited(ranking.RateLimitUsers, ranking.WriteRateLimited, ranking.UsersV1))))
+	mux.Handle(ranking.V1TournamentsPrefix, instrument("TournamentsV1", withCors(limited(ranking.RateLimitTournaments, ranking.WriteRateLimited, ranking.TournamentsV1))))
+	mux.Handle(ranking.V1TournamentsPrefix+"/", instrument("TournamentsV1", withCors(limited(ranking.RateLimitTournaments, ranking.WriteRateLimited, ranking.TournamentsV1))))
+	mux.Handle(ranking.V1WebhooksPrefix, instrument("WebhooksV1", withCors(limited(ranking.RateLimitWebhooks, ranking.WriteRateLimited, ranking.WebhooksV1))))
+	mux.Handle(ranking.V1WebhooksPrefix+"/", instrument("WebhooksV1", withCors(limited(ranking.RateLimitWebhooks, ranking.WriteRateLimited, ranking.WebhooksV1))))
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/openapi.yaml", openapi.Handler)
+	mux.HandleFunc("/healthz", healthz)
+	mux.HandleFunc("/readyz", readyz)
+	mux.HandleFunc("/status", status)
+	server := &http.Server{
+		Addr:    cfg.Server.ListenAddr,
+		Handler: mux,
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		if cfg.TLS.Enabled {
+			serverErr <- server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCListenAddr != "" {
+		if grpcServer, err = newGRPCServer(cfg, limiter); err != nil {
+			return err
+		}
+		listener, err := net.Listen("tcp", cfg.Server.GRPCListenAddr)
+		if err != nil {
+			return fmt.Errorf("listen grpc: %v", err)
+		}
+		zap.L().Info("grpc listen", zap.String("addr", cfg.Server.GRPCListenAddr))
+		go func() {
+			serverErr <- grpcServer.Serve(listener)
+		}()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-serverErr:
+		return err
+	case sig := <-stop:
+		zap.L().Info("shutting down", zap.String("signal", sig.String()), zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	// stop accepting and wait in-flight requests, their events are done when handlers return
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("drain http requests: %v", err)
+	}
+	if grpcServer != nil {
+		ranking.CloseSubscriptions()
+		if err := stopGRPCServer(ctx, grpcServer); err != nil {
+			return fmt.Errorf("drain grpc calls: %v", err)
+		}
+	}
+	// finish events queued by background work such as initial rebuild, decay job and tournament finalizer queue no more events
+	stopJobs()
+	if err := ranking.Shutdown(ctx); err != nil {
+		return fmt.Errorf("drain event loop: %v", err)
+	}
+	// enqueue webhook events emitted by drained events, deliveries in flight are retried after restart
+	stopWebhooks()
+	select {
+	case <-webhooksDone:
+	case <-ctx.Done():
+		return fmt.Errorf("drain webhook events: %v", ctx.Err())
+	}
+	return nil
+}
+
+// newGRPCServer create gRPC server of Ranking service, use same certificate as https when tls is enabled
+func newGRPCServer(cfg *config.Config, limiter ratelimit.Limiter) (*grpc.Server, error) {
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor, tracing.UnaryServerInterceptor, ratelimit.UnaryServerInterceptor(limiter, ranking.RateLimitRPC)),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor, tracing.StreamServerInterceptor, ratelimit.StreamServerInterceptor(limiter, ranking.RateLimitRPC)),
+	}
+	if cfg.TLS.Enabled {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load grpc certificate: %v", err)
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(options...)
+	rankingpb.RegisterRankingServer(grpcServer, ranking.NewGRPCServer())
+	return grpcServer, nil
+}
+
+// newLimiter keep rate limit buckets in redis when storage use redis so every replica share them
+func newLimiter() ratelimit.Limiter {
+	if storage.DataSources.RedisClient != nil {
+		return ratelimit.NewRedisLimiter(storage.DataSources.RedisClient)
+	}
+	zap.L().Info("rate limit buckets are kept in process memory, limits are per replica")
+	return ratelimit.NewMemoryLimiter()
+}
+
+// newWebhookQueue keep webhook queue in redis when storage use redis, else in DB tables of migration 0003
+func newWebhookQueue() webhook.Queue {
+	if storage.DataSources.RedisClient != nil {
+		return webhook.NewRedisQueue(storage.DataSources.RedisClient)
 	}
+	return webhook.NewSQLQueue(storage.DataSources.DB)
 }
 
-func handle(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, "Please add choices before spin.")
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte("This is an example server.\n"))
+// stopGRPCServer wait in-flight calls until ctx is done then close remaining connections
+func stopGRPCServer(ctx context.Context, grpcServer *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// reloadConfigOnHangup reload config file on SIGHUP, connection settings keep value from start
+func reloadConfigOnHangup(configFile string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		ignored, err := config.Reload(configFile)
+		if err != nil {
+			zap.L().Error("cannot reload config, keep current config", zap.Error(err))
+			continue
+		}
+		if len(ignored) > 0 {
+			zap.L().Warn("config reloaded, changed settings need restart", zap.Strings("ignored", ignored))
+			continue
+		}
+		zap.L().Info("config reloaded")
+	}
+}
+
+// instrument add metrics and tracing to handler
+func instrument(name string, handler http.Handler) http.Handler {
+	return metrics.InstrumentHandler(name, tracing.Middleware(name, handler))
 }
 
 func withCors(handler func(w http.ResponseWriter, r *http.Request)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		addCors(&w)
+		addCors(&w, r.Header.Get("Origin"))
 		handler(w, r)
 	})
 }
 
-func addCors(w *http.ResponseWriter) {
+func addCors(w *http.ResponseWriter, origin string) {
+	allowOrigin := config.Current().AllowOrigin(origin)
+	if allowOrigin == "" {
+		return
+	}
 	(*w).Header().Set("Access-Control-Allow-Credentials", "true")
 	(*w).Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization")
-	(*w).Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
+	(*w).Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	(*w).Header().Set("Access-Control-Allow-Origin", allowOrigin)
 }
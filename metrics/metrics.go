@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ranking"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by handler and status code.",
+	}, []string{"handler", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests by handler.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler"})
+
+	eventDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "event_processing_duration_seconds",
+		Help:      "Time event loop spend on one event by event type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"event"})
+
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_call_duration_seconds",
+		Help:      "Latency of storage calls by backend and operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 5},
+	}, []string{"backend", "operation"})
+
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_call_errors_total",
+		Help:      "Number of failed storage calls by backend and operation.",
+	}, []string{"backend", "operation"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by rate limit by rule.",
+	}, []string{"rule"})
+
+	rebuildDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rebuild_duration_seconds",
+		Help:      "Duration of last ranking rebuild from DB.",
+	})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Number of webhook delivery attempts and dropped events by event kind and result.",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequests, httpDuration, eventDuration, storageDuration, storageErrors, rateLimited, rebuildDuration, webhookDeliveries)
+}
+
+// Handler serve /metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// InstrumentHandler count requests and observe latency of handler by name
+func InstrumentHandler(name string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		handler.ServeHTTP(sw, r)
+		httpDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		httpRequests.WithLabelValues(name, strconv.Itoa(sw.statusCode)).Inc()
+	})
+}
+
+// ObserveEvent observe time event loop spend on event
+func ObserveEvent(event string, start time.Time) {
+	eventDuration.WithLabelValues(event).Observe(time.Since(start).Seconds())
+}
+
+// ObserveStorage observe latency and count error of storage call
+func ObserveStorage(backend string, operation string, start time.Time, err error) {
+	storageDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		storageErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// CountRateLimited count request rejected by rate limit rule
+func CountRateLimited(rule string) {
+	rateLimited.WithLabelValues(rule).Inc()
+}
+
+// CountWebhookDelivery count webhook delivery attempt by result delivered, retry or failed, or event dropped
+func CountWebhookDelivery(kind string, result string) {
+	webhookDeliveries.WithLabelValues(kind, result).Inc()
+}
+
+// SetRebuildDuration keep duration of last rebuild
+func SetRebuildDuration(d time.Duration) {
+	rebuildDuration.Set(d.Seconds())
+}
+
+// RegisterQueueDepth export event queue depth and capacity from depth func
+func RegisterQueueDepth(depth func() (int, int)) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "event_queue_depth",
+			Help:      "Number of events waiting in event loop queue.",
+		}, func() float64 {
+			d, _ := depth()
+			return float64(d)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "event_queue_capacity",
+			Help:      "Capacity of event loop queue.",
+		}, func() float64 {
+			_, c := depth()
+			return float64(c)
+		}),
+	)
+}
+
+// statusWriter keep status code written by handler
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	sw.statusCode = statusCode
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
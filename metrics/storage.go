@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"context"
+	"rangkingserver/storage"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// instrumentedStore LeaderboardStore that observe every call of wrapped store
+type instrumentedStore struct {
+	store   storage.LeaderboardStore
+	backend string
+}
+
+// InstrumentStore wrap store to observe latency and errors of every call by operation
+func InstrumentStore(store storage.LeaderboardStore, backend string) storage.LeaderboardStore {
+	return &instrumentedStore{store: store, backend: backend}
+}
+
+func (is *instrumentedStore) IncreaseScore(ctx context.Context, rankingName string, score float64, uid string, listKey string) error {
+	start := time.Now()
+	err := is.store.IncreaseScore(ctx, rankingName, score, uid, listKey)
+	ObserveStorage(is.backend, "IncreaseScore", start, err)
+	return err
+}
+
+func (is *instrumentedStore) SetScore(ctx context.Context, rankingName string, score float64, uid string) error {
+	start := time.Now()
+	err := is.store.SetScore(ctx, rankingName, score, uid)
+	ObserveStorage(is.backend, "SetScore", start, err)
+	return err
+}
+
+func (is *instrumentedStore) GetScore(ctx context.Context, rankingName string, uid string) (float64, error) {
+	start := time.Now()
+	score, err := is.store.GetScore(ctx, rankingName, uid)
+	ObserveStorage(is.backend, "GetScore", start, ignoreNotFound(err))
+	return score, err
+}
+
+func (is *instrumentedStore) GetRank(ctx context.Context, rankingName string, uid string) (int64, error) {
+	start := time.Now()
+	rank, err := is.store.GetRank(ctx, rankingName, uid)
+	ObserveStorage(is.backend, "GetRank", start, ignoreNotFound(err))
+	return rank, err
+}
+
+func (is *instrumentedStore) GetRange(ctx context.Context, rankingName string, minScore float64, count int64) ([]storage.Member, error) {
+	start := time.Now()
+	members, err := is.store.GetRange(ctx, rankingName, minScore, count)
+	ObserveStorage(is.backend, "GetRange", start, err)
+	return members, err
+}
+
+func (is *instrumentedStore) GetRankRange(ctx context.Context, rankingName string, start int64, stop int64) ([]storage.Member, error) {
+	begin := time.Now()
+	members, err := is.store.GetRankRange(ctx, rankingName, start, stop)
+	ObserveStorage(is.backend, "GetRankRange", begin, err)
+	return members, err
+}
+
+func (is *instrumentedStore) GetScores(ctx context.Context, rankingName string, uids []string) ([]storage.Member, error) {
+	start := time.Now()
+	members, err := is.store.GetScores(ctx, rankingName, uids)
+	ObserveStorage(is.backend, "GetScores", start, err)
+	return members, err
+}
+
+func (is *instrumentedStore) Count(ctx context.Context, rankingName string) (int64, error) {
+	start := time.Now()
+	count, err := is.store.Count(ctx, rankingName)
+	ObserveStorage(is.backend, "Count", start, err)
+	return count, err
+}
+
+func (is *instrumentedStore) RemoveMember(ctx context.Context, rankingName string, uid string) error {
+	start := time.Now()
+	err := is.store.RemoveMember(ctx, rankingName, uid)
+	ObserveStorage(is.backend, "RemoveMember", start, err)
+	return err
+}
+
+func (is *instrumentedStore) Delete(ctx context.Context, rankingName string) error {
+	start := time.Now()
+	err := is.store.Delete(ctx, rankingName)
+	ObserveStorage(is.backend, "Delete", start, err)
+	return err
+}
+
+func (is *instrumentedStore) ClearAll(ctx context.Context, listKey string) (int64, error) {
+	start := time.Now()
+	result, err := is.store.ClearAll(ctx, listKey)
+	ObserveStorage(is.backend, "ClearAll", start, err)
+	return result, err
+}
+
+func (is *instrumentedStore) ListRankings(ctx context.Context, listKey string) ([]string, error) {
+	start := time.Now()
+	names, err := is.store.ListRankings(ctx, listKey)
+	ObserveStorage(is.backend, "ListRankings", start, err)
+	return names, err
+}
+
+func (is *instrumentedStore) UnionStore(ctx context.Context, destination string, sources []string, listKey string) error {
+	start := time.Now()
+	err := is.store.UnionStore(ctx, destination, sources, listKey)
+	ObserveStorage(is.backend, "UnionStore", start, err)
+	return err
+}
+
+func (is *instrumentedStore) ReplaceRanking(ctx context.Context, rankingName string, archiveName string, archiveListKey string, members []storage.Member) error {
+	start := time.Now()
+	err := is.store.ReplaceRanking(ctx, rankingName, archiveName, archiveListKey, members)
+	ObserveStorage(is.backend, "ReplaceRanking", start, err)
+	return err
+}
+
+func (is *instrumentedStore) AddFriends(ctx context.Context, uid string, friends []string) error {
+	start := time.Now()
+	err := is.store.AddFriends(ctx, uid, friends)
+	ObserveStorage(is.backend, "AddFriends", start, err)
+	return err
+}
+
+func (is *instrumentedStore) RemoveFriends(ctx context.Context, uid string, friends []string) error {
+	start := time.Now()
+	err := is.store.RemoveFriends(ctx, uid, friends)
+	ObserveStorage(is.backend, "RemoveFriends", start, err)
+	return err
+}
+
+func (is *instrumentedStore) GetFriends(ctx context.Context, uid string) ([]string, error) {
+	start := time.Now()
+	friends, err := is.store.GetFriends(ctx, uid)
+	ObserveStorage(is.backend, "GetFriends", start, err)
+	return friends, err
+}
+
+func (is *instrumentedStore) GetTeam(ctx context.Context, uid string) (string, error) {
+	start := time.Now()
+	team, err := is.store.GetTeam(ctx, uid)
+	ObserveStorage(is.backend, "GetTeam", start, err)
+	return team, err
+}
+
+func (is *instrumentedStore) SetTeam(ctx context.Context, uid string, team string) error {
+	start := time.Now()
+	err := is.store.SetTeam(ctx, uid, team)
+	ObserveStorage(is.backend, "SetTeam", start, err)
+	return err
+}
+
+func (is *instrumentedStore) GetTeamMembers(ctx context.Context, team string) ([]string, error) {
+	start := time.Now()
+	members, err := is.store.GetTeamMembers(ctx, team)
+	ObserveStorage(is.backend, "GetTeamMembers", start, err)
+	return members, err
+}
+
+func (is *instrumentedStore) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := is.store.Ping(ctx)
+	ObserveStorage(is.backend, "Ping", start, err)
+	return err
+}
+
+func (is *instrumentedStore) Close() error {
+	return is.store.Close()
+}
+
+// instrumentedEvents EventRepository that observe every call of wrapped repository
+type instrumentedEvents struct {
+	events  storage.EventRepository
+	backend string
+}
+
+// InstrumentEvents wrap event repository to observe latency and errors of every call
+func InstrumentEvents(events storage.EventRepository, backend string) storage.EventRepository {
+	return &instrumentedEvents{events: events, backend: backend}
+}
+
+func (ie *instrumentedEvents) GetAllUserEventData(ctx context.Context, since time.Time) ([]storage.UserData, error) {
+	start := time.Now()
+	userDataList, err := ie.events.GetAllUserEventData(ctx, since)
+	ObserveStorage(ie.backend, "GetAllUserEventData", start, err)
+	return userDataList, err
+}
+
+func (ie *instrumentedEvents) Close() error {
+	return ie.events.Close()
+}
+
+func ignoreNotFound(err error) error {
+	if err == storage.ErrMemberNotFound {
+		return nil
+	}
+	return err
+}
+
+// boardCollector export member count of every ranking under list keys when scraped
+type boardCollector struct {
+	store    storage.LeaderboardStore
+	listKeys func() []string
+	members  *prometheus.Desc
+}
+
+// RegisterBoardMembers export member count of every ranking registered under listKeys
+func RegisterBoardMembers(store storage.LeaderboardStore, listKeys func() []string) {
+	prometheus.MustRegister(&boardCollector{
+		store:    store,
+		listKeys: listKeys,
+		members: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "leaderboard_members"),
+			"Number of members in leaderboard.",
+			[]string{"list_key", "leaderboard"}, nil,
+		),
+	})
+}
+
+func (bc *boardCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bc.members
+}
+
+func (bc *boardCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	for _, listKey := range bc.listKeys() {
+		rankingNames, err := bc.store.ListRankings(ctx, listKey)
+		if err != nil {
+			zap.L().Warn("metrics cannot list rankings", zap.String("key", listKey), zap.Error(err))
+			continue
+		}
+		for _, rankingName := range rankingNames {
+			count, err := bc.store.Count(ctx, rankingName)
+			if err != nil {
+				zap.L().Warn("metrics cannot count ranking", zap.String("ranking", rankingName), zap.Error(err))
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(bc.members, prometheus.GaugeValue, float64(count), listKey, rankingName)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"rangkingserver/config"
+	"rangkingserver/migration"
+	"rangkingserver/storage"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runMigrate run migrate subcommand, command is up, down or status
+func runMigrate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: rangkingserver migrate up|down|status")
+	}
+
+	db, err := storage.OpenDB(config.Current().DB.Driver)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migration.NewMigrator(db.DB, config.Current().DB.Driver)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migrations\n", count)
+	case "down":
+		rolledBack, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		if !rolledBack {
+			fmt.Println("no migration to roll back")
+		}
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d %-40s %s\n", s.Version, s.Name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	return nil
+}
+
+// migrateOnStart apply pending migrations before serving
+func migrateOnStart() {
+	db, err := storage.OpenDB(config.Current().DB.Driver)
+	if err != nil {
+		zap.L().Fatal("cannot open db for migration", zap.Error(err))
+	}
+	defer db.Close()
+
+	migrator, err := migration.NewMigrator(db.DB, config.Current().DB.Driver)
+	if err != nil {
+		zap.L().Fatal("cannot load migrations", zap.Error(err))
+	}
+	count, err := migrator.Up()
+	if err != nil {
+		zap.L().Fatal("cannot apply migrations", zap.Error(err))
+	}
+	zap.L().Info("migrations up to date", zap.Int("applied", count))
+}
@@ -0,0 +1,271 @@
+package migration
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// migration files are <version>_<name>.up.sql and <version>_<name>.down.sql in directory of driver name
+//
+//go:embed mysql/*.sql postgres/*.sql
+var files embed.FS
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is migration and time it was applied, AppliedAt is nil when not applied
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator apply embedded migrations of driver to db and record them in schema_version
+type Migrator struct {
+	db         *sql.DB
+	driverName string
+	migrations []Migration
+}
+
+// NewMigrator create Migrator for driver name, mysql or postgres
+func NewMigrator(db *sql.DB, driverName string) (*Migrator, error) {
+	migrations, err := Load(driverName)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		driverName: driverName,
+		migrations: migrations,
+	}, nil
+}
+
+// Load read embedded migrations of driver sorted by version
+func Load(driverName string) ([]Migration, error) {
+	entries, err := files.ReadDir(driverName)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driverName)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("invalid migration file %s", fileName)
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file %s", fileName)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %v", fileName, err)
+		}
+
+		body, err := files.ReadFile(path.Join(driverName, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		} else if m.Name != parts[1] {
+			return nil, fmt.Errorf("migration version %d has two names %s and %s", version, m.Name, parts[1])
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration version %d has no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up apply all migrations not in schema_version, return number of applied migrations
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.run(migration.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(m.bind("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)"),
+				migration.Version, migration.Name, time.Now().UTC())
+			return err
+		}); err != nil {
+			return count, fmt.Errorf("migration %d_%s up: %v", migration.Version, migration.Name, err)
+		}
+		zap.L().Info("migration applied", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+		count++
+	}
+	return count, nil
+}
+
+// Down roll back latest applied migration, return false when nothing to roll back
+func (m *Migrator) Down() (bool, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return false, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return false, fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+		if err := m.run(migration.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(m.bind("DELETE FROM schema_version WHERE version = ?"), migration.Version)
+			return err
+		}); err != nil {
+			return false, fmt.Errorf("migration %d_%s down: %v", migration.Version, migration.Name, err)
+		}
+		zap.L().Info("migration rolled back", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+		return true, nil
+	}
+	return false, nil
+}
+
+// Status get all migrations with time applied
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// applied create schema_version when not exist and get applied versions
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	if _, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+  version integer NOT NULL PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  applied_at timestamp NOT NULL
+)`); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// run exec statements of migration and record it in one transaction.
+// mysql commit DDL implicitly so only the version record is atomic there.
+func (m *Migrator) run(body string, record func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range splitStatements(body) {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// bind replace ? placeholder with $n for postgres
+func (m *Migrator) bind(query string) string {
+	if m.driverName != "postgres" {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// splitStatements split file body by ; at end of line
+func splitStatements(body string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(body, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			if statement := strings.TrimSpace(current.String()); statement != ";" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		}
+	}
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `play_event` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `event_type` int(11) NOT NULL,
+  `uid` bigint(20) NOT NULL,
+  `value` int(11) NOT NULL DEFAULT 0,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `timestamp` (`timestamp`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
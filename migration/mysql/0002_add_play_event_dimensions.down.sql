@@ -0,0 +1 @@
+ALTER TABLE `play_event` DROP COLUMN `dimensions`;
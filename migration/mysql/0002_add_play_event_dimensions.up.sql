@@ -0,0 +1 @@
+ALTER TABLE `play_event` ADD COLUMN `dimensions` varchar(255) NOT NULL DEFAULT '';
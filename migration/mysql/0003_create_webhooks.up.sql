@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS `webhook_subscription` (
+  `id` varchar(32) NOT NULL,
+  `url` varchar(2048) NOT NULL,
+  `kinds` varchar(255) NOT NULL,
+  `secret` varchar(255) NOT NULL,
+  `created_at` datetime(3) NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+CREATE TABLE IF NOT EXISTS `webhook_delivery` (
+  `id` varchar(32) NOT NULL,
+  `subscription_id` varchar(32) NOT NULL,
+  `event_id` varchar(32) NOT NULL,
+  `kind` varchar(32) NOT NULL,
+  `payload` mediumtext NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime(3) NOT NULL,
+  `last_attempt_at` datetime(3) NULL DEFAULT NULL,
+  `response_status` int(11) NOT NULL DEFAULT 0,
+  `last_error` varchar(1024) NOT NULL DEFAULT '',
+  `created_at` datetime(3) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `status_next_attempt_at` (`status`, `next_attempt_at`),
+  KEY `subscription_id_created_at` (`subscription_id`, `created_at`),
+  KEY `created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
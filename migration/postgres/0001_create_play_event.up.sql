@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "play_event" (
+  "id" serial PRIMARY KEY,
+  "event_type" integer NOT NULL,
+  "uid" bigint NOT NULL,
+  "value" integer NOT NULL DEFAULT 0,
+  "timestamp" timestamp NOT NULL DEFAULT current_timestamp
+);
+CREATE INDEX IF NOT EXISTS "play_event_timestamp" ON "play_event" ("timestamp");
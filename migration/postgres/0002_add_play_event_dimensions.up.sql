@@ -0,0 +1 @@
+ALTER TABLE "play_event" ADD COLUMN IF NOT EXISTS "dimensions" varchar(255) NOT NULL DEFAULT '';
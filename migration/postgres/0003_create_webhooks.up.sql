@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS "webhook_subscription" (
+  "id" varchar(32) PRIMARY KEY,
+  "url" varchar(2048) NOT NULL,
+  "kinds" varchar(255) NOT NULL,
+  "secret" varchar(255) NOT NULL,
+  "created_at" timestamp(3) NOT NULL
+);
+CREATE TABLE IF NOT EXISTS "webhook_delivery" (
+  "id" varchar(32) PRIMARY KEY,
+  "subscription_id" varchar(32) NOT NULL,
+  "event_id" varchar(32) NOT NULL,
+  "kind" varchar(32) NOT NULL,
+  "payload" text NOT NULL,
+  "status" varchar(16) NOT NULL,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamp(3) NOT NULL,
+  "last_attempt_at" timestamp(3) NULL,
+  "response_status" integer NOT NULL DEFAULT 0,
+  "last_error" varchar(1024) NOT NULL DEFAULT '',
+  "created_at" timestamp(3) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS "webhook_delivery_status_next_attempt_at" ON "webhook_delivery" ("status", "next_attempt_at");
+CREATE INDEX IF NOT EXISTS "webhook_delivery_subscription_id_created_at" ON "webhook_delivery" ("subscription_id", "created_at");
+CREATE INDEX IF NOT EXISTS "webhook_delivery_created_at" ON "webhook_delivery" ("created_at");
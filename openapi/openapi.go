@@ -0,0 +1,18 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec is OpenAPI 3 document of ranking server, keep it in step with handlers and client package
+//
+//go:embed openapi.yaml
+var Spec []byte
+
+// Handler serve Spec
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(Spec)
+}
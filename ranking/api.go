@@ -0,0 +1,333 @@
+package ranking
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"rangkingserver/config"
+	"strconv"
+	"strings"
+)
+
+const (
+	// V1Prefix is path of v1 leaderboards collection
+	V1Prefix = "/v1/leaderboards"
+	// maxEntriesLimit is largest limit of entries query
+	maxEntriesLimit = 1000
+	// maxBodyBytes is largest request body accepted
+	maxBodyBytes = 1 << 20
+)
+
+type apiResponse struct {
+	data interface{}
+	err  error
+}
+
+// LeaderboardsV1 route /v1/leaderboards and /v1/leaderboards/{id}/...
+//
+//	DELETE /v1/leaderboards?period={period}          clear every leaderboard of period
+//	GET    /v1/leaderboards/seasons                  current season and archived seasons
+//	POST   /v1/leaderboards/seasons                  end season, archive leaderboards of event ranking key and carry scores over
+//	POST   /v1/leaderboards/{id}/scores              add amount to score of uid
+//	POST   /v1/leaderboards/{id}/matches             update ratings of players of match, rating leaderboard only
+//	GET    /v1/leaderboards/{id}/entries?limit=      top entries
+//	GET    /v1/leaderboards/{id}/entries/{uid}       rank and score of uid
+//	GET    /v1/leaderboards/{id}/friends/{uid}       entries of uid and friends, ?friends=a,b or friend set of uid
+//	GET    /v1/leaderboards/{id}/teams?limit=        top teams
+//	GET    /v1/leaderboards/{id}/teams/{team}        rank and score of team with contribution of members
+//	GET    /v1/leaderboards/{id}/leagues/{uid}       group of uid in current league season
+//	GET    /v1/leaderboards/{id}/leagues/{uid}/history  results of uid in ended seasons
+//	GET    /v1/leaderboards/{id}/ratings/{uid}       rating, deviation and matches of uid
+//	GET    /v1/leaderboards/{id}/seasons/{season}/entries?limit=  top entries of archived season
+//	GET    /v1/leaderboards/{id}/milestones/{uid}    milestones of leaderboard and whether uid reached them
+//
+// Entries select slice of leaderboard by dimension query parameters, ex. ?game_mode=1&region=eu
+func LeaderboardsV1(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, V1Prefix), "/")
+	if path == "" {
+		if !allowMethod(w, r, http.MethodDelete) {
+			return
+		}
+		clearLeaderboards(w, r)
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	leaderboardID := segments[0]
+	switch {
+	case len(segments) == 1 && segments[0] == "seasons":
+		if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+			return
+		}
+		if r.Method == http.MethodPost {
+			endSeason(w, r)
+			return
+		}
+		getSeasons(w, r)
+	case len(segments) == 2 && segments[1] == "scores":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		submitScoreV1(w, r, leaderboardID)
+	case len(segments) == 2 && segments[1] == "matches":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		submitMatch(w, r, leaderboardID)
+	case len(segments) == 2 && segments[1] == "entries":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getEntries(w, r, leaderboardID)
+	case len(segments) == 3 && segments[1] == "entries" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getEntry(w, r, leaderboardID, segments[2])
+	case len(segments) == 3 && segments[1] == "friends" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getFriendEntries(w, r, leaderboardID, segments[2])
+	case len(segments) == 2 && segments[1] == "teams":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getTeams(w, r, leaderboardID)
+	case len(segments) == 3 && segments[1] == "teams" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getTeam(w, r, leaderboardID, segments[2])
+	case len(segments) == 3 && segments[1] == "leagues" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getLeague(w, r, leaderboardID, segments[2])
+	case len(segments) == 4 && segments[1] == "leagues" && segments[2] != "" && segments[3] == "history":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getLeagueHistory(w, r, leaderboardID, segments[2])
+	case len(segments) == 3 && segments[1] == "ratings" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getRating(w, r, leaderboardID, segments[2])
+	case len(segments) == 4 && segments[1] == "seasons" && segments[2] != "" && segments[3] == "entries":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getSeasonEntries(w, r, leaderboardID, segments[2])
+	case len(segments) == 3 && segments[1] == "milestones" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getMilestones(w, r, leaderboardID, segments[2])
+	default:
+		writeError(w, r, newAPIError(http.StatusNotFound, CodeNotFound, "no route for "+r.URL.Path))
+	}
+}
+
+// allowMethod write method not allowed error when method of r is not one of methods
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", ")+", OPTIONS")
+	writeError(w, r, newAPIError(http.StatusMethodNotAllowed, CodeMethodNotAllowed,
+		fmt.Sprintf("method %s is not allowed, use %s", r.Method, strings.Join(methods, " or "))))
+	return false
+}
+
+func submitScoreV1(w http.ResponseWriter, r *http.Request, leaderboardID string) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, r, newAPIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be application/json"))
+		return
+	}
+	var body ScoreRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, r, invalidArgument(map[string]string{"body": err.Error()}))
+		return
+	}
+
+	details := make(map[string]string)
+	if body.UID == "" {
+		details["uid"] = "is required"
+	}
+	if body.Amount == nil {
+		details["amount"] = "is required"
+	} else if math.IsNaN(*body.Amount) || math.IsInf(*body.Amount, 0) {
+		details["amount"] = "must be finite number"
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- submitScoreEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		uid:            body.UID,
+		amount:         *body.Amount,
+		dimensions:     body.Dimensions,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getEntries(w http.ResponseWriter, r *http.Request, leaderboardID string) {
+	details := make(map[string]string)
+	period := queryBoardPeriod(r, details)
+	limit := config.Current().Leaderboard.Limit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed == 0 {
+			parsed = -1
+		}
+		limit = checkLimit(parsed, details)
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	slice, err := querySlice(leaderboardID, queryDimensions(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getEntriesEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		period:         period,
+		limit:          limit,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getEntry(w http.ResponseWriter, r *http.Request, leaderboardID string, uid string) {
+	details := make(map[string]string)
+	period := queryBoardPeriod(r, details)
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	slice, err := querySlice(leaderboardID, queryDimensions(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getEntryEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		period:         period,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func clearLeaderboards(w http.ResponseWriter, r *http.Request) {
+	details := make(map[string]string)
+	if r.URL.Query().Get("period") == "" {
+		details["period"] = "is required"
+	}
+	period := queryPeriod(r, details)
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	if err := endLeagueGroups(r.Context(), period); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- clearLeaderboardsEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		period:         period,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// queryPeriod get period query parameter, event ranking key when empty, issue is added to details when period is unknown
+func queryPeriod(r *http.Request, details map[string]string) string {
+	return checkPeriod(r.URL.Query().Get("period"), details)
+}
+
+// checkPeriod get period, event ranking key when empty, issue is added to details when period is unknown
+func checkPeriod(period string, details map[string]string) string {
+	leaderboard := config.Current().Leaderboard
+	switch period {
+	case "":
+		return leaderboard.EventRankingKey
+	case leaderboard.EventRankingKey, leaderboard.WorldRankingKey:
+		return period
+	default:
+		if _, ok := details["period"]; !ok {
+			details["period"] = fmt.Sprintf("must be %s or %s", leaderboard.EventRankingKey, leaderboard.WorldRankingKey)
+		}
+		return period
+	}
+}
+
+// queryBoardPeriod get period query parameter like queryPeriod, rolling window is also accepted
+func queryBoardPeriod(r *http.Request, details map[string]string) string {
+	return checkBoardPeriod(r.URL.Query().Get("period"), details)
+}
+
+// checkBoardPeriod get period like checkPeriod, rolling window is also accepted
+func checkBoardPeriod(period string, details map[string]string) string {
+	if _, ok := config.Current().Window(period); ok {
+		return period
+	}
+	_, reported := details["period"]
+	period = checkPeriod(period, details)
+	if _, ok := details["period"]; ok && !reported && len(config.Current().Leaderboard.Windows) > 0 {
+		names := make([]string, 0, len(config.Current().Leaderboard.Windows))
+		for _, window := range config.Current().Leaderboard.Windows {
+			names = append(names, window.Name)
+		}
+		details["period"] += " or window " + strings.Join(names, ", ")
+	}
+	return period
+}
+
+// checkLimit get limit, leaderboard limit when 0, issue is added to details when limit is out of range
+func checkLimit(limit int64, details map[string]string) int64 {
+	if limit == 0 {
+		return config.Current().Leaderboard.Limit
+	}
+	if limit < 1 || limit > maxEntriesLimit {
+		details["limit"] = fmt.Sprintf("must be integer from 1 to %d", maxEntriesLimit)
+	}
+	return limit
+}
+
+func writeAPIResponse(w http.ResponseWriter, r *http.Request, response apiResponse) {
+	if response.err != nil {
+		writeError(w, r, response.err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, response.data)
+}
@@ -0,0 +1,263 @@
+package ranking
+
+import (
+	"context"
+	"math"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"rangkingserver/tracing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// decay state of leaderboard with decay policy, registered under decayListKey(period) and cleared with period
+//
+//	decay:{event type}:activity   uid -> minus unix time of last score, least active uid rank first
+//	decay:{event type}:decayed    uid -> unix time decay of uid is applied until
+//	decay:{event type}:boards     ranking names of leaderboard and slices that got score
+//	decay:{event type}:debt:{ranking name}  uid -> score decay took from uid in ranking, taken again after rebuild from DB
+func decayName(eventType string, name string) string {
+	return "decay:" + eventType + ":" + name
+}
+
+// decayListKey get list key of decay state of period
+func decayListKey(period string) string {
+	return "decay:" + period
+}
+
+// decayProgress is result of one decay batch, next is offset of next batch in activity set
+type decayProgress struct {
+	next int64
+	done bool
+}
+
+// decayPolicy get decay policy of leaderboard, zero policy when leaderboard does not decay
+func decayPolicy(eventType string) config.DecayPolicy {
+	definition, _ := config.Current().Definition(eventType)
+	return definition.Decay
+}
+
+// recordActivity set last activity of uid to now and keep ranking names of its score for decay job
+func recordActivity(ctx context.Context, eventType string, rankingNames []string, uid string, now time.Time) error {
+	if decayPolicy(eventType).Percent <= 0 {
+		return nil
+	}
+	listKey := decayListKey(config.Current().Leaderboard.EventRankingKey)
+	if err := addDecayBoards(ctx, eventType, rankingNames, listKey); err != nil {
+		return err
+	}
+	return setRegistered(ctx, decayName(eventType, "activity"), -float64(now.Unix()), uid, listKey)
+}
+
+// recordRebuiltActivity keep ranking names of rebuilt score for decay job, uid without activity is active at now
+func recordRebuiltActivity(ctx context.Context, eventType string, rankingNames []string, uid string, now time.Time) error {
+	if decayPolicy(eventType).Percent <= 0 {
+		return nil
+	}
+	listKey := decayListKey(config.Current().Leaderboard.EventRankingKey)
+	if err := addDecayBoards(ctx, eventType, rankingNames, listKey); err != nil {
+		return err
+	}
+	_, err := store.GetScore(ctx, decayName(eventType, "activity"), uid)
+	if err != storage.ErrMemberNotFound {
+		return err
+	}
+	return setRegistered(ctx, decayName(eventType, "activity"), -float64(now.Unix()), uid, listKey)
+}
+
+func addDecayBoards(ctx context.Context, eventType string, rankingNames []string, listKey string) error {
+	for _, rankingName := range rankingNames {
+		if err := store.IncreaseScore(ctx, decayName(eventType, "boards"), 0, rankingName, listKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decayDebtName get name of set of score decay took from uids in rankingName
+func decayDebtName(eventType string, rankingName string) string {
+	return decayName(eventType, "debt:"+rankingName)
+}
+
+// applyDecayDebt take decay of every uid again from rebuilt scores, so rebuilt scores are sums without decay minus decay already applied
+func applyDecayDebt(ctx context.Context) error {
+	eventRankingKey := config.Current().Leaderboard.EventRankingKey
+	for _, definition := range config.Current().Leaderboard.Definitions {
+		boards, err := store.GetRange(ctx, decayName(definition.EventType, "boards"), 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, board := range boards {
+			debts, err := store.GetRange(ctx, decayDebtName(definition.EventType, board.UID), 0, 0)
+			if err != nil {
+				return err
+			}
+			for _, debt := range debts {
+				if err := store.IncreaseScore(ctx, board.UID, -debt.Score, debt.UID, eventRankingKey); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// clearDecayDebt forget decay taken from every uid, scores it was taken from are no longer rebuilt from DB
+func clearDecayDebt(ctx context.Context) error {
+	for _, definition := range config.Current().Leaderboard.Definitions {
+		boards, err := store.GetRange(ctx, decayName(definition.EventType, "boards"), 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, board := range boards {
+			if err := store.Delete(ctx, decayDebtName(definition.EventType, board.UID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RunDecay apply decay policies every leaderboard.decay.interval until ctx is done.
+// Batches are queued to event loop one at a time, so requests queued meanwhile are served between them.
+func RunDecay(ctx context.Context) {
+	for {
+		timer := time.NewTimer(config.Current().Leaderboard.Decay.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !RankingSystemDataReady() {
+			continue
+		}
+		if err := decayLeaderboards(ctx, clock()); err != nil {
+			return
+		}
+	}
+}
+
+// decayLeaderboards decay every leaderboard with decay policy at now, error of leaderboard is logged and only ctx error is returned
+func decayLeaderboards(ctx context.Context, now time.Time) error {
+	for _, definition := range config.Current().Leaderboard.Definitions {
+		if definition.Decay.Percent <= 0 {
+			continue
+		}
+		if err := decayLeaderboard(ctx, definition.EventType, now); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			zap.L().Warn("cannot decay leaderboard", zap.String("event_type", definition.EventType), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// decayLeaderboard queue decay batches of event type until every inactive uid is decayed
+func decayLeaderboard(ctx context.Context, eventType string, now time.Time) error {
+	var offset int64
+	for {
+		responseCh := make(chan apiResponse, 1)
+		select {
+		case eventCh <- decayBatchEvent{
+			requestContext: newRequestContext(ctx),
+			responseCh:     responseCh,
+			now:            now,
+			eventType:      eventType,
+			offset:         offset,
+		}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		response := <-responseCh
+		if response.err != nil {
+			return response.err
+		}
+		progress := response.data.(decayProgress)
+		if progress.done {
+			return nil
+		}
+		offset = progress.next
+	}
+}
+
+// handleDecayBatch decay scores of batch size uids from offset of activity set, least active first.
+// Batch stop at first uid still in grace. Uid that score between batches move to end and may be skipped, next run decay it for whole time.
+func handleDecayBatch(ctx context.Context, ev decayBatchEvent) {
+	policy := decayPolicy(ev.eventType)
+	if policy.Percent <= 0 {
+		ev.responseCh <- apiResponse{data: decayProgress{done: true}}
+		return
+	}
+	batchSize := int64(config.Current().Leaderboard.Decay.BatchSize)
+	members, err := store.GetRankRange(ctx, decayName(ev.eventType, "activity"), ev.offset+1, ev.offset+batchSize)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	count, err := store.Count(ctx, decayName(ev.eventType, "boards"))
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	boards, err := store.GetRankRange(ctx, decayName(ev.eventType, "boards"), 1, count)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	decayed := 0
+	for _, member := range members {
+		lastActivity := time.Unix(int64(-member.Score), 0)
+		if lastActivity.Add(policy.Grace).After(ev.now) {
+			tracing.Logger(ctx).Debug("decay batch done", zap.String("event_type", ev.eventType), zap.Int("decayed", decayed))
+			ev.responseCh <- apiResponse{data: decayProgress{done: true}}
+			return
+		}
+		if err := decayUser(ctx, ev.eventType, policy, boards, member.UID, lastActivity, ev.now); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+		decayed++
+	}
+	tracing.Logger(ctx).Debug("decay batch done", zap.String("event_type", ev.eventType), zap.Int("decayed", decayed))
+	ev.responseCh <- apiResponse{data: decayProgress{
+		next: ev.offset + int64(len(members)),
+		done: int64(len(members)) < batchSize,
+	}}
+}
+
+// decayUser multiply positive scores of uid by (1 - percent) for every day since grace ended or decay was last applied
+func decayUser(ctx context.Context, eventType string, policy config.DecayPolicy, boards []storage.Member, uid string, lastActivity time.Time, now time.Time) error {
+	from := lastActivity.Add(policy.Grace)
+	decayedAt, err := store.GetScore(ctx, decayName(eventType, "decayed"), uid)
+	if err != nil && err != storage.ErrMemberNotFound {
+		return err
+	}
+	if err == nil && time.Unix(int64(decayedAt), 0).After(from) {
+		from = time.Unix(int64(decayedAt), 0)
+	}
+	if !now.After(from) {
+		return nil
+	}
+	factor := math.Pow(1-policy.Percent/100, now.Sub(from).Hours()/24)
+	eventRankingKey := config.Current().Leaderboard.EventRankingKey
+	for _, board := range boards {
+		score, err := store.GetScore(ctx, board.UID, uid)
+		if err == storage.ErrMemberNotFound || (err == nil && score <= 0) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := store.IncreaseScore(ctx, board.UID, score*(factor-1), uid, eventRankingKey); err != nil {
+			return err
+		}
+		if err := store.IncreaseScore(ctx, decayDebtName(eventType, board.UID), score*(1-factor), uid, decayListKey(eventRankingKey)); err != nil {
+			return err
+		}
+		notifyBoard(board.UID)
+	}
+	return setRegistered(ctx, decayName(eventType, "decayed"), float64(now.Unix()), uid, decayListKey(eventRankingKey))
+}
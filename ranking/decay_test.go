@@ -0,0 +1,131 @@
+package ranking
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func enableDecay(c *config.Config) {
+	c.Leaderboard.Definitions = []config.LeaderboardDefinition{{EventType: "1", Decay: config.DecayPolicy{Percent: 10, Grace: 24 * time.Hour}}}
+}
+
+// testEvents is play_event table of rebuild
+type testEvents []storage.UserData
+
+func (events testEvents) GetAllUserEventData(ctx context.Context, since time.Time) ([]storage.UserData, error) {
+	return events, nil
+}
+
+func (events testEvents) Close() error {
+	return nil
+}
+
+// rebuildFrom run rebuild from DB with play_event sums of events
+func rebuildFrom(t *testing.T, events testEvents) {
+	t.Helper()
+	oldSources := storage.DataSources
+	storage.DataSources = &storage.DataSource{Events: events}
+	t.Cleanup(func() {
+		storage.DataSources = oldSources
+		atomic.StoreInt32(&rebuildDone, 0)
+	})
+	InitRankingSystemData()
+	// queue is served in order, so entries are read after rebuild
+	scoreOf(t, "a")
+}
+
+func scoreOf(t *testing.T, uid string) float64 {
+	t.Helper()
+	var entry Entry
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/entries/"+uid, nil, &entry); code != http.StatusOK {
+		t.Fatalf("get entry of %s: status %d", uid, code)
+	}
+	return entry.Score
+}
+
+func decayAt(t *testing.T, now time.Time) {
+	t.Helper()
+	if err := decayLeaderboards(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecayInactivePlayer(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	setNow := setClock(t, start)
+	startTest(t, enableDecay)
+	submit(t, "1", "a", 100)
+	submit(t, "1", "b", 100)
+	setNow(start.Add(48 * time.Hour))
+	submit(t, "1", "b", 1)
+
+	// a decay for 2 days after grace, b scored within grace
+	decayAt(t, start.Add(72*time.Hour))
+	if got := scoreOf(t, "a"); !near(got, 81, 1e-9) {
+		t.Errorf("score of inactive a = %v, want 81", got)
+	}
+	if got := scoreOf(t, "b"); got != 101 {
+		t.Errorf("score of active b = %v, want 101", got)
+	}
+	// run again at same time does not decay twice
+	decayAt(t, start.Add(72*time.Hour))
+	if got := scoreOf(t, "a"); !near(got, 81, 1e-9) {
+		t.Errorf("score of a after second run = %v, want 81", got)
+	}
+	// half day later decay continue from last run
+	decayAt(t, start.Add(84*time.Hour))
+	if got, want := scoreOf(t, "a"), 81*math.Sqrt(0.9); !near(got, want, 1e-9) {
+		t.Errorf("score of a after half day = %v, want %v", got, want)
+	}
+}
+
+func TestDecayActivePlayerExempt(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	setNow := setClock(t, start)
+	startTest(t, enableDecay)
+	submit(t, "1", "a", 100)
+	// a score every day, grace never end
+	for day := 1; day <= 5; day++ {
+		setNow(start.Add(time.Duration(day) * 24 * time.Hour))
+		submit(t, "1", "a", 1)
+		decayAt(t, clock())
+	}
+	if got := scoreOf(t, "a"); got != 105 {
+		t.Errorf("score of active a = %v, want 105", got)
+	}
+}
+
+func TestDecayDebtSurviveRebuild(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	setClock(t, start)
+	memory := startTest(t, enableDecay)
+	submit(t, "1", "a", 100)
+	submit(t, "1", "b", 50)
+	decayAt(t, start.Add(72*time.Hour))
+
+	rebuildFrom(t, testEvents{
+		{UID: "a", EventType: "1", Amount: "100"},
+		{UID: "b", EventType: "1", Amount: "50"},
+	})
+	if got := scoreOf(t, "a"); !near(got, 81, 1e-9) {
+		t.Errorf("rebuilt score of a = %v, want 81 with decay taken again", got)
+	}
+	if got := scoreOf(t, "b"); !near(got, 40.5, 1e-9) {
+		t.Errorf("rebuilt score of b = %v, want 40.5", got)
+	}
+	// rebuild keep last activity and decayed time, next run decay only day after it
+	decayAt(t, start.Add(96*time.Hour))
+	if got := scoreOf(t, "a"); !near(got, 72.9, 1e-9) {
+		t.Errorf("score of a day after rebuild = %v, want 72.9", got)
+	}
+	debt, err := memory.GetScore(context.Background(), decayDebtName("1", "1"+config.Current().Leaderboard.EventRankingKey), "a")
+	if err != nil || !near(debt, 27.1, 1e-9) {
+		t.Errorf("decay debt of a = %v, %v, want 27.1", debt, err)
+	}
+}
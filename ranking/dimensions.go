@@ -0,0 +1,155 @@
+package ranking
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"rangkingserver/config"
+	"strings"
+)
+
+// dimensions of old routes, read from gameMode and subTitle when leaderboard declare them
+const (
+	DimensionGameMode = "game_mode"
+	DimensionSubTitle = "sub_title"
+)
+
+// maxDimensionValueLength is longest value of dimension
+const maxDimensionValueLength = 64
+
+// writeSlices get slices a score of event type with dimension values is added to, "" is leaderboard without dimension.
+// Slices that need dimension not in values are skipped.
+func writeSlices(eventType string, values map[string]string) ([]string, error) {
+	definition, _ := config.Current().Definition(eventType)
+	if err := checkDimensions(definition, values); err != nil {
+		return nil, err
+	}
+
+	slices := []string{""}
+	for _, slice := range definition.Slices {
+		complete := true
+		for _, name := range slice {
+			if _, ok := values[name]; !ok {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			slices = append(slices, sliceKey(definition, slice, values))
+		}
+	}
+	return slices, nil
+}
+
+// querySlice get slice selected by dimension values, values must have exactly dimensions of one slice of definition
+func querySlice(eventType string, values map[string]string) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	definition, _ := config.Current().Definition(eventType)
+	if err := checkDimensions(definition, values); err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	selected := strings.Join(definition.OrderDimensions(names), ",")
+	for _, slice := range definition.Slices {
+		if strings.Join(definition.OrderDimensions(slice), ",") == selected {
+			return sliceKey(definition, slice, values), nil
+		}
+	}
+
+	available := make([]string, 0, len(definition.Slices))
+	for _, slice := range definition.Slices {
+		available = append(available, "["+strings.Join(definition.OrderDimensions(slice), ",")+"]")
+	}
+	return "", invalidArgument(map[string]string{
+		"dimensions": fmt.Sprintf("event type %s has no leaderboard for [%s], slices are %s", eventType, selected, strings.Join(available, " ")),
+	})
+}
+
+// checkDimensions every name must be declared by definition and every value must be short and have no separator
+func checkDimensions(definition config.LeaderboardDefinition, values map[string]string) error {
+	details := make(map[string]string)
+	for name, value := range values {
+		switch {
+		case !definition.HasDimension(name):
+			details[name] = fmt.Sprintf("is not dimension of event type %s", definition.EventType)
+		case value == "" || len(value) > maxDimensionValueLength:
+			details[name] = fmt.Sprintf("must be 1 to %d characters", maxDimensionValueLength)
+		case strings.ContainsAny(value, "{},= \t\r\n"):
+			details[name] = "must not contain braces, comma, equal sign or space"
+		}
+	}
+	if len(details) > 0 {
+		return invalidArgument(details)
+	}
+	return nil
+}
+
+// sliceKey get part of ranking name of slice, ex. {game_mode=1,sub_title=2}, dimensions in declared order
+func sliceKey(definition config.LeaderboardDefinition, slice []string, values map[string]string) string {
+	var parts []string
+	for _, name := range definition.OrderDimensions(slice) {
+		parts = append(parts, name+"="+values[name])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// queryDimensions get dimension values from query parameters that are not period, limit or friends
+func queryDimensions(r *http.Request) map[string]string {
+	values := make(map[string]string)
+	for name, value := range r.URL.Query() {
+		if name == "period" || name == "limit" || name == "friends" || len(value) == 0 {
+			continue
+		}
+		values[name] = value[0]
+	}
+	return values
+}
+
+// legacyDimensions get game_mode and sub_title of old routes when leaderboard of event type declare them
+func legacyDimensions(eventType string, gameMode string, subTitle string) map[string]string {
+	definition, _ := config.Current().Definition(eventType)
+	values := make(map[string]string)
+	if gameMode != "" && definition.HasDimension(DimensionGameMode) {
+		values[DimensionGameMode] = gameMode
+	}
+	if subTitle != "" && definition.HasDimension(DimensionSubTitle) {
+		values[DimensionSubTitle] = subTitle
+	}
+	return values
+}
+
+// storedDimensions parse dimensions column of play_event, ex. game_mode=1&region=eu
+func storedDimensions(dimensions string) map[string]string {
+	query, err := url.ParseQuery(dimensions)
+	if err != nil || len(query) == 0 {
+		return nil
+	}
+	values := make(map[string]string)
+	for name, value := range query {
+		if len(value) > 0 {
+			values[name] = value[0]
+		}
+	}
+	return values
+}
+
+// sliceDimensions get dimension values back from slice key, nil for leaderboard without dimension
+func sliceDimensions(slice string) map[string]string {
+	slice = strings.TrimSuffix(strings.TrimPrefix(slice, "{"), "}")
+	if slice == "" {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, part := range strings.Split(slice, ",") {
+		if pair := strings.SplitN(part, "=", 2); len(pair) == 2 {
+			values[pair[0]] = pair[1]
+		}
+	}
+	return values
+}
@@ -0,0 +1,88 @@
+package ranking
+
+import (
+	"encoding/json"
+	"net/http"
+	"rangkingserver/tracing"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// error codes of v1 api
+const (
+	CodeInvalidArgument      = "invalid_argument"
+	CodeNotFound             = "not_found"
+	CodeForbidden            = "forbidden"
+	CodeConflict             = "conflict"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal"
+)
+
+// APIError is error body of v1 api
+type APIError struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+type errorBody struct {
+	Error *APIError `json:"error"`
+}
+
+func newAPIError(status int, code string, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// invalidArgument error with issue of every invalid field in details
+func invalidArgument(details map[string]string) *APIError {
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    CodeInvalidArgument,
+		Message: "invalid request parameters",
+		Details: details,
+	}
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Text get error with issue of every field for plain text and gRPC status
+func (e *APIError) Text() string {
+	if len(e.Details) == 0 {
+		return e.Error()
+	}
+	fields := make([]string, 0, len(e.Details))
+	for field, issue := range e.Details {
+		fields = append(fields, field+" "+issue)
+	}
+	sort.Strings(fields)
+	return e.Error() + ": " + strings.Join(fields, ", ")
+}
+
+// writeError write err as error body, err that is not APIError is internal error and its message is only logged
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		tracing.Logger(r.Context()).Error("v1 api internal error", zap.String("path", r.URL.Path), zap.Error(err))
+		apiErr = newAPIError(http.StatusInternalServerError, CodeInternal, "internal server error")
+	}
+	writeJSON(w, r, apiErr.Status, errorBody{Error: apiErr})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		tracing.Logger(r.Context()).Error("v1 api parse json error", zap.Error(err))
+		http.Error(w, `{"error":{"code":"internal","message":"internal server error"}}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(jsonData)
+}
@@ -0,0 +1,264 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// V1UsersPrefix is path of v1 users collection
+	V1UsersPrefix = "/v1/users"
+	// maxFriends is largest friend set of uid and friend list of query
+	maxFriends = 1000
+)
+
+// UsersV1 route /v1/users/{uid}/...
+//
+//	GET    /v1/users/{uid}/friends            friend set of uid
+//	POST   /v1/users/{uid}/friends            add uids of body to friend set of uid
+//	DELETE /v1/users/{uid}/friends/{friend}   remove friend from friend set of uid
+//	GET    /v1/users/{uid}/team               team of uid
+//	PUT    /v1/users/{uid}/team               move uid to team of body
+//	DELETE /v1/users/{uid}/team               leave team
+//
+// Friend set is one way, add uid to friend set of friend too for mutual friends
+func UsersV1(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, V1UsersPrefix), "/"), "/")
+	switch {
+	case len(segments) == 2 && segments[0] != "" && segments[1] == "friends":
+		if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+			return
+		}
+		if r.Method == http.MethodGet {
+			getFriends(w, r, segments[0])
+			return
+		}
+		addFriends(w, r, segments[0])
+	case len(segments) == 3 && segments[0] != "" && segments[1] == "friends" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodDelete) {
+			return
+		}
+		sendUpdateFriends(w, r, segments[0], nil, []string{segments[2]})
+	case len(segments) == 2 && segments[0] != "" && segments[1] == "team":
+		if !allowMethod(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			getUserTeam(w, r, segments[0])
+		case http.MethodPut:
+			joinTeam(w, r, segments[0])
+		default:
+			sendSetTeam(w, r, segments[0], "")
+		}
+	default:
+		writeError(w, r, newAPIError(http.StatusNotFound, CodeNotFound, "no route for "+r.URL.Path))
+	}
+}
+
+func getFriends(w http.ResponseWriter, r *http.Request, uid string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getFriendsEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func addFriends(w http.ResponseWriter, r *http.Request, uid string) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, r, newAPIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be application/json"))
+		return
+	}
+	var body FriendsRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, r, invalidArgument(map[string]string{"body": err.Error()}))
+		return
+	}
+	details := make(map[string]string)
+	friends := checkFriends(uid, body.UIDs, "uids", details)
+	if len(friends) == 0 && len(details) == 0 {
+		details["uids"] = "is required"
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	sendUpdateFriends(w, r, uid, friends, nil)
+}
+
+func sendUpdateFriends(w http.ResponseWriter, r *http.Request, uid string, add []string, remove []string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- updateFriendsEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		uid:            uid,
+		add:            add,
+		remove:         remove,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getFriendEntries(w http.ResponseWriter, r *http.Request, leaderboardID string, uid string) {
+	details := make(map[string]string)
+	period := queryBoardPeriod(r, details)
+	var friends []string
+	if values, ok := r.URL.Query()["friends"]; ok {
+		var uids []string
+		for _, value := range values {
+			if value != "" {
+				uids = append(uids, strings.Split(value, ",")...)
+			}
+		}
+		friends = checkFriends(uid, uids, "friends", details)
+		if friends == nil {
+			friends = []string{}
+		}
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	slice, err := querySlice(leaderboardID, queryDimensions(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getFriendEntriesEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		period:         period,
+		uid:            uid,
+		friends:        friends,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// checkFriends get distinct friends without uid itself, issue of field is added to details when a friend is empty or there are too many
+func checkFriends(uid string, friends []string, field string, details map[string]string) []string {
+	seen := map[string]bool{uid: true}
+	var result []string
+	for _, friend := range friends {
+		friend = strings.TrimSpace(friend)
+		if friend == "" {
+			details[field] = "must not contain empty uid"
+			continue
+		}
+		if !seen[friend] {
+			seen[friend] = true
+			result = append(result, friend)
+		}
+	}
+	if len(result) > maxFriends {
+		details[field] = fmt.Sprintf("must have at most %d uids", maxFriends)
+	}
+	return result
+}
+
+// handleGetFriendEntries rank uid and friends by score in leaderboard, uid without score are left out
+func handleGetFriendEntries(ctx context.Context, ev getFriendEntriesEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	friends := ev.friends
+	if friends == nil {
+		var err error
+		if friends, err = store.GetFriends(ctx, ev.uid); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	rankingName, err := boardName(ctx, ev.leaderboardID, ev.slice, ev.period)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	members, err := store.GetScores(ctx, rankingName, append([]string{ev.uid}, friends...))
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	// same order as leaderboard, uid of equal score in reverse order like redis
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score > members[j].Score
+		}
+		return members[i].UID > members[j].UID
+	})
+	entries := make([]Entry, 0, len(members))
+	for index, member := range members {
+		entries = append(entries, Entry{UID: member.UID, Rank: int64(index + 1), Score: member.Score})
+	}
+	ev.responseCh <- apiResponse{data: EntryList{
+		LeaderboardID: ev.leaderboardID,
+		Dimensions:    sliceDimensions(ev.slice),
+		Period:        ev.period,
+		Entries:       entries,
+	}}
+}
+
+// handleGetFriends get friend set of uid
+func handleGetFriends(ctx context.Context, ev getFriendsEvent) {
+	friends, err := store.GetFriends(ctx, ev.uid)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	ev.responseCh <- apiResponse{data: FriendList{UID: ev.uid, Friends: friends}}
+}
+
+// handleUpdateFriends add and remove friends of uid, friend set must stay within maxFriends
+func handleUpdateFriends(ctx context.Context, ev updateFriendsEvent) {
+	friends, err := store.GetFriends(ctx, ev.uid)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if len(ev.add) > 0 {
+		total := len(friends)
+		current := make(map[string]bool, len(friends))
+		for _, friend := range friends {
+			current[friend] = true
+		}
+		for _, friend := range ev.add {
+			if !current[friend] {
+				total++
+			}
+		}
+		if total > maxFriends {
+			ev.responseCh <- apiResponse{err: invalidArgument(map[string]string{
+				"uids": fmt.Sprintf("friend set of %s would have %d uids, at most %d", ev.uid, total, maxFriends),
+			})}
+			return
+		}
+		if err := store.AddFriends(ctx, ev.uid, ev.add); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	if len(ev.remove) > 0 {
+		if err := store.RemoveFriends(ctx, ev.uid, ev.remove); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	handleGetFriends(ctx, getFriendsEvent{uid: ev.uid, responseCh: ev.responseCh})
+}
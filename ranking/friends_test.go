@@ -0,0 +1,119 @@
+package ranking
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFriendEntriesIncludeSelf(t *testing.T) {
+	startTest(t, nil)
+	submit(t, "1", "me", 20)
+	submit(t, "1", "a", 30)
+	submit(t, "1", "b", 10)
+	submit(t, "1", "stranger", 50)
+
+	var list EntryList
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/friends/me?friends=a,b,me,a", nil, &list); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if got, want := entryUIDs(list.Entries), []string{"a", "me", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+	for index, entry := range list.Entries {
+		if entry.Rank != int64(index+1) {
+			t.Errorf("rank of %s = %d, want %d", entry.UID, entry.Rank, index+1)
+		}
+	}
+
+	// friend set of uid never hold uid itself
+	var friends FriendList
+	if code := call(t, UsersV1, http.MethodPost, V1UsersPrefix+"/me/friends", FriendsRequest{UIDs: []string{"me", "b"}}, &friends); code != http.StatusOK {
+		t.Fatalf("add friends status %d", code)
+	}
+	if !reflect.DeepEqual(friends.Friends, []string{"b"}) {
+		t.Errorf("friend set = %v, want [b]", friends.Friends)
+	}
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/friends/me", nil, &list); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if got, want := entryUIDs(list.Entries), []string{"me", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries of friend set = %v, want %v", got, want)
+	}
+}
+
+func TestFriendEntriesUnknownFriends(t *testing.T) {
+	startTest(t, nil)
+	submit(t, "1", "a", 30)
+
+	var list EntryList
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/friends/me?friends=a,ghost", nil, &list); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if got, want := entryUIDs(list.Entries), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v, uid and friends without score are left out", got, want)
+	}
+
+	// uid without friend set get only itself
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/friends/a", nil, &list); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if got, want := entryUIDs(list.Entries), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries without friend set = %v, want %v", got, want)
+	}
+
+	// empty friends query is no friend, not friend set
+	var friends FriendList
+	call(t, UsersV1, http.MethodPost, V1UsersPrefix+"/a/friends", FriendsRequest{UIDs: []string{"b"}}, &friends)
+	submit(t, "1", "b", 5)
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/friends/a?friends=", nil, &list); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if got, want := entryUIDs(list.Entries), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries with empty friends = %v, want %v", got, want)
+	}
+}
+
+func TestFriendListSizeCap(t *testing.T) {
+	startTest(t, nil)
+	uids := func(prefix string, count int) []string {
+		result := make([]string, count)
+		for index := range result {
+			result[index] = fmt.Sprintf("%s%d", prefix, index)
+		}
+		return result
+	}
+
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/friends/me?friends="+strings.Join(uids("f", maxFriends+1), ","), nil, nil); code != http.StatusBadRequest {
+		t.Errorf("query with %d friends: status %d, want 400", maxFriends+1, code)
+	}
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/friends/me?friends=me,"+strings.Join(uids("f", maxFriends), ","), nil, nil); code != http.StatusOK {
+		t.Errorf("query with %d friends and uid itself: status %d, want 200", maxFriends, code)
+	}
+	if code := call(t, UsersV1, http.MethodPost, V1UsersPrefix+"/me/friends", FriendsRequest{UIDs: uids("f", maxFriends+1)}, nil); code != http.StatusBadRequest {
+		t.Errorf("add %d friends: status %d, want 400", maxFriends+1, code)
+	}
+
+	var friends FriendList
+	if code := call(t, UsersV1, http.MethodPost, V1UsersPrefix+"/me/friends", FriendsRequest{UIDs: uids("f", maxFriends-1)}, &friends); code != http.StatusOK {
+		t.Fatalf("add %d friends: status %d", maxFriends-1, code)
+	}
+	// friends already in set do not count twice
+	if code := call(t, UsersV1, http.MethodPost, V1UsersPrefix+"/me/friends", FriendsRequest{UIDs: []string{"f0", "f1", "g0"}}, &friends); code != http.StatusOK {
+		t.Fatalf("add up to cap: status %d", code)
+	}
+	if len(friends.Friends) != maxFriends {
+		t.Errorf("friend set has %d uids, want %d", len(friends.Friends), maxFriends)
+	}
+	if code := call(t, UsersV1, http.MethodPost, V1UsersPrefix+"/me/friends", FriendsRequest{UIDs: []string{"g1"}}, nil); code != http.StatusBadRequest {
+		t.Errorf("add beyond cap: status %d, want 400", code)
+	}
+	if code := call(t, UsersV1, http.MethodDelete, V1UsersPrefix+"/me/friends/g0", nil, &friends); code != http.StatusOK {
+		t.Fatalf("remove friend: status %d", code)
+	}
+	if code := call(t, UsersV1, http.MethodPost, V1UsersPrefix+"/me/friends", FriendsRequest{UIDs: []string{"g1"}}, nil); code != http.StatusOK {
+		t.Errorf("add after remove: status %d, want 200", code)
+	}
+}
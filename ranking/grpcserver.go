@@ -0,0 +1,247 @@
+package ranking
+
+import (
+	"context"
+	"io"
+	"rangkingserver/rankingpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultAroundRadius is entries above and below user of GetAroundMe when radius is 0
+	defaultAroundRadius = 5
+	// maxAroundRadius is largest radius of GetAroundMe
+	maxAroundRadius = 100
+)
+
+// grpcServer serve rankingpb.RankingServer on event loop, same as http handlers
+type grpcServer struct {
+	rankingpb.UnimplementedRankingServer
+}
+
+// NewGRPCServer create Ranking service, register it with rankingpb.RegisterRankingServer
+func NewGRPCServer() rankingpb.RankingServer {
+	return grpcServer{}
+}
+
+func (grpcServer) SubmitScore(ctx context.Context, in *rankingpb.SubmitScoreRequest) (*rankingpb.Entry, error) {
+	entry, err := submitScoreRPC(ctx, in)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return entry, nil
+}
+
+// SubmitScores add every score of stream, invalid scores are reported in Rejected and do not stop the batch
+func (grpcServer) SubmitScores(stream rankingpb.Ranking_SubmitScoresServer) error {
+	var response rankingpb.SubmitScoresResponse
+	for index := int64(0); ; index++ {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&response)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := submitScoreRPC(stream.Context(), in); err != nil {
+			apiErr, ok := err.(*APIError)
+			if !ok {
+				return grpcError(err)
+			}
+			response.Rejected = append(response.Rejected, &rankingpb.RejectedScore{
+				Index:   index,
+				Uid:     in.Uid,
+				Code:    apiErr.Code,
+				Message: apiErr.Message,
+			})
+			continue
+		}
+		response.Accepted++
+	}
+}
+
+func (grpcServer) GetTop(ctx context.Context, in *rankingpb.GetTopRequest) (*rankingpb.EntryList, error) {
+	details := make(map[string]string)
+	period := checkBoardPeriod(in.Period, details)
+	limit := checkLimit(in.Limit, details)
+	if len(details) > 0 {
+		return nil, grpcError(invalidArgument(details))
+	}
+	slice, err := querySlice(in.LeaderboardId, in.Dimensions)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return getTopRPC(ctx, in.LeaderboardId, slice, period, limit)
+}
+
+func (grpcServer) GetAroundMe(ctx context.Context, in *rankingpb.GetAroundMeRequest) (*rankingpb.EntryList, error) {
+	details := make(map[string]string)
+	period := checkBoardPeriod(in.Period, details)
+	if in.Uid == "" {
+		details["uid"] = "is required"
+	}
+	radius := in.Radius
+	if radius == 0 {
+		radius = defaultAroundRadius
+	}
+	if radius < 1 || radius > maxAroundRadius {
+		details["radius"] = "must be from 1 to 100"
+	}
+	if len(details) > 0 {
+		return nil, grpcError(invalidArgument(details))
+	}
+	slice, err := querySlice(in.LeaderboardId, in.Dimensions)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getAroundEvent{
+		requestContext: newRequestContext(ctx),
+		responseCh:     responseCh,
+		leaderboardID:  in.LeaderboardId,
+		slice:          slice,
+		period:         period,
+		uid:            in.Uid,
+		radius:         radius,
+	}
+	response := <-responseCh
+	if response.err != nil {
+		return nil, grpcError(response.err)
+	}
+	return toEntryList(response.data.(EntryList)), nil
+}
+
+func (grpcServer) GetMyRank(ctx context.Context, in *rankingpb.GetMyRankRequest) (*rankingpb.Entry, error) {
+	details := make(map[string]string)
+	period := checkBoardPeriod(in.Period, details)
+	if in.Uid == "" {
+		details["uid"] = "is required"
+	}
+	if len(details) > 0 {
+		return nil, grpcError(invalidArgument(details))
+	}
+	slice, err := querySlice(in.LeaderboardId, in.Dimensions)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getEntryEvent{
+		requestContext: newRequestContext(ctx),
+		responseCh:     responseCh,
+		leaderboardID:  in.LeaderboardId,
+		slice:          slice,
+		period:         period,
+		uid:            in.Uid,
+	}
+	response := <-responseCh
+	if response.err != nil {
+		return nil, grpcError(response.err)
+	}
+	return toEntry(response.data.(Entry)), nil
+}
+
+// Subscribe send top entries now and after every change of leaderboard until client cancel or server stop
+func (grpcServer) Subscribe(in *rankingpb.SubscribeRequest, stream rankingpb.Ranking_SubscribeServer) error {
+	details := make(map[string]string)
+	period := checkBoardPeriod(in.Period, details)
+	limit := checkLimit(in.Limit, details)
+	if len(details) > 0 {
+		return grpcError(invalidArgument(details))
+	}
+	if err := checkLeaderboard(in.LeaderboardId); err != nil {
+		return grpcError(err)
+	}
+	slice, err := querySlice(in.LeaderboardId, in.Dimensions)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	changed, cancel := watchBoard(in.LeaderboardId + slice + period)
+	defer cancel()
+	ctx := stream.Context()
+	for {
+		entries, err := getTopRPC(ctx, in.LeaderboardId, slice, period, limit)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(entries); err != nil {
+			return err
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		case <-subscriptionsDone:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+func submitScoreRPC(ctx context.Context, in *rankingpb.SubmitScoreRequest) (*rankingpb.Entry, error) {
+	if in.Uid == "" {
+		return nil, invalidArgument(map[string]string{"uid": "is required"})
+	}
+	responseCh := make(chan apiResponse)
+	eventCh <- submitScoreEvent{
+		requestContext: newRequestContext(ctx),
+		responseCh:     responseCh,
+		leaderboardID:  in.LeaderboardId,
+		uid:            in.Uid,
+		amount:         in.Amount,
+		dimensions:     in.Dimensions,
+	}
+	response := <-responseCh
+	if response.err != nil {
+		return nil, response.err
+	}
+	return toEntry(response.data.(Entry)), nil
+}
+
+func getTopRPC(ctx context.Context, leaderboardID string, slice string, period string, limit int64) (*rankingpb.EntryList, error) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getEntriesEvent{
+		requestContext: newRequestContext(ctx),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		period:         period,
+		limit:          limit,
+	}
+	response := <-responseCh
+	if response.err != nil {
+		return nil, grpcError(response.err)
+	}
+	return toEntryList(response.data.(EntryList)), nil
+}
+
+func toEntry(entry Entry) *rankingpb.Entry {
+	return &rankingpb.Entry{Uid: entry.UID, Rank: entry.Rank, Score: entry.Score}
+}
+
+func toEntryList(list EntryList) *rankingpb.EntryList {
+	entries := make([]*rankingpb.Entry, 0, len(list.Entries))
+	for _, entry := range list.Entries {
+		entries = append(entries, toEntry(entry))
+	}
+	return &rankingpb.EntryList{LeaderboardId: list.LeaderboardID, Dimensions: list.Dimensions, Period: list.Period, Entries: entries}
+}
+
+// grpcError convert APIError to status of same meaning, other errors are internal
+func grpcError(err error) error {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return status.Error(codes.Internal, "internal server error")
+	}
+	code := codes.Internal
+	switch apiErr.Code {
+	case CodeInvalidArgument:
+		code = codes.InvalidArgument
+	case CodeNotFound:
+		code = codes.NotFound
+	}
+	return status.Error(code, apiErr.Text())
+}
@@ -6,8 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"rangkingserver/storage"
-
-	"go.uber.org/zap"
+	"rangkingserver/tracing"
 )
 
 type httpResponse struct {
@@ -22,6 +21,8 @@ type userBody struct {
 	Name      string `json:"name"`
 	EventType string `json:"event_type"`
 	Amount    string `json:"amount"`
+	GameMode  string `json:"game_mode"`
+	SubTitle  string `json:"sub_title"`
 }
 
 // InitRankingSystemData prepare data when starter
@@ -29,10 +30,10 @@ func InitRankingSystemData() {
 	eventCh <- initRankingSystemDataEvent{}
 }
 
-// SaveRankingByEvent save rank via event type
+// SaveRankingByEvent save rank via event type, kept for old clients, new clients use POST /v1/leaderboards/{id}/scores
 func SaveRankingByEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		zap.L().Warn("SaveRankingByEvent method is not POST")
+		tracing.Logger(r.Context()).Warn("SaveRankingByEvent method is not POST")
 		http.Error(w, "SaveRankingByEvent method is not POST", http.StatusMethodNotAllowed)
 		return
 	}
@@ -50,13 +51,15 @@ func SaveRankingByEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	eventCh <- sendRequestSaveRankingEvent{
-		responseCh: receiveResponseCh,
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     receiveResponseCh,
 		info: storage.UserData{
 			UID:       info.UID,
 			EventType: info.EventType,
 			Amount:    info.Amount,
 			Name:      info.Name,
 		},
+		dimensions: legacyDimensions(info.EventType, info.GameMode, info.SubTitle),
 	}
 
 	responseData := <-receiveResponseCh
@@ -76,10 +79,10 @@ func SaveRankingByEvent(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// GetRankingByEvent get ranking by event type gameMode and subtitle rate
+// GetRankingByEvent get ranking by event type gameMode and subtitle rate, kept for old clients, new clients use GET /v1/leaderboards/{id}/entries
 func GetRankingByEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		zap.L().Warn("SaveWorldRanking method is not GET")
+		tracing.Logger(r.Context()).Warn("SaveWorldRanking method is not GET")
 		http.Error(w, "SaveWorldRanking method is not GET", http.StatusMethodNotAllowed)
 		return
 	}
@@ -98,15 +101,22 @@ func GetRankingByEvent(w http.ResponseWriter, r *http.Request) {
 	}
 	// eventType ex. 1 =  PlayCount
 	// serverRequest ex.  1 or 0
-	// in case name of ranking is 11
+	// in case name of ranking is 1ScoreKey, or 1{game_mode=2,sub_title=3}ScoreKey when leaderboard declare game_mode and sub_title
+	slice, err := querySlice(eventType, legacyDimensions(eventType, gameMode, subtitle))
+	if err != nil {
+		http.Error(w, err.(*APIError).Text(), http.StatusBadRequest)
+		return
+	}
 
 	eventCh <- getRankingByEvent{
-		responseCh: receiveResponseCh,
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     receiveResponseCh,
 		info: storage.UserData{
 			UID:             UID,
 			EventType:       eventType,
 			RankingDuration: rankingDuration,
 		},
+		slice:           slice,
 		isServerRequest: serverRequest,
 	}
 
@@ -127,10 +137,10 @@ func GetRankingByEvent(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// ClearRankingByKey clear ranking by key ex. daily or weekly
+// ClearRankingByKey clear ranking by key ex. daily or weekly, kept for old clients, new clients use DELETE /v1/leaderboards
 func ClearRankingByKey(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		zap.L().Warn("ClearRankingBykey method is not GET")
+		tracing.Logger(r.Context()).Warn("ClearRankingBykey method is not GET")
 		http.Error(w, "ClearRankingBykey method is not GET", http.StatusMethodNotAllowed)
 		return
 	}
@@ -139,11 +149,16 @@ func ClearRankingByKey(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid param", http.StatusNoContent)
 		return
 	}
+	if err := endLeagueGroups(r.Context(), key); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	receiveResponseCh := make(chan httpResponse)
 
 	eventCh <- clearRankingByEvent{
-		responseCh: receiveResponseCh,
-		rankingKey: key,
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     receiveResponseCh,
+		rankingKey:     key,
 	}
 
 	responseData := <-receiveResponseCh
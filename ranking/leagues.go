@@ -0,0 +1,488 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"rangkingserver/tracing"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// leagues are kept in sorted sets so they work on every store
+//
+//	leagues                       leaderboard id -> 0, leaderboards that have leagues
+//	leagues:{id}:season           "season" -> current season, 1 when missing
+//	leagues:{id}:tiers            uid -> tier of next group, lowest tier when missing
+//	leagues:{id}:history:{uid}    LeagueResult JSON -> season
+//	leagues:{id}:fill             tier -> number of uid put in groups of tier in season, cleared at season end
+//	leagues:{id}:groups           uid -> tier * leagueGroupStride + group in season, cleared at season end
+//	leagues:{id}:ended            tier * leagueGroupStride + group -> 0, groups already ended at season end, cleared at season end
+//	leagues:{id}:{tier}:{group}   uid -> score in season, cleared at season end
+const (
+	leaguesIndex      = "leagues"
+	leagueGroupStride = 1 << 20
+
+	OutcomePromoted  = "promoted"
+	OutcomeRelegated = "relegated"
+	OutcomeStayed    = "stayed"
+)
+
+// leagueName get name of sorted set of leagues of leaderboard
+func leagueName(leaderboardID string, name string) string {
+	return "leagues:" + leaderboardID + ":" + name
+}
+
+// leagueGroupName get ranking name of group in tier
+func leagueGroupName(leaderboardID string, tier int64, group int64) string {
+	return leagueName(leaderboardID, strconv.FormatInt(tier, 10)+":"+strconv.FormatInt(group, 10))
+}
+
+// leaguesListKey get list key of league sets of season, kept apart from period so groups are not listed as leaderboards
+func leaguesListKey(period string) string {
+	return "leagues:" + period
+}
+
+// addLeagueScore add amount to group of uid in current season, uid is put in group on first score of season
+func addLeagueScore(ctx context.Context, leaderboardID string, period string, uid string, amount float64) error {
+	if !config.Current().Leaderboard.Leagues.Enabled {
+		return nil
+	}
+	tier, group, err := leagueGroup(ctx, leaderboardID, uid)
+	if err == storage.ErrMemberNotFound {
+		tier, group, err = assignLeagueGroup(ctx, leaderboardID, period, uid)
+	}
+	if err != nil {
+		return err
+	}
+	rankingName := leagueGroupName(leaderboardID, tier, group)
+	if err := store.IncreaseScore(ctx, rankingName, amount, uid, leaguesListKey(period)); err != nil {
+		return err
+	}
+	notifyBoard(rankingName)
+	return nil
+}
+
+// clearLeagueScores remove score sets of groups of period and keep fill, groups and ended, so rebuilt scores go back to group uid was put in
+func clearLeagueScores(ctx context.Context, period string) error {
+	names, err := store.ListRankings(ctx, leaguesListKey(period))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, ":fill") || strings.HasSuffix(name, ":groups") || strings.HasSuffix(name, ":ended") {
+			continue
+		}
+		if err := store.Delete(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leagueGroup get tier and group of uid in current season, ErrMemberNotFound when uid has not scored in season
+func leagueGroup(ctx context.Context, leaderboardID string, uid string) (int64, int64, error) {
+	score, err := store.GetScore(ctx, leagueName(leaderboardID, "groups"), uid)
+	if err != nil {
+		return 0, 0, err
+	}
+	value := int64(score)
+	return value / leagueGroupStride, value % leagueGroupStride, nil
+}
+
+// assignLeagueGroup put uid in last group of its tier, new group is started when last group is full or ended
+func assignLeagueGroup(ctx context.Context, leaderboardID string, period string, uid string) (int64, int64, error) {
+	tier, err := leagueTier(ctx, leaderboardID, uid)
+	if err != nil {
+		return 0, 0, err
+	}
+	fill := leagueName(leaderboardID, "fill")
+	tierKey := strconv.FormatInt(tier, 10)
+	assigned, err := store.GetScore(ctx, fill, tierKey)
+	if err != nil && err != storage.ErrMemberNotFound {
+		return 0, 0, err
+	}
+	groupSize := int64(config.Current().Leaderboard.Leagues.GroupSize)
+	group := int64(assigned) / groupSize
+	// group already ended by season end batches take no more players, uid start next group
+	_, err = store.GetScore(ctx, leagueName(leaderboardID, "ended"), leagueGroupKey(tier, group))
+	if err == nil {
+		group++
+		assigned = float64(group * groupSize)
+	} else if err != storage.ErrMemberNotFound {
+		return 0, 0, err
+	}
+
+	if err := setRegistered(ctx, fill, assigned+1, tierKey, leaguesListKey(period)); err != nil {
+		return 0, 0, err
+	}
+	if err := store.IncreaseScore(ctx, leagueName(leaderboardID, "groups"), float64(tier*leagueGroupStride+group), uid, leaguesListKey(period)); err != nil {
+		return 0, 0, err
+	}
+	if err := store.SetScore(ctx, leaguesIndex, 0, leaderboardID); err != nil {
+		return 0, 0, err
+	}
+	return tier, group, nil
+}
+
+// leagueTier get tier uid play in next group, lowest tier when uid has none and highest tier when tiers were removed from config
+func leagueTier(ctx context.Context, leaderboardID string, uid string) (int64, error) {
+	score, err := store.GetScore(ctx, leagueName(leaderboardID, "tiers"), uid)
+	if err == storage.ErrMemberNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return clampTier(int64(score)), nil
+}
+
+func clampTier(tier int64) int64 {
+	if last := int64(len(config.Current().Leaderboard.Leagues.Tiers) - 1); tier > last {
+		return last
+	}
+	if tier < 0 {
+		return 0
+	}
+	return tier
+}
+
+// leagueSeason get current season of leagues of leaderboard
+func leagueSeason(ctx context.Context, leaderboardID string) (int64, error) {
+	season, err := store.GetScore(ctx, leagueName(leaderboardID, "season"), "season")
+	if err == storage.ErrMemberNotFound {
+		return 1, nil
+	}
+	return int64(season), err
+}
+
+// leagueProgress is result of one league season batch, next is offset of next batch in groups of every leaderboard
+type leagueProgress struct {
+	next int64
+	done bool
+}
+
+// leagueGroupKey get member of group in ended set
+func leagueGroupKey(tier int64, group int64) string {
+	return strconv.FormatInt(tier*leagueGroupStride+group, 10)
+}
+
+// forEachLeagueGroup call fn with every group of current season of every leaderboard, leaderboards by id and groups by tier, until fn return false
+func forEachLeagueGroup(ctx context.Context, fn func(leaderboardID string, tier int64, group int64) (bool, error)) error {
+	groupSize := int64(config.Current().Leaderboard.Leagues.GroupSize)
+	boards, err := store.GetRange(ctx, leaguesIndex, 0, 0)
+	if err != nil {
+		return err
+	}
+	sort.Slice(boards, func(i, j int) bool { return boards[i].UID < boards[j].UID })
+	for _, board := range boards {
+		fills, err := store.GetRange(ctx, leagueName(board.UID, "fill"), 0, 0)
+		if err != nil {
+			return err
+		}
+		sort.Slice(fills, func(i, j int) bool { return fills[i].UID < fills[j].UID })
+		for _, fill := range fills {
+			tier, err := strconv.ParseInt(fill.UID, 10, 64)
+			if err != nil {
+				continue
+			}
+			numGroups := (int64(fill.Score) + groupSize - 1) / groupSize
+			for group := int64(0); group < numGroups; group++ {
+				more, err := fn(board.UID, tier, group)
+				if err != nil || !more {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// endLeagueGroups queue league season batches until every group of current season is ended, so clear of period that end season
+// only start next season. Nothing is done unless period is event ranking key and leagues are enabled.
+func endLeagueGroups(ctx context.Context, period string) error {
+	if period != config.Current().Leaderboard.EventRankingKey || !config.Current().Leaderboard.Leagues.Enabled {
+		return nil
+	}
+	var offset int64
+	for {
+		responseCh := make(chan apiResponse, 1)
+		select {
+		case eventCh <- leagueSeasonBatchEvent{
+			requestContext: newRequestContext(ctx),
+			responseCh:     responseCh,
+			offset:         offset,
+		}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		response := <-responseCh
+		if response.err != nil {
+			return response.err
+		}
+		progress := response.data.(leagueProgress)
+		if progress.done {
+			return nil
+		}
+		offset = progress.next
+	}
+}
+
+// handleLeagueSeasonBatch end batch size groups from offset that are not ended yet.
+// Group ended in batch is final, score added to it before season end count for leaderboard but not for league result.
+func handleLeagueSeasonBatch(ctx context.Context, ev leagueSeasonBatchEvent) {
+	if !config.Current().Leaderboard.Leagues.Enabled {
+		ev.responseCh <- apiResponse{data: leagueProgress{done: true}}
+		return
+	}
+	last := ev.offset + int64(config.Current().Leaderboard.Leagues.BatchSize)
+	index, numPromoted, numRelegated := int64(0), 0, 0
+	err := forEachLeagueGroup(ctx, func(leaderboardID string, tier int64, group int64) (bool, error) {
+		if index >= last {
+			return false, nil
+		}
+		index++
+		if index <= ev.offset {
+			return true, nil
+		}
+		_, err := store.GetScore(ctx, leagueName(leaderboardID, "ended"), leagueGroupKey(tier, group))
+		if err == nil {
+			return true, nil
+		}
+		if err != storage.ErrMemberNotFound {
+			return false, err
+		}
+		promoted, relegated, err := endLeagueGroup(ctx, leaderboardID, tier, group)
+		numPromoted += promoted
+		numRelegated += relegated
+		return err == nil, err
+	})
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	tracing.Logger(ctx).Debug("league season batch done", zap.Int64("offset", ev.offset),
+		zap.Int("promoted", numPromoted), zap.Int("relegated", numRelegated))
+	ev.responseCh <- apiResponse{data: leagueProgress{next: index, done: index < last}}
+}
+
+// endLeagueSeason end every group of every leaderboard that batches did not end, groups started after batches included,
+// then start next season. Group sets are left for clear of period.
+func endLeagueSeason(ctx context.Context) error {
+	ended := make(map[string]map[string]bool)
+	err := forEachLeagueGroup(ctx, func(leaderboardID string, tier int64, group int64) (bool, error) {
+		if _, ok := ended[leaderboardID]; !ok {
+			members, err := store.GetRange(ctx, leagueName(leaderboardID, "ended"), 0, 0)
+			if err != nil {
+				return false, err
+			}
+			ended[leaderboardID] = make(map[string]bool, len(members))
+			for _, member := range members {
+				ended[leaderboardID][member.UID] = true
+			}
+		}
+		if ended[leaderboardID][leagueGroupKey(tier, group)] {
+			return true, nil
+		}
+		_, _, err := endLeagueGroup(ctx, leaderboardID, tier, group)
+		return err == nil, err
+	})
+	if err != nil {
+		return err
+	}
+	boards, err := store.GetRange(ctx, leaguesIndex, 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, board := range boards {
+		season, err := leagueSeason(ctx, board.UID)
+		if err != nil {
+			return err
+		}
+		if err := store.SetScore(ctx, leagueName(board.UID, "season"), float64(season+1), "season"); err != nil {
+			return err
+		}
+		zap.L().Info("league season ended", zap.String("leaderboard", board.UID), zap.Int64("season", season))
+	}
+	return nil
+}
+
+// endLeagueGroup move top of group up and bottom of group down one tier and mark group ended, promotion win when group is too small for both
+func endLeagueGroup(ctx context.Context, leaderboardID string, tier int64, group int64) (int, int, error) {
+	leagues := config.Current().Leaderboard.Leagues
+	season, err := leagueSeason(ctx, leaderboardID)
+	if err != nil {
+		return 0, 0, err
+	}
+	rankingName := leagueGroupName(leaderboardID, tier, group)
+	count, err := store.Count(ctx, rankingName)
+	if err != nil {
+		return 0, 0, err
+	}
+	members, err := store.GetRankRange(ctx, rankingName, 1, count)
+	if err != nil {
+		return 0, 0, err
+	}
+	groupKey := leagueGroupKey(tier, group)
+	tier = clampTier(tier)
+	last := int64(len(leagues.Tiers) - 1)
+	numPromoted, numRelegated := 0, 0
+	for index, member := range members {
+		result := LeagueResult{
+			Season: season,
+			Tier:   leagues.Tiers[tier],
+			Group:  group + 1,
+			Rank:   int64(index + 1),
+			Score:  member.Score,
+		}
+		next := tier
+		switch {
+		case index < leagues.Promote && tier < last:
+			next, result.Outcome = tier+1, OutcomePromoted
+			numPromoted++
+		case index >= len(members)-leagues.Relegate && tier > 0:
+			next, result.Outcome = tier-1, OutcomeRelegated
+			numRelegated++
+		default:
+			result.Outcome = OutcomeStayed
+		}
+		result.NextTier = leagues.Tiers[next]
+
+		if err := store.SetScore(ctx, leagueName(leaderboardID, "tiers"), float64(next), member.UID); err != nil {
+			return 0, 0, err
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := store.SetScore(ctx, leagueName(leaderboardID, "history:"+member.UID), float64(season), string(data)); err != nil {
+			return 0, 0, err
+		}
+	}
+	listKey := leaguesListKey(config.Current().Leaderboard.EventRankingKey)
+	if err := setRegistered(ctx, leagueName(leaderboardID, "ended"), 0, groupKey, listKey); err != nil {
+		return 0, 0, err
+	}
+	return numPromoted, numRelegated, nil
+}
+
+// checkLeagues leagues must be enabled
+func checkLeagues() error {
+	if !config.Current().Leaderboard.Leagues.Enabled {
+		return newAPIError(http.StatusNotFound, CodeNotFound, "leagues are disabled")
+	}
+	return nil
+}
+
+func getLeague(w http.ResponseWriter, r *http.Request, leaderboardID string, uid string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getLeagueEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getLeagueHistory(w http.ResponseWriter, r *http.Request, leaderboardID string, uid string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getLeagueHistoryEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// handleGetLeague get group of uid in current season ranked by score
+func handleGetLeague(ctx context.Context, ev getLeagueEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := checkLeagues(); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	tier, group, err := leagueGroup(ctx, ev.leaderboardID, ev.uid)
+	if err == storage.ErrMemberNotFound {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusNotFound, CodeNotFound,
+			fmt.Sprintf("uid %s has no league in this season, first score put uid in one", ev.uid))}
+		return
+	}
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	season, err := leagueSeason(ctx, ev.leaderboardID)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	rankingName := leagueGroupName(ev.leaderboardID, tier, group)
+	count, err := store.Count(ctx, rankingName)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	members, err := store.GetRankRange(ctx, rankingName, 1, count)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+
+	leagues := config.Current().Leaderboard.Leagues
+	tier = clampTier(tier)
+	league := League{
+		LeaderboardID: ev.leaderboardID,
+		Season:        season,
+		Tier:          leagues.Tiers[tier],
+		Group:         group + 1,
+		Entries:       make([]Entry, 0, len(members)),
+	}
+	if tier < int64(len(leagues.Tiers)-1) {
+		league.Promote = leagues.Promote
+	}
+	if tier > 0 {
+		league.Relegate = leagues.Relegate
+	}
+	for index, member := range members {
+		league.Entries = append(league.Entries, Entry{UID: member.UID, Rank: int64(index + 1), Score: member.Score})
+	}
+	ev.responseCh <- apiResponse{data: league}
+}
+
+// handleGetLeagueHistory get result of uid in every ended season, newest first
+func handleGetLeagueHistory(ctx context.Context, ev getLeagueHistoryEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := checkLeagues(); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	members, err := store.GetRankRange(ctx, leagueName(ev.leaderboardID, "history:"+ev.uid), 1, maxEntriesLimit)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	history := LeagueHistory{LeaderboardID: ev.leaderboardID, UID: ev.uid, Seasons: make([]LeagueResult, 0, len(members))}
+	for _, member := range members {
+		var result LeagueResult
+		if err := json.Unmarshal([]byte(member.UID), &result); err != nil {
+			tracing.Logger(ctx).Warn("handleGetLeagueHistory cannot decode result", zap.String("result", member.UID), zap.Error(err))
+			continue
+		}
+		history.Seasons = append(history.Seasons, result)
+	}
+	ev.responseCh <- apiResponse{data: history}
+}
@@ -0,0 +1,162 @@
+package ranking
+
+import (
+	"context"
+	"net/http"
+	"rangkingserver/config"
+	"reflect"
+	"testing"
+)
+
+func enableLeagues(c *config.Config) {
+	c.Leaderboard.Leagues = config.LeaguesConfig{
+		Enabled:   true,
+		Tiers:     []string{"Bronze", "Gold"},
+		GroupSize: 3,
+		Promote:   1,
+		Relegate:  1,
+		BatchSize: 1,
+	}
+}
+
+func getLeagueOf(t *testing.T, uid string) League {
+	t.Helper()
+	var league League
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/leagues/"+uid, nil, &league); code != http.StatusOK {
+		t.Fatalf("get league of %s: status %d", uid, code)
+	}
+	return league
+}
+
+func leagueHistory(t *testing.T, uid string) []LeagueResult {
+	t.Helper()
+	var history LeagueHistory
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/leagues/"+uid+"/history", nil, &history); code != http.StatusOK {
+		t.Fatalf("get league history of %s: status %d", uid, code)
+	}
+	return history.Seasons
+}
+
+func endLeagueSeasonByClear(t *testing.T) {
+	t.Helper()
+	if code := call(t, LeaderboardsV1, http.MethodDelete, V1Prefix+"?period="+config.Current().Leaderboard.EventRankingKey, nil, nil); code != http.StatusOK {
+		t.Fatalf("clear event ranking key: status %d", code)
+	}
+}
+
+func TestLeagueGroupBucketing(t *testing.T) {
+	startTest(t, enableLeagues)
+	for index, uid := range []string{"p1", "p2", "p3", "p4", "p5", "p6", "p7"} {
+		submit(t, "1", uid, float64(index+1))
+	}
+	for uid, group := range map[string]int64{"p1": 1, "p3": 1, "p4": 2, "p6": 2, "p7": 3} {
+		if league := getLeagueOf(t, uid); league.Group != group || league.Tier != "Bronze" || league.Season != 1 {
+			t.Errorf("league of %s = season %d %s group %d, want season 1 Bronze group %d", uid, league.Season, league.Tier, league.Group, group)
+		}
+	}
+	league := getLeagueOf(t, "p5")
+	if got, want := entryUIDs(league.Entries), []string{"p6", "p5", "p4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("group of p5 = %v, want %v", got, want)
+	}
+	if league.Promote != 1 || league.Relegate != 0 {
+		t.Errorf("lowest tier promote %d relegate %d, want 1 and 0", league.Promote, league.Relegate)
+	}
+	// score later in season stay in group
+	submit(t, "1", "p1", 100)
+	if league := getLeagueOf(t, "p1"); league.Group != 1 || league.Entries[0].UID != "p1" {
+		t.Errorf("league of p1 after score = group %d %v, want group 1 led by p1", league.Group, entryUIDs(league.Entries))
+	}
+}
+
+func TestLeagueSeasonEnd(t *testing.T) {
+	startTest(t, enableLeagues)
+	// Bronze group 1: p1 10, p2 20, p3 30, group 2: p4 5, p5 15, p6 25
+	for uid, score := range map[string]float64{"p1": 10, "p2": 20, "p3": 30} {
+		submit(t, "1", uid, score)
+	}
+	for uid, score := range map[string]float64{"p4": 5, "p5": 15, "p6": 25} {
+		submit(t, "1", uid, score)
+	}
+	endLeagueSeasonByClear(t)
+
+	want := map[string]LeagueResult{
+		"p3": {Season: 1, Tier: "Bronze", Rank: 1, Score: 30, Outcome: OutcomePromoted, NextTier: "Gold"},
+		"p2": {Season: 1, Tier: "Bronze", Rank: 2, Score: 20, Outcome: OutcomeStayed, NextTier: "Bronze"},
+		// lowest tier is not relegated
+		"p1": {Season: 1, Tier: "Bronze", Rank: 3, Score: 10, Outcome: OutcomeStayed, NextTier: "Bronze"},
+		"p6": {Season: 1, Tier: "Bronze", Rank: 1, Score: 25, Outcome: OutcomePromoted, NextTier: "Gold"},
+		"p4": {Season: 1, Tier: "Bronze", Rank: 3, Score: 5, Outcome: OutcomeStayed, NextTier: "Bronze"},
+	}
+	for uid, result := range want {
+		history := leagueHistory(t, uid)
+		if len(history) != 1 {
+			t.Errorf("history of %s = %+v, want one season", uid, history)
+			continue
+		}
+		result.Group = history[0].Group
+		if history[0] != result {
+			t.Errorf("season 1 of %s = %+v, want %+v", uid, history[0], result)
+		}
+	}
+
+	// Gold group: p3 30, p6 10, p7 who is new start in Bronze
+	submit(t, "1", "p3", 30)
+	submit(t, "1", "p6", 10)
+	submit(t, "1", "p7", 1)
+	league := getLeagueOf(t, "p3")
+	if league.Season != 2 || league.Tier != "Gold" || !reflect.DeepEqual(entryUIDs(league.Entries), []string{"p3", "p6"}) {
+		t.Errorf("league of p3 = season %d %s %v, want season 2 Gold [p3 p6]", league.Season, league.Tier, entryUIDs(league.Entries))
+	}
+	if league.Promote != 0 || league.Relegate != 1 {
+		t.Errorf("highest tier promote %d relegate %d, want 0 and 1", league.Promote, league.Relegate)
+	}
+	if league := getLeagueOf(t, "p7"); league.Tier != "Bronze" {
+		t.Errorf("new player start in %s, want Bronze", league.Tier)
+	}
+	endLeagueSeasonByClear(t)
+
+	// highest tier is not promoted, newest season first
+	history := leagueHistory(t, "p3")
+	if len(history) != 2 || history[0].Season != 2 || history[0].Outcome != OutcomeStayed || history[0].NextTier != "Gold" || history[1].Season != 1 {
+		t.Errorf("history of p3 = %+v, want season 2 stayed in Gold then season 1", history)
+	}
+	history = leagueHistory(t, "p6")
+	if len(history) != 2 || history[0].Outcome != OutcomeRelegated || history[0].Tier != "Gold" || history[0].NextTier != "Bronze" {
+		t.Errorf("history of p6 = %+v, want season 2 relegated from Gold", history)
+	}
+}
+
+func TestLeagueGroupEndedInBatchIsFinal(t *testing.T) {
+	startTest(t, enableLeagues)
+	ctx := context.Background()
+	for index, uid := range []string{"p1", "p2", "p3", "p4", "p5", "p6", "p7"} {
+		submit(t, "1", uid, float64(10*(index+1)))
+	}
+	if err := endLeagueGroups(ctx, config.Current().Leaderboard.EventRankingKey); err != nil {
+		t.Fatal(err)
+	}
+	// score after batches ended group 1 does not change its result, p8 and p9 start group 4 because group 3 is ended,
+	// it is ended by clear
+	submit(t, "1", "p1", 1000)
+	submit(t, "1", "p8", 5)
+	submit(t, "1", "p9", 5)
+	endLeagueSeasonByClear(t)
+
+	history := leagueHistory(t, "p1")
+	if len(history) != 1 || history[0].Score != 10 || history[0].Outcome != OutcomeStayed {
+		t.Errorf("history of p1 = %+v, want one result with score 10 before group ended", history)
+	}
+	if history := leagueHistory(t, "p9"); len(history) != 1 || history[0].Group != 4 || history[0].Season != 1 {
+		t.Errorf("history of p9 = %+v, want season 1 result of group 4", history)
+	}
+	if league, err := leagueSeason(ctx, "1"); err != nil || league != 2 {
+		t.Errorf("league season = %d, %v, want 2", league, err)
+	}
+	// ended groups are forgotten with season
+	if err := endLeagueGroups(ctx, config.Current().Leaderboard.EventRankingKey); err != nil {
+		t.Fatal(err)
+	}
+	if history := leagueHistory(t, "p1"); len(history) != 1 {
+		t.Errorf("history of p1 after next batches = %+v, want one result", history)
+	}
+}
@@ -0,0 +1,165 @@
+package ranking
+
+import (
+	"context"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"rangkingserver/webhook"
+	"sync"
+	"time"
+)
+
+// milestones reached on leaderboard without dimension of event ranking key, registered under milestonesListKey(period)
+// and cleared with period or at end of season, so every milestone is reached once per season
+//
+//	milestones:{ranking}:{name}   uid -> unix time milestone was reached
+func milestonesName(rankingName string, name string) string {
+	return "milestones:" + rankingName + ":" + name
+}
+
+// milestonesListKey get list key of reached milestones of period
+func milestonesListKey(period string) string {
+	return "milestones:" + period
+}
+
+// MilestoneOutlet receive every milestone reached, it is called in event loop and must not block
+type MilestoneOutlet func(MilestoneReached)
+
+var milestoneOutlets struct {
+	sync.Mutex
+	outlets []MilestoneOutlet
+}
+
+// AddMilestoneOutlet send milestones reached from now on to outlet
+func AddMilestoneOutlet(outlet MilestoneOutlet) {
+	milestoneOutlets.Lock()
+	defer milestoneOutlets.Unlock()
+	milestoneOutlets.outlets = append(milestoneOutlets.outlets, outlet)
+}
+
+func emitMilestone(reached MilestoneReached) {
+	milestoneOutlets.Lock()
+	defer milestoneOutlets.Unlock()
+	for _, outlet := range milestoneOutlets.outlets {
+		outlet(reached)
+	}
+}
+
+// boardPosition is score and rank of uid in leaderboard, rank 0 when uid has no score
+type boardPosition struct {
+	score float64
+	rank  int64
+}
+
+// positionBefore get position of uid before write, zero position when leaderboard has no milestone and no webhook receive player.overtaken
+func positionBefore(ctx context.Context, eventType string, rankingName string, uid string) (boardPosition, error) {
+	definition, _ := config.Current().Definition(eventType)
+	if len(definition.Milestones) == 0 && !webhookWanted(webhook.KindPlayerOvertaken) {
+		return boardPosition{}, nil
+	}
+	return positionOf(ctx, rankingName, uid)
+}
+
+// positionOf get position of uid in ranking
+func positionOf(ctx context.Context, rankingName string, uid string) (boardPosition, error) {
+	rank, err := store.GetRank(ctx, rankingName, uid)
+	if err == storage.ErrMemberNotFound {
+		return boardPosition{}, nil
+	}
+	if err != nil {
+		return boardPosition{}, err
+	}
+	score, err := store.GetScore(ctx, rankingName, uid)
+	return boardPosition{score: score, rank: rank}, err
+}
+
+// milestoneReached report whether position meet score or rank of milestone
+func milestoneReached(milestone config.MilestoneConfig, position boardPosition) bool {
+	if position.rank == 0 {
+		return false
+	}
+	if milestone.Rank > 0 {
+		return position.rank <= milestone.Rank
+	}
+	return position.score >= milestone.Score
+}
+
+// checkMilestones compare position of uid before write with position after it, record and emit every milestone
+// uid meet now that is not recorded yet
+func checkMilestones(ctx context.Context, eventType string, rankingName string, uid string, before boardPosition, now time.Time) error {
+	definition, _ := config.Current().Definition(eventType)
+	if len(definition.Milestones) == 0 {
+		return nil
+	}
+	after, err := positionOf(ctx, rankingName, uid)
+	if err != nil {
+		return err
+	}
+	period := config.Current().Leaderboard.EventRankingKey
+	for _, milestone := range definition.Milestones {
+		if !milestoneReached(milestone, after) {
+			continue
+		}
+		_, err := store.GetScore(ctx, milestonesName(rankingName, milestone.Name), uid)
+		if err == nil {
+			continue
+		}
+		if err != storage.ErrMemberNotFound {
+			return err
+		}
+		if err := setRegistered(ctx, milestonesName(rankingName, milestone.Name), float64(now.Unix()), uid, milestonesListKey(period)); err != nil {
+			return err
+		}
+		emitMilestone(MilestoneReached{
+			LeaderboardID: eventType,
+			Period:        period,
+			UID:           uid,
+			Milestone:     milestone.Name,
+			Score:         after.score,
+			Rank:          after.rank,
+			PreviousScore: before.score,
+			PreviousRank:  before.rank,
+			ReachedAt:     now.UTC().Truncate(time.Second),
+		})
+	}
+	return nil
+}
+
+func getMilestones(w http.ResponseWriter, r *http.Request, leaderboardID string, uid string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getMilestonesEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// handleGetMilestones get every milestone of leaderboard and whether uid reached it in current season
+func handleGetMilestones(ctx context.Context, ev getMilestonesEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	definition, _ := config.Current().Definition(ev.leaderboardID)
+	period := config.Current().Leaderboard.EventRankingKey
+	rankingName := ev.leaderboardID + period
+	list := MilestoneList{LeaderboardID: ev.leaderboardID, Period: period, UID: ev.uid, Milestones: []Milestone{}}
+	for _, milestone := range definition.Milestones {
+		entry := Milestone{Name: milestone.Name, Score: milestone.Score, Rank: milestone.Rank}
+		reachedAt, err := store.GetScore(ctx, milestonesName(rankingName, milestone.Name), ev.uid)
+		if err != nil && err != storage.ErrMemberNotFound {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+		if err == nil {
+			at := time.Unix(int64(reachedAt), 0).UTC()
+			entry.Reached = true
+			entry.ReachedAt = &at
+		}
+		list.Milestones = append(list.Milestones, entry)
+	}
+	ev.responseCh <- apiResponse{data: list}
+}
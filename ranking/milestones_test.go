@@ -0,0 +1,109 @@
+package ranking
+
+import (
+	"net/http"
+	"rangkingserver/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func enableMilestones(c *config.Config) {
+	c.Leaderboard.Definitions = []config.LeaderboardDefinition{{EventType: "1", Milestones: []config.MilestoneConfig{
+		{Name: "bronze", Score: 10},
+		{Name: "silver", Score: 50},
+		{Name: "top", Rank: 1},
+	}}}
+	c.Leaderboard.Seasons.CarryOver = 1
+}
+
+// collectMilestones record milestones emitted to outlets until test end
+func collectMilestones(t *testing.T) *[]MilestoneReached {
+	t.Helper()
+	milestoneOutlets.Lock()
+	oldOutlets := milestoneOutlets.outlets
+	milestoneOutlets.Unlock()
+	reached := []MilestoneReached{}
+	AddMilestoneOutlet(func(milestone MilestoneReached) {
+		reached = append(reached, milestone)
+	})
+	t.Cleanup(func() {
+		milestoneOutlets.Lock()
+		milestoneOutlets.outlets = oldOutlets
+		milestoneOutlets.Unlock()
+	})
+	return &reached
+}
+
+// milestoneNames get uid and name of milestones in order they were emitted
+func milestoneNames(reached []MilestoneReached) []string {
+	names := make([]string, 0, len(reached))
+	for _, milestone := range reached {
+		names = append(names, milestone.UID+" "+milestone.Milestone)
+	}
+	return names
+}
+
+func TestMilestonesInOneIncrement(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	setClock(t, at)
+	startTest(t, enableMilestones)
+	reached := collectMilestones(t)
+
+	submit(t, "1", "a", 5)
+	submit(t, "1", "a", 55)
+	want := []MilestoneReached{
+		{LeaderboardID: "1", Period: config.Current().Leaderboard.EventRankingKey, UID: "a", Milestone: "top", Score: 5, Rank: 1, ReachedAt: at.UTC()},
+		{LeaderboardID: "1", Period: config.Current().Leaderboard.EventRankingKey, UID: "a", Milestone: "bronze", Score: 60, Rank: 1, PreviousScore: 5, PreviousRank: 1, ReachedAt: at.UTC()},
+		{LeaderboardID: "1", Period: config.Current().Leaderboard.EventRankingKey, UID: "a", Milestone: "silver", Score: 60, Rank: 1, PreviousScore: 5, PreviousRank: 1, ReachedAt: at.UTC()},
+	}
+	if !reflect.DeepEqual(*reached, want) {
+		t.Errorf("milestones = %+v, want %+v", *reached, want)
+	}
+
+	// b cross every threshold at once, a take rank 1 back but reached it already
+	submit(t, "1", "b", 100)
+	submit(t, "1", "a", 100)
+	if got, want := milestoneNames(*reached), []string{"a top", "a bronze", "a silver", "b bronze", "b silver", "b top"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("milestones = %v, want %v", got, want)
+	}
+
+	var list MilestoneList
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/milestones/b", nil, &list); code != http.StatusOK {
+		t.Fatalf("get milestones: status %d", code)
+	}
+	for _, milestone := range list.Milestones {
+		if !milestone.Reached || milestone.ReachedAt == nil || !milestone.ReachedAt.Equal(at) {
+			t.Errorf("milestone %+v of b, want reached at %v", milestone, at)
+		}
+	}
+}
+
+func TestMilestonesOncePerSeason(t *testing.T) {
+	setClock(t, time.Unix(1700000000, 0))
+	startTest(t, enableMilestones)
+	reached := collectMilestones(t)
+	submit(t, "1", "a", 60)
+	if got, want := milestoneNames(*reached), []string{"a bronze", "a silver", "a top"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("milestones = %v, want %v", got, want)
+	}
+
+	// rebuild from DB does not emit milestones and keep reached ones
+	rebuildFrom(t, testEvents{{UID: "a", EventType: "1", Amount: "60"}})
+	submit(t, "1", "a", 1)
+	if len(*reached) != 3 {
+		t.Errorf("milestones after rebuild = %v, want none fired again", milestoneNames(*reached))
+	}
+
+	// milestones can be reached again in next season
+	if code := call(t, LeaderboardsV1, http.MethodPost, V1Prefix+"/seasons", nil, nil); code != http.StatusOK {
+		t.Fatalf("end season: status %d", code)
+	}
+	submit(t, "1", "a", 1)
+	if got, want := milestoneNames((*reached)[3:]), []string{"a bronze", "a silver", "a top"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("milestones of season 2 = %v, want %v", got, want)
+	}
+	if last := (*reached)[len(*reached)-1]; last.PreviousScore != 61 || last.Score != 62 {
+		t.Errorf("last milestone = %+v, want previous score 61 carried over and score 62", last)
+	}
+}
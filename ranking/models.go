@@ -1,5 +1,9 @@
 package ranking
 
+import (
+	"encoding/json"
+	"time"
+)
 
 // UserResponseData is response data to client
 type UserResponseData struct {
@@ -16,3 +20,314 @@ type UserRankData struct {
 	Point       uint32 `json:"point"`
 	RankingName string `json:"ranking_name"`
 }
+
+// Entry is rank and score of user in leaderboard of v1 api
+type Entry struct {
+	UID   string  `json:"uid"`
+	Rank  int64   `json:"rank"`
+	Score float64 `json:"score"`
+}
+
+// EntryList is top entries of leaderboard of v1 api
+type EntryList struct {
+	LeaderboardID string            `json:"leaderboard_id"`
+	Dimensions    map[string]string `json:"dimensions,omitempty"`
+	Period        string            `json:"period"`
+	// Season is archived season of entries, 0 for current leaderboard
+	Season  int64   `json:"season,omitempty"`
+	Entries []Entry `json:"entries"`
+}
+
+// SeasonResult is season ended and season started of v1 api, Archived is number of leaderboards archived
+type SeasonResult struct {
+	Ended    int64 `json:"ended"`
+	Season   int64 `json:"season"`
+	Archived int64 `json:"archived"`
+}
+
+// SeasonList is current season and archived seasons that can be read of v1 api
+type SeasonList struct {
+	Season   int64   `json:"season"`
+	Archived []int64 `json:"archived"`
+}
+
+// ClearResult is number of leaderboards cleared in period of v1 api
+type ClearResult struct {
+	Period  string `json:"period"`
+	Cleared int64  `json:"cleared"`
+}
+
+// ScoreRequest is body of submit score of v1 api
+type ScoreRequest struct {
+	UID    string   `json:"uid"`
+	Name   string   `json:"name"`
+	Amount *float64 `json:"amount"`
+	// Dimensions are values of dimensions declared by leaderboard, score is also added to every slice they complete
+	Dimensions map[string]string `json:"dimensions"`
+}
+
+// FriendList is friend set of uid of v1 api
+type FriendList struct {
+	UID     string   `json:"uid"`
+	Friends []string `json:"friends"`
+}
+
+// FriendsRequest is body of add friends of v1 api
+type FriendsRequest struct {
+	UIDs []string `json:"uids"`
+}
+
+// TeamRequest is body of join team of v1 api
+type TeamRequest struct {
+	Team string `json:"team"`
+}
+
+// TeamMembership is team of uid of v1 api, team is empty after leave
+type TeamMembership struct {
+	UID  string `json:"uid"`
+	Team string `json:"team"`
+}
+
+// TeamEntry is rank and score of team in team leaderboard of v1 api
+type TeamEntry struct {
+	Team  string  `json:"team"`
+	Rank  int64   `json:"rank"`
+	Score float64 `json:"score"`
+}
+
+// TeamEntryList is top teams of team leaderboard of v1 api
+type TeamEntryList struct {
+	LeaderboardID string            `json:"leaderboard_id"`
+	Dimensions    map[string]string `json:"dimensions,omitempty"`
+	Period        string            `json:"period"`
+	Teams         []TeamEntry       `json:"teams"`
+}
+
+// Contribution is score uid earned for team, Counted is false when it is not in top_k of team
+type Contribution struct {
+	UID     string  `json:"uid"`
+	Score   float64 `json:"score"`
+	Counted bool    `json:"counted"`
+	// Member is false when uid left team
+	Member bool `json:"member"`
+}
+
+// TeamBreakdown is rank and score of team with contribution of members of v1 api
+type TeamBreakdown struct {
+	LeaderboardID string            `json:"leaderboard_id"`
+	Dimensions    map[string]string `json:"dimensions,omitempty"`
+	Period        string            `json:"period"`
+	Team          string            `json:"team"`
+	Rank          int64             `json:"rank"`
+	Score         float64           `json:"score"`
+	Contributions []Contribution    `json:"contributions"`
+}
+
+// League is group of uid in current season of v1 api, Promote and Relegate are number of ranks that move tier at season end
+type League struct {
+	LeaderboardID string  `json:"leaderboard_id"`
+	Season        int64   `json:"season"`
+	Tier          string  `json:"tier"`
+	Group         int64   `json:"group"`
+	Promote       int     `json:"promote"`
+	Relegate      int     `json:"relegate"`
+	Entries       []Entry `json:"entries"`
+}
+
+// LeagueResult is rank of uid in group of ended season and tier of next season
+type LeagueResult struct {
+	Season   int64   `json:"season"`
+	Tier     string  `json:"tier"`
+	Group    int64   `json:"group"`
+	Rank     int64   `json:"rank"`
+	Score    float64 `json:"score"`
+	Outcome  string  `json:"outcome"`
+	NextTier string  `json:"next_tier"`
+}
+
+// LeagueHistory is results of uid in ended seasons of v1 api, newest first
+type LeagueHistory struct {
+	LeaderboardID string         `json:"leaderboard_id"`
+	UID           string         `json:"uid"`
+	Seasons       []LeagueResult `json:"seasons"`
+}
+
+// TournamentRequest is body of create tournament of v1 api, registration_end_at default to end_at
+type TournamentRequest struct {
+	ID                   string     `json:"id"`
+	Name                 string     `json:"name"`
+	StartAt              time.Time  `json:"start_at"`
+	EndAt                time.Time  `json:"end_at"`
+	RegistrationRequired bool       `json:"registration_required"`
+	RegistrationEndAt    *time.Time `json:"registration_end_at"`
+	MaxParticipants      int        `json:"max_participants"`
+}
+
+// Tournament is timed leaderboard of v1 api, only registered uid score when registration is required
+type Tournament struct {
+	ID                   string     `json:"id"`
+	Name                 string     `json:"name,omitempty"`
+	StartAt              time.Time  `json:"start_at"`
+	EndAt                time.Time  `json:"end_at"`
+	RegistrationRequired bool       `json:"registration_required"`
+	RegistrationEndAt    *time.Time `json:"registration_end_at,omitempty"`
+	MaxParticipants      int        `json:"max_participants"`
+	Status               string     `json:"status,omitempty"`
+	Participants         int64      `json:"participants"`
+	FinalizedAt          *time.Time `json:"finalized_at,omitempty"`
+}
+
+// TournamentList is tournaments of v1 api, latest start first
+type TournamentList struct {
+	Tournaments []Tournament `json:"tournaments"`
+}
+
+// RegistrationRequest is body of tournament registration of v1 api
+type RegistrationRequest struct {
+	UID string `json:"uid"`
+}
+
+// Registration is registration of uid in tournament of v1 api
+type Registration struct {
+	TournamentID string    `json:"tournament_id"`
+	UID          string    `json:"uid"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// TournamentScoreRequest is body of tournament score of v1 api
+type TournamentScoreRequest struct {
+	UID    string   `json:"uid"`
+	Amount *float64 `json:"amount"`
+}
+
+// TournamentEntryList is top entries of tournament of v1 api, final after tournament end
+type TournamentEntryList struct {
+	TournamentID string  `json:"tournament_id"`
+	Status       string  `json:"status"`
+	Final        bool    `json:"final"`
+	Entries      []Entry `json:"entries"`
+}
+
+// MatchRequest is body of match result of v1 api, winner and loser or placements from first place
+type MatchRequest struct {
+	Winner     string            `json:"winner"`
+	Loser      string            `json:"loser"`
+	Draw       bool              `json:"draw"`
+	Placements []string          `json:"placements"`
+	Dimensions map[string]string `json:"dimensions"`
+}
+
+// PlayerRating is rating of uid in rating leaderboard of v1 api, deviation and volatility are glicko-2 only
+type PlayerRating struct {
+	UID        string  `json:"uid"`
+	Rank       int64   `json:"rank"`
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation,omitempty"`
+	Volatility float64 `json:"volatility,omitempty"`
+	Matches    int64   `json:"matches"`
+	Change     float64 `json:"change,omitempty"`
+}
+
+// MatchResult is ratings of players after match of v1 api, in order of placements
+type MatchResult struct {
+	LeaderboardID string         `json:"leaderboard_id"`
+	Period        string         `json:"period"`
+	Players       []PlayerRating `json:"players"`
+}
+
+// MilestoneReached is milestone uid reached by write to leaderboard, sent to milestone outlets.
+// Previous score and rank are position of uid before the write, rank 0 when uid had no score.
+type MilestoneReached struct {
+	LeaderboardID string    `json:"leaderboard_id"`
+	Period        string    `json:"period"`
+	UID           string    `json:"uid"`
+	Milestone     string    `json:"milestone"`
+	Score         float64   `json:"score"`
+	Rank          int64     `json:"rank"`
+	PreviousScore float64   `json:"previous_score"`
+	PreviousRank  int64     `json:"previous_rank"`
+	ReachedAt     time.Time `json:"reached_at"`
+}
+
+// Milestone is milestone of leaderboard of v1 api, ReachedAt is set when uid reached it
+type Milestone struct {
+	Name      string     `json:"name"`
+	Score     float64    `json:"score,omitempty"`
+	Rank      int64      `json:"rank,omitempty"`
+	Reached   bool       `json:"reached"`
+	ReachedAt *time.Time `json:"reached_at,omitempty"`
+}
+
+// MilestoneList is every milestone of leaderboard and whether uid reached it in current season of v1 api
+type MilestoneList struct {
+	LeaderboardID string      `json:"leaderboard_id"`
+	Period        string      `json:"period"`
+	UID           string      `json:"uid"`
+	Milestones    []Milestone `json:"milestones"`
+}
+
+// WebhookRequest is body of webhook subscription of v1 api, payloads are signed with secret
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Kinds  []string `json:"kinds"`
+	Secret string   `json:"secret"`
+}
+
+// Webhook is webhook subscription of v1 api, secret is never returned
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Kinds     []string  `json:"kinds"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookList is webhook subscriptions of v1 api, oldest first
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookDelivery is one event sent to webhook of v1 api, NextAttemptAt is set while delivery is pending
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	EventID        string          `json:"event_id"`
+	Kind           string          `json:"kind"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookDeliveryList is delivery log of webhook of v1 api, newest first
+type WebhookDeliveryList struct {
+	WebhookID  string            `json:"webhook_id"`
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// PlayerOvertaken is data of player.overtaken webhook event, uid is pushed to rank by score of by_uid
+type PlayerOvertaken struct {
+	LeaderboardID string  `json:"leaderboard_id"`
+	Period        string  `json:"period"`
+	UID           string  `json:"uid"`
+	Rank          int64   `json:"rank"`
+	Score         float64 `json:"score"`
+	ByUID         string  `json:"by_uid"`
+	ByRank        int64   `json:"by_rank"`
+	ByScore       float64 `json:"by_score"`
+}
+
+// BoardReset is data of board.reset webhook event, season is set when leaderboards are reset by end of season
+type BoardReset struct {
+	Period  string `json:"period"`
+	Cleared int64  `json:"cleared"`
+	Season  int64  `json:"season,omitempty"`
+}
+
+// TournamentFinished is data of tournament.finished webhook event with final top entries
+type TournamentFinished struct {
+	Tournament Tournament `json:"tournament"`
+	Entries    []Entry    `json:"entries"`
+}
@@ -1,15 +1,22 @@
 package ranking
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"rangkingserver/config"
+	"rangkingserver/metrics"
 	"rangkingserver/storage"
+	"rangkingserver/tracing"
 	"rangkingserver/utils"
+	"rangkingserver/webhook"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -19,60 +26,452 @@ import (
 
 var eventCh chan event
 
+// rebuildDone is set when initial ranking data is loaded or rebuild is not needed
+var rebuildDone int32
+
+// store leaderboard store used by event loop
+var store storage.LeaderboardStore
+
+// clock get time scores are earned at and background jobs run at, tests replace it
+var clock = time.Now
+
 type event interface{}
 
+// requestContext carry context of request that queued event and time it was queued
+type requestContext struct {
+	ctx      context.Context
+	queuedAt time.Time
+}
+
+func newRequestContext(ctx context.Context) requestContext {
+	return requestContext{ctx: ctx, queuedAt: time.Now()}
+}
+
+func (rc requestContext) eventContext() requestContext {
+	return rc
+}
+
+// contextEvent is event queued by request
+type contextEvent interface {
+	eventContext() requestContext
+}
+
 type sendRequestSaveRankingEvent struct {
+	requestContext
 	responseCh chan<- httpResponse
 	info       storage.UserData
+	dimensions map[string]string
 }
 
 type sendRequestSaveWorldRankingEvent struct {
+	requestContext
 	responseCh chan<- httpResponse
 	info       storage.UserData
 }
 
 type getRankingByEvent struct {
+	requestContext
 	responseCh      chan<- httpResponse
 	info            storage.UserData
+	slice           string
 	isServerRequest string
 }
 
 type initRankingSystemDataEvent struct{}
 
 type clearRankingByEvent struct {
+	requestContext
 	responseCh chan<- httpResponse
 	rankingKey string
 }
 
+type submitScoreEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	uid           string
+	amount        float64
+	dimensions    map[string]string
+}
+
+type getEntriesEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	period        string
+	limit         int64
+}
+
+type getEntryEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	period        string
+	uid           string
+}
+
+type getAroundEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	period        string
+	uid           string
+	radius        int64
+}
+
+type clearLeaderboardsEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	period     string
+}
+
+type getFriendEntriesEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	period        string
+	uid           string
+	// friends of query, nil read friend set of uid
+	friends []string
+}
+
+type getFriendsEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	uid        string
+}
+
+type updateFriendsEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	uid        string
+	add        []string
+	remove     []string
+}
+
+type getTeamsEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	period        string
+	limit         int64
+}
+
+type getTeamEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	period        string
+	team          string
+}
+
+type getUserTeamEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	uid        string
+}
+
+type setTeamEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	uid        string
+	team       string
+}
+
+type submitMatchEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	placements    []string
+	draw          bool
+	dimensions    map[string]string
+}
+
+type getRatingEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	period        string
+	uid           string
+}
+
+// tournament events carry time of request, windows are checked against it
+type createTournamentEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	now        time.Time
+	tournament Tournament
+}
+
+type getTournamentsEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	now        time.Time
+	status     string
+	limit      int64
+}
+
+type getTournamentEvent struct {
+	requestContext
+	responseCh   chan<- apiResponse
+	now          time.Time
+	tournamentID string
+}
+
+type registerTournamentEvent struct {
+	requestContext
+	responseCh   chan<- apiResponse
+	now          time.Time
+	tournamentID string
+	uid          string
+}
+
+type submitTournamentScoreEvent struct {
+	requestContext
+	responseCh   chan<- apiResponse
+	now          time.Time
+	tournamentID string
+	uid          string
+	amount       float64
+}
+
+type getTournamentEntriesEvent struct {
+	requestContext
+	responseCh   chan<- apiResponse
+	now          time.Time
+	tournamentID string
+	limit        int64
+}
+
+type getTournamentEntryEvent struct {
+	requestContext
+	responseCh   chan<- apiResponse
+	now          time.Time
+	tournamentID string
+	uid          string
+}
+
+// finalizeTournamentsEvent is queued by tournament finalizer, not by request
+type finalizeTournamentsEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	now        time.Time
+}
+
+type getLeagueEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	uid           string
+}
+
+type getLeagueHistoryEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	uid           string
+}
+
+type getMilestonesEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	uid           string
+}
+
+type endSeasonEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+}
+
+type getSeasonsEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+}
+
+type getSeasonEntriesEvent struct {
+	requestContext
+	responseCh    chan<- apiResponse
+	leaderboardID string
+	slice         string
+	season        int64
+	limit         int64
+}
+
+// decayBatchEvent is queued by decay job, not by request
+type decayBatchEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	now        time.Time
+	eventType  string
+	offset     int64
+}
+
+// leagueSeasonBatchEvent is queued before clear that end league season, not by request
+type leagueSeasonBatchEvent struct {
+	requestContext
+	responseCh chan<- apiResponse
+	offset     int64
+}
+
+// stopEventLoopEvent is last event, eventLoop close done and return
+type stopEventLoopEvent struct {
+	done chan struct{}
+}
+
 // eventLoop execute user event queue
 func eventLoop() {
-	for {
-		for event := range eventCh {
-			switch ev := event.(type) {
-			case initRankingSystemDataEvent:
-				handleLoadUserEventData()
-			case getRankingByEvent:
-				handleGetRankingByEventType(ev.info, ev.responseCh, ev.isServerRequest)
-			case clearRankingByEvent:
-				handleClearRankingByKey(ev.rankingKey, ev.responseCh)
-			}
+	for event := range eventCh {
+		start := time.Now()
+		ctx := context.Background()
+		if ev, ok := event.(contextEvent); ok {
+			rc := ev.eventContext()
+			ctx = rc.ctx
+			// span of time event wait in eventCh
+			_, waitSpan := tracing.Tracer().Start(ctx, "eventCh.wait", trace.WithTimestamp(rc.queuedAt))
+			waitSpan.End(trace.WithTimestamp(start))
+		}
+		ctx, span := tracing.Tracer().Start(ctx, "event."+eventName(event))
 
+		switch ev := event.(type) {
+		case initRankingSystemDataEvent:
+			handleLoadUserEventData(ctx)
+		case sendRequestSaveRankingEvent:
+			handleProcessRankingByEvent(ctx, ev.info, ev.dimensions, ev.responseCh)
+		case getRankingByEvent:
+			handleGetRankingByEventType(ctx, ev.info, ev.slice, ev.responseCh, ev.isServerRequest)
+		case clearRankingByEvent:
+			handleClearRankingByKey(ctx, ev.rankingKey, ev.responseCh)
+		case submitScoreEvent:
+			handleSubmitScore(ctx, ev)
+		case getEntriesEvent:
+			handleGetEntries(ctx, ev)
+		case getEntryEvent:
+			handleGetEntry(ctx, ev)
+		case clearLeaderboardsEvent:
+			handleClearLeaderboards(ctx, ev)
+		case getAroundEvent:
+			handleGetAround(ctx, ev)
+		case getFriendEntriesEvent:
+			handleGetFriendEntries(ctx, ev)
+		case getFriendsEvent:
+			handleGetFriends(ctx, ev)
+		case updateFriendsEvent:
+			handleUpdateFriends(ctx, ev)
+		case getTeamsEvent:
+			handleGetTeams(ctx, ev)
+		case getTeamEvent:
+			handleGetTeam(ctx, ev)
+		case getUserTeamEvent:
+			handleGetUserTeam(ctx, ev)
+		case setTeamEvent:
+			handleSetTeam(ctx, ev)
+		case getLeagueEvent:
+			handleGetLeague(ctx, ev)
+		case getLeagueHistoryEvent:
+			handleGetLeagueHistory(ctx, ev)
+		case submitMatchEvent:
+			handleSubmitMatch(ctx, ev)
+		case getRatingEvent:
+			handleGetRating(ctx, ev)
+		case createTournamentEvent:
+			handleCreateTournament(ctx, ev)
+		case getTournamentsEvent:
+			handleGetTournaments(ctx, ev)
+		case getTournamentEvent:
+			handleGetTournament(ctx, ev)
+		case registerTournamentEvent:
+			handleRegisterTournament(ctx, ev)
+		case submitTournamentScoreEvent:
+			handleSubmitTournamentScore(ctx, ev)
+		case getTournamentEntriesEvent:
+			handleGetTournamentEntries(ctx, ev)
+		case getTournamentEntryEvent:
+			handleGetTournamentEntry(ctx, ev)
+		case finalizeTournamentsEvent:
+			handleFinalizeTournaments(ctx, ev)
+		case getMilestonesEvent:
+			handleGetMilestones(ctx, ev)
+		case endSeasonEvent:
+			handleEndSeason(ctx, ev)
+		case getSeasonsEvent:
+			handleGetSeasons(ctx, ev)
+		case getSeasonEntriesEvent:
+			handleGetSeasonEntries(ctx, ev)
+		case decayBatchEvent:
+			handleDecayBatch(ctx, ev)
+		case leagueSeasonBatchEvent:
+			handleLeagueSeasonBatch(ctx, ev)
+		case stopEventLoopEvent:
+			span.End()
+			close(ev.done)
+			return
 		}
+		span.End()
+		metrics.ObserveEvent(eventName(event), start)
 	}
 }
 
-// InitHandler initial eventLoop
-func InitHandler() {
-	eventCh = make(chan event)
+// eventName name of event type for metrics and spans
+func eventName(ev event) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", ev), "ranking.")
+}
+
+// InitHandler initial eventLoop with leaderboard store
+func InitHandler(leaderboardStore storage.LeaderboardStore) {
+	store = leaderboardStore
+	eventCh = make(chan event, config.Current().Server.EventQueueSize)
 	go eventLoop()
 }
 
+// SkipRankingSystemData mark initial data ready when rankings are not rebuilt from DB
+func SkipRankingSystemData() {
+	atomic.StoreInt32(&rebuildDone, 1)
+}
+
+// RankingSystemDataReady report whether initial ranking data is loaded
+func RankingSystemDataReady() bool {
+	return atomic.LoadInt32(&rebuildDone) == 1
+}
+
+// QueueDepth get number of queued events and capacity of event queue
+func QueueDepth() (int, int) {
+	return len(eventCh), cap(eventCh)
+}
+
+// Shutdown stop eventLoop after events already queued are done, call it after http server stop accepting requests
+func Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case eventCh <- stopEventLoopEvent{done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // handleProcessRankingByEvent save user statistic via game type
-func handleProcessRankingByEvent(info storage.UserData, responseCh chan<- httpResponse) {
-	rankingName := info.EventType
-	if err := storage.IncreaseScoreDataRedisByRankingData(storage.DataSources, rankingName, utils.ToFloat64(info.Amount), info.UID); err != nil {
+func handleProcessRankingByEvent(ctx context.Context, info storage.UserData, dimensions map[string]string, responseCh chan<- httpResponse) {
+	if err := submitScore(ctx, info.EventType, info.UID, utils.ToFloat64(info.Amount), dimensions); err != nil {
+		statusCode := http.StatusInternalServerError
+		if apiErr, ok := err.(*APIError); ok {
+			statusCode = http.StatusBadRequest
+			err = errors.New(apiErr.Text())
+		}
 		responseCh <- httpResponse{
-			statusCode: http.StatusInternalServerError,
+			statusCode: statusCode,
 			err:        err,
 		}
 		return
@@ -85,57 +484,40 @@ func handleProcessRankingByEvent(info storage.UserData, responseCh chan<- httpRe
 }
 
 // handleGetRankingByEventType for get score by event name
-func handleGetRankingByEventType(info storage.UserData, responseCh chan<- httpResponse, isServerRequest string) {
-	rankingName := info.EventType
-	var vals []redis.Z
-	var err error
-	rankingName += info.RankingDuration
-
+func handleGetRankingByEventType(ctx context.Context, info storage.UserData, slice string, responseCh chan<- httpResponse, isServerRequest string) {
+	rankingName := info.EventType + slice + info.RankingDuration
+	count := config.Current().Leaderboard.Limit
 	if isServerRequest == "1" {
-		if vals, err = storage.GetRedisAllRanking(storage.DataSources, rankingName, true); err != nil {
-			responseCh <- httpResponse{
-				statusCode: http.StatusInternalServerError,
-				err:        err,
-			}
-			return
-		}
-
-	} else {
-		if vals, err = storage.GetRedisAllRanking(storage.DataSources, rankingName, false); err != nil {
-			responseCh <- httpResponse{
-				statusCode: http.StatusInternalServerError,
-				err:        err,
-			}
-			return
+		count = 0
+	}
+	vals, err := topEntries(ctx, rankingName, count)
+	if err != nil {
+		responseCh <- httpResponse{
+			statusCode: http.StatusInternalServerError,
+			err:        err,
 		}
+		return
 	}
 
 	var rankingData []UserResponseData
-	rank, err := storage.GetUserRank(storage.DataSources, rankingName, info.UID)
-	score, err := storage.GetScoreRedis(storage.DataSources, rankingName, info.UID)
-	if err != nil || score <= 0 {
-		rank = -1
-		score = 0
-	}
-
 	if isServerRequest == "0" {
-		myUser := UserResponseData{
-			UID:   info.UID,
-			Rank:  utils.Int64ToString(rank),
-			Point: uint64(score),
+		myUser := UserResponseData{UID: info.UID, Rank: "-1", Point: 0}
+		if entry, err := userEntry(ctx, rankingName, info.UID); err == nil && entry.Score > 0 {
+			myUser.Rank = utils.Int64ToString(entry.Rank)
+			myUser.Point = uint64(entry.Score)
 		}
 		rankingData = append(rankingData, myUser)
 	}
 
-	for index := 0; index < len(vals); index++ {
-		var userData UserResponseData
-		userData.UID = fmt.Sprintf("%v", vals[index].Member)
-		userData.Rank = fmt.Sprintf("%v", index+1)
-		userData.Point = uint64(vals[index].Score)
-		rankingData = append(rankingData, userData)
+	for _, val := range vals {
+		rankingData = append(rankingData, UserResponseData{
+			UID:   val.UID,
+			Rank:  utils.Int64ToString(val.Rank),
+			Point: uint64(val.Score),
+		})
 	}
 	if jsonData, err := json.Marshal(rankingData); err != nil {
-		zap.L().Warn("handleGetRankingByEvent Type parse json error: ", zap.Error(err))
+		tracing.Logger(ctx).Warn("handleGetRankingByEvent Type parse json error: ", zap.Error(err))
 		responseCh <- httpResponse{
 			statusCode: http.StatusInternalServerError,
 			err:        err,
@@ -151,32 +533,315 @@ func handleGetRankingByEventType(info storage.UserData, responseCh chan<- httpRe
 
 }
 
+// handleSubmitScore add score of v1 api
+func handleSubmitScore(ctx context.Context, ev submitScoreEvent) {
+	if err := submitScore(ctx, ev.leaderboardID, ev.uid, ev.amount, ev.dimensions); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	entry, err := userEntry(ctx, ev.leaderboardID+config.Current().Leaderboard.EventRankingKey, ev.uid)
+	ev.responseCh <- apiResponse{data: entry, err: err}
+}
+
+// handleGetEntries get top entries of v1 api
+func handleGetEntries(ctx context.Context, ev getEntriesEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	rankingName, err := boardName(ctx, ev.leaderboardID, ev.slice, ev.period)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	entries, err := topEntries(ctx, rankingName, ev.limit)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	ev.responseCh <- apiResponse{data: EntryList{
+		LeaderboardID: ev.leaderboardID,
+		Dimensions:    sliceDimensions(ev.slice),
+		Period:        ev.period,
+		Entries:       entries,
+	}}
+}
+
+// handleGetEntry get entry of one user of v1 api
+func handleGetEntry(ctx context.Context, ev getEntryEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	rankingName, err := boardName(ctx, ev.leaderboardID, ev.slice, ev.period)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	entry, err := userEntry(ctx, rankingName, ev.uid)
+	ev.responseCh <- apiResponse{data: entry, err: err}
+}
+
+// handleGetAround get entries from radius above to radius below uid
+func handleGetAround(ctx context.Context, ev getAroundEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	rankingName, err := boardName(ctx, ev.leaderboardID, ev.slice, ev.period)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	entry, err := userEntry(ctx, rankingName, ev.uid)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	start := entry.Rank - ev.radius
+	if start < 1 {
+		start = 1
+	}
+	members, err := store.GetRankRange(ctx, rankingName, start, entry.Rank+ev.radius)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	entries := make([]Entry, 0, len(members))
+	for index, member := range members {
+		entries = append(entries, Entry{UID: member.UID, Rank: start + int64(index), Score: member.Score})
+	}
+	ev.responseCh <- apiResponse{data: EntryList{
+		LeaderboardID: ev.leaderboardID,
+		Dimensions:    sliceDimensions(ev.slice),
+		Period:        ev.period,
+		Entries:       entries,
+	}}
+}
+
+// handleClearLeaderboards clear every leaderboard of period of v1 api
+func handleClearLeaderboards(ctx context.Context, ev clearLeaderboardsEvent) {
+	cleared, err := clearPeriod(ctx, ev.period)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	notifyAllBoards()
+	ev.responseCh <- apiResponse{data: ClearResult{Period: ev.period, Cleared: cleared}}
+}
+
+// clearPeriod clear every leaderboard, team leaderboard, team contribution, league group, rating, decay state, reached milestone and season seed of period.
+// Clear of event ranking key end league season first, board.reset webhook is emitted after clear.
+func clearPeriod(ctx context.Context, period string) (int64, error) {
+	if period == config.Current().Leaderboard.EventRankingKey && config.Current().Leaderboard.Leagues.Enabled {
+		if err := endLeagueSeason(ctx); err != nil {
+			return 0, err
+		}
+	}
+	cleared, err := store.ClearAll(ctx, period)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := store.ClearAll(ctx, teamListKey(period)); err != nil {
+		return 0, err
+	}
+	if _, err := store.ClearAll(ctx, contributionsListKey(period)); err != nil {
+		return 0, err
+	}
+	if _, err := store.ClearAll(ctx, leaguesListKey(period)); err != nil {
+		return 0, err
+	}
+	if _, err := store.ClearAll(ctx, ratingsListKey(period)); err != nil {
+		return 0, err
+	}
+	if _, err := store.ClearAll(ctx, decayListKey(period)); err != nil {
+		return 0, err
+	}
+	if _, err := store.ClearAll(ctx, milestonesListKey(period)); err != nil {
+		return 0, err
+	}
+	if _, err := store.ClearAll(ctx, seedListKey(period)); err != nil {
+		return 0, err
+	}
+	emitWebhook(webhook.KindBoardReset, BoardReset{Period: period, Cleared: cleared})
+	return cleared, nil
+}
+
+// submitScore add amount to score of uid in leaderboard of event type and every slice of its dimension values
+func submitScore(ctx context.Context, eventType string, uid string, amount float64, dimensions map[string]string) error {
+	if err := checkLeaderboard(eventType); err != nil {
+		return err
+	}
+	if ratingOf(eventType) != "" {
+		return invalidArgument(map[string]string{
+			"leaderboard": fmt.Sprintf("event type %s is rating leaderboard, submit match results", eventType),
+		})
+	}
+	slices, err := writeSlices(eventType, dimensions)
+	if err != nil {
+		return err
+	}
+	team, err := teamOf(ctx, uid)
+	if err != nil {
+		return err
+	}
+	eventRankingKey := config.Current().Leaderboard.EventRankingKey
+	now := clock()
+	before, err := positionBefore(ctx, eventType, eventType+eventRankingKey, uid)
+	if err != nil {
+		return err
+	}
+	rankingNames := make([]string, 0, len(slices))
+	for _, slice := range slices {
+		rankingName := eventType + slice + eventRankingKey
+		rankingNames = append(rankingNames, rankingName)
+		if err := store.IncreaseScore(ctx, rankingName, amount, uid, eventRankingKey); err != nil {
+			return err
+		}
+		notifyBoard(rankingName)
+		if team != "" {
+			if err := addContribution(ctx, rankingName, eventRankingKey, team, uid, amount); err != nil {
+				return err
+			}
+		}
+		if err := addWindowScore(ctx, eventType+slice, uid, amount, now); err != nil {
+			return err
+		}
+	}
+	if err := recordActivity(ctx, eventType, rankingNames, uid, now); err != nil {
+		return err
+	}
+	if err := checkMilestones(ctx, eventType, eventType+eventRankingKey, uid, before, now); err != nil {
+		return err
+	}
+	if err := checkOvertaken(ctx, eventType, eventType+eventRankingKey, uid, before); err != nil {
+		return err
+	}
+	return addLeagueScore(ctx, eventType, eventRankingKey, uid, amount)
+}
+
+// checkLeaderboard event type must have leaderboard definition
+func checkLeaderboard(eventType string) error {
+	if !config.Current().LeaderboardDefined(eventType) {
+		return newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("event type %s has no leaderboard", eventType))
+	}
+	return nil
+}
+
+// topEntries get count entries with highest score, every entries when count <= 0
+func topEntries(ctx context.Context, rankingName string, count int64) ([]Entry, error) {
+	minScore := float64(1)
+	if count <= 0 {
+		minScore = 0
+	}
+	members, err := store.GetRange(ctx, rankingName, minScore, count)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(members))
+	for index, member := range members {
+		entries = append(entries, Entry{
+			UID:   member.UID,
+			Rank:  int64(index + 1),
+			Score: member.Score,
+		})
+	}
+	return entries, nil
+}
+
+// userEntry get rank and score of uid, not found error when uid has no score
+func userEntry(ctx context.Context, rankingName string, uid string) (Entry, error) {
+	rank, err := store.GetRank(ctx, rankingName, uid)
+	if err == storage.ErrMemberNotFound {
+		return Entry{}, newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("uid %s has no score", uid))
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	score, err := store.GetScore(ctx, rankingName, uid)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{UID: uid, Rank: rank, Score: score}, nil
+}
+
 // handleLoadUserEventData for init server load data from Database fill to redis
-func handleLoadUserEventData() {
-	_, err := storage.ClearAllRankingByKey(storage.DataSources, config.EventRankingKey)
+func handleLoadUserEventData(ctx context.Context) {
+	start := time.Now()
+	eventRankingKey := config.Current().Leaderboard.EventRankingKey
+	_, err := store.ClearAll(ctx, eventRankingKey)
 	if err != nil {
 		zap.S().Panic("Error handleLoadUserEventData clear all user data from Redis: ", err)
 	}
+	// contributions are kept, play_event has no team to rebuild them from
+	if err := rebuildTeamBoards(ctx, eventRankingKey); err != nil {
+		zap.S().Panic("Error handleLoadUserEventData rebuild team boards: ", err)
+	}
+	// league scores are rebuilt into groups uid already play in, so groups are not reshuffled on restart
+	if err := clearLeagueScores(ctx, eventRankingKey); err != nil {
+		zap.S().Panic("Error handleLoadUserEventData clear league scores: ", err)
+	}
 	zap.L().Info("handleLoadUserGamePlayEventData clear all user data from Redis")
 
-	dailyUserDataList, userDataErr := storage.GetAllUserEventDataFromDB(storage.DataSources)
+	// leaderboards start season from carried over scores, only play_event of current season is added to them
+	if err := seedSeason(ctx, eventRankingKey); err != nil {
+		zap.S().Panic("Error handleLoadUserEventData seed season: ", err)
+	}
+	started, err := seasonStart(ctx)
+	if err != nil {
+		zap.S().Panic("Error handleLoadUserEventData get season start: ", err)
+	}
+	dailyUserDataList, userDataErr := storage.GetAllUserEventDataFromDB(ctx, storage.DataSources, started)
 	if userDataErr != nil {
 		zap.L().Panic("GetDailyAllUserGamePlayEventDataFromDB get user data error: ", zap.Error(userDataErr))
 	}
 
 	for _, dailyData := range dailyUserDataList {
-		rankingName := dailyData.EventType
-		if err := storage.IncreaseScoreDataRedisByRankingKey(storage.DataSources, rankingName, utils.ToFloat64(dailyData.Amount), dailyData.UID, config.EventRankingKey); err != nil {
-			zap.L().Panic("handleLoadUserGamePlayEventData dailyData increase redis error: ", zap.Error(err))
+		// play_event has no match result, rating leaderboards keep their ratings
+		if ratingOf(dailyData.EventType) != "" {
+			continue
+		}
+		slices, err := writeSlices(dailyData.EventType, storedDimensions(dailyData.Dimensions))
+		if err != nil {
+			zap.L().Warn("handleLoadUserGamePlayEventData dimensions do not match leaderboard definition, load without dimension",
+				zap.String("event_type", dailyData.EventType), zap.String("dimensions", dailyData.Dimensions), zap.Error(err))
+			slices = []string{""}
 		}
+		rankingNames := make([]string, 0, len(slices))
+		for _, slice := range slices {
+			rankingName := dailyData.EventType + slice + eventRankingKey
+			rankingNames = append(rankingNames, rankingName)
+			if err := store.IncreaseScore(ctx, rankingName, utils.ToFloat64(dailyData.Amount), dailyData.UID, eventRankingKey); err != nil {
+				zap.L().Panic("handleLoadUserGamePlayEventData dailyData increase redis error: ", zap.Error(err))
+			}
+		}
+		if err := recordRebuiltActivity(ctx, dailyData.EventType, rankingNames, dailyData.UID, start); err != nil {
+			zap.L().Panic("handleLoadUserGamePlayEventData record activity error: ", zap.Error(err))
+		}
+		if err := addLeagueScore(ctx, dailyData.EventType, eventRankingKey, dailyData.UID, utils.ToFloat64(dailyData.Amount)); err != nil {
+			zap.L().Panic("handleLoadUserGamePlayEventData add league score error: ", zap.Error(err))
+		}
+	}
+	// rebuilt scores are sums without decay, decay already applied is taken again and continue from where it stopped
+	if err := applyDecayDebt(ctx); err != nil {
+		zap.L().Panic("handleLoadUserGamePlayEventData apply decay debt error: ", zap.Error(err))
+	}
+	if err := publishRatings(ctx, eventRankingKey); err != nil {
+		zap.L().Panic("handleLoadUserGamePlayEventData publish ratings error: ", zap.Error(err))
 	}
+	notifyAllBoards()
+	metrics.SetRebuildDuration(time.Since(start))
+	atomic.StoreInt32(&rebuildDone, 1)
 	zap.L().Info("LoadUserGamePlayEventData Done")
 }
 
 // handleClearRankingByKey for clear all data by key
-func handleClearRankingByKey(key string, responseCh chan<- httpResponse) {
+func handleClearRankingByKey(ctx context.Context, key string, responseCh chan<- httpResponse) {
 	if key != "" {
-		if _, err := storage.ClearAllRankingByKey(storage.DataSources, key); err != nil {
+		if _, err := clearPeriod(ctx, key); err != nil {
 			responseCh <- httpResponse{
 				statusCode: http.StatusInternalServerError,
 				err:        err,
@@ -191,6 +856,7 @@ func handleClearRankingByKey(key string, responseCh chan<- httpResponse) {
 		return
 	}
 
+	notifyAllBoards()
 	responseCh <- httpResponse{
 		statusCode: http.StatusOK,
 		err:        nil,
@@ -0,0 +1,92 @@
+package ranking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"testing"
+)
+
+// setTestConfig use default config changed by configure until test end
+func setTestConfig(t *testing.T, configure func(c *config.Config)) {
+	t.Helper()
+	oldConfig := config.Current()
+	c := config.Default()
+	c.Storage.Backend = "memory"
+	c.TLS.Enabled = false
+	if configure != nil {
+		configure(c)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	config.Set(c)
+	t.Cleanup(func() { config.Set(oldConfig) })
+}
+
+// startTest run event loop on empty memory store with default config changed by configure,
+// config and store are restored when test end
+func startTest(t *testing.T, configure func(c *config.Config)) *storage.MemoryStore {
+	t.Helper()
+	setTestConfig(t, configure)
+	oldStore := store
+	memory := storage.NewMemoryStore()
+	InitHandler(memory)
+	t.Cleanup(func() {
+		if err := Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+		store = oldStore
+	})
+	return memory
+}
+
+// call send request with JSON body to handler, decode JSON response into out when out is not nil and return status code
+func call(t *testing.T, handler http.HandlerFunc, method string, target string, body interface{}, out interface{}) int {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if out != nil && w.Code < 300 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", method, target, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// submit add amount to score of uid in leaderboard, test fail when it is refused
+func submit(t *testing.T, leaderboardID string, uid string, amount float64) Entry {
+	t.Helper()
+	var entry Entry
+	if code := call(t, LeaderboardsV1, http.MethodPost, V1Prefix+"/"+leaderboardID+"/scores", ScoreRequest{UID: uid, Amount: &amount}, &entry); code != http.StatusOK {
+		t.Fatalf("submit %s %v to %s: status %d", uid, amount, leaderboardID, code)
+	}
+	return entry
+}
+
+// entryUIDs get uid of every entry in rank order
+func entryUIDs(entries []Entry) []string {
+	uids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		uids = append(uids, entry.UID)
+	}
+	return uids
+}
@@ -0,0 +1,126 @@
+package ranking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/rankingpb"
+	"rangkingserver/ratelimit"
+	"strconv"
+	"strings"
+)
+
+// RateLimitSaveRanking classify /saveGamePlayRanking by uid of body
+func RateLimitSaveRanking(r *http.Request) ratelimit.Request {
+	return ratelimit.Request{UID: bodyUID(r)}
+}
+
+// RateLimitGetRanking classify /getRankingByEvent, isServerRequest=1 read whole leaderboard and is expensive
+func RateLimitGetRanking(r *http.Request) ratelimit.Request {
+	return ratelimit.Request{
+		UID:       r.FormValue("uid"),
+		Expensive: r.FormValue("isServerRequest") == "1",
+	}
+}
+
+// RateLimitClearRanking classify /clearRankingByKey, clear is always expensive
+func RateLimitClearRanking(r *http.Request) ratelimit.Request {
+	return ratelimit.Request{Expensive: true}
+}
+
+// RateLimitV1 classify /v1/leaderboards routes, clear, end of season and entries or teams above leaderboard limit are expensive
+func RateLimitV1(r *http.Request) ratelimit.Request {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, V1Prefix), "/"), "/")
+	switch {
+	case r.Method == http.MethodDelete || (r.Method == http.MethodPost && len(segments) == 1 && segments[0] == "seasons"):
+		return ratelimit.Request{Expensive: true}
+	case len(segments) == 2 && segments[1] == "scores":
+		return ratelimit.Request{UID: bodyUID(r)}
+	case (len(segments) == 2 && (segments[1] == "entries" || segments[1] == "teams")) || (len(segments) == 4 && segments[1] == "seasons"):
+		limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+		return ratelimit.Request{Expensive: limit > config.Current().Leaderboard.Limit}
+	case len(segments) == 3 && (segments[1] == "entries" || segments[1] == "friends" || segments[1] == "ratings" || segments[1] == "milestones"):
+		return ratelimit.Request{UID: segments[2]}
+	case len(segments) >= 3 && segments[1] == "leagues":
+		return ratelimit.Request{UID: segments[2]}
+	}
+	return ratelimit.Request{}
+}
+
+// RateLimitUsers classify /v1/users routes by uid of path
+func RateLimitUsers(r *http.Request) ratelimit.Request {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, V1UsersPrefix), "/"), "/")
+	return ratelimit.Request{UID: segments[0]}
+}
+
+// RateLimitTournaments classify /v1/tournaments routes, entries above leaderboard limit are expensive
+func RateLimitTournaments(r *http.Request) ratelimit.Request {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, V1TournamentsPrefix), "/"), "/")
+	switch {
+	case len(segments) == 2 && (segments[1] == "scores" || segments[1] == "registrations"):
+		return ratelimit.Request{UID: bodyUID(r)}
+	case len(segments) == 2 && segments[1] == "entries":
+		limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+		return ratelimit.Request{Expensive: limit > config.Current().Leaderboard.Limit}
+	case len(segments) == 3 && segments[1] == "entries":
+		return ratelimit.Request{UID: segments[2]}
+	}
+	return ratelimit.Request{}
+}
+
+// RateLimitWebhooks classify /v1/webhooks routes, delivery log above leaderboard limit is expensive
+func RateLimitWebhooks(r *http.Request) ratelimit.Request {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, V1WebhooksPrefix), "/"), "/")
+	if len(segments) == 2 && segments[1] == "deliveries" {
+		limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+		return ratelimit.Request{Expensive: limit > config.Current().Leaderboard.Limit}
+	}
+	return ratelimit.Request{}
+}
+
+// RateLimitRPC classify request messages of Ranking service, every score of SubmitScores stream is one SubmitScoreRequest
+func RateLimitRPC(ctx context.Context, req interface{}) ratelimit.Request {
+	switch in := req.(type) {
+	case *rankingpb.SubmitScoreRequest:
+		return ratelimit.Request{UID: in.Uid}
+	case *rankingpb.GetTopRequest:
+		return ratelimit.Request{Expensive: in.Limit > config.Current().Leaderboard.Limit}
+	case *rankingpb.SubscribeRequest:
+		return ratelimit.Request{Expensive: in.Limit > config.Current().Leaderboard.Limit}
+	case *rankingpb.GetAroundMeRequest:
+		return ratelimit.Request{UID: in.Uid}
+	case *rankingpb.GetMyRankRequest:
+		return ratelimit.Request{UID: in.Uid}
+	}
+	return ratelimit.Request{}
+}
+
+// WriteRateLimited write 429 error body of v1 api
+func WriteRateLimited(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, newAPIError(http.StatusTooManyRequests, CodeRateLimited, "rate limit exceeded, retry after Retry-After seconds"))
+}
+
+// WriteRateLimitedText write 429 plain text of old routes
+func WriteRateLimitedText(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
+
+// bodyUID read uid of JSON body and put body back for handler
+func bodyUID(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var info struct {
+		UID string `json:"uid"`
+	}
+	json.Unmarshal(body, &info)
+	return info.UID
+}
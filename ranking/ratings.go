@@ -0,0 +1,439 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"strings"
+	"time"
+)
+
+// rating state of every slice of rating leaderboard, registered under ratingsListKey(period) and cleared with period
+//
+//	ratings:{ranking}:rating       uid -> rating, leaderboard {ranking} is published copy of it
+//	ratings:{ranking}:deviation    uid -> glicko-2 rating deviation
+//	ratings:{ranking}:volatility   uid -> glicko-2 volatility
+//	ratings:{ranking}:matches      uid -> number of matches
+const (
+	// maxMatchPlayers is largest placement list of match
+	maxMatchPlayers = 100
+	// glicko2Scale convert glicko rating to glicko-2 scale
+	glicko2Scale = 173.7178
+	// glicko2Epsilon is convergence tolerance of volatility iteration
+	glicko2Epsilon = 0.000001
+)
+
+func ratingsName(rankingName string, name string) string {
+	return "ratings:" + rankingName + ":" + name
+}
+
+// ratingsListKey get list key of rating state of period
+func ratingsListKey(period string) string {
+	return "ratings:" + period
+}
+
+// ratingOf get rating system of leaderboard, empty when leaderboard sum scores
+func ratingOf(eventType string) string {
+	definition, _ := config.Current().Definition(eventType)
+	return definition.Rating
+}
+
+func getRating(w http.ResponseWriter, r *http.Request, leaderboardID string, uid string) {
+	details := make(map[string]string)
+	period := queryPeriod(r, details)
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	slice, err := querySlice(leaderboardID, queryDimensions(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getRatingEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		period:         period,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func submitMatch(w http.ResponseWriter, r *http.Request, leaderboardID string) {
+	var body MatchRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	details := make(map[string]string)
+	placements := checkMatch(body, details)
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- submitMatchEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		placements:     placements,
+		draw:           body.Draw,
+		dimensions:     body.Dimensions,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// checkMatch get uids of match from first place, match is winner and loser or placements
+func checkMatch(body MatchRequest, details map[string]string) []string {
+	if len(body.Placements) > 0 {
+		if body.Winner != "" || body.Loser != "" {
+			details["placements"] = "must not be used with winner and loser"
+		}
+		if body.Draw {
+			details["draw"] = "is only for winner and loser"
+		}
+		if len(body.Placements) < 2 || len(body.Placements) > maxMatchPlayers {
+			details["placements"] = fmt.Sprintf("must have 2 to %d uids", maxMatchPlayers)
+		}
+		seen := make(map[string]bool)
+		for _, uid := range body.Placements {
+			if uid == "" || seen[uid] {
+				details["placements"] = "must not have empty or duplicated uid"
+			}
+			seen[uid] = true
+		}
+		return body.Placements
+	}
+
+	if body.Winner == "" {
+		details["winner"] = "is required without placements"
+	}
+	if body.Loser == "" {
+		details["loser"] = "is required without placements"
+	}
+	if body.Winner != "" && body.Winner == body.Loser {
+		details["loser"] = "must differ from winner"
+	}
+	return []string{body.Winner, body.Loser}
+}
+
+// handleSubmitMatch update rating of every player of match in every slice and publish it to leaderboard
+func handleSubmitMatch(ctx context.Context, ev submitMatchEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	system := ratingOf(ev.leaderboardID)
+	if system == "" {
+		ev.responseCh <- apiResponse{err: invalidArgument(map[string]string{
+			"leaderboard": fmt.Sprintf("event type %s sum scores and has no rating, submit scores", ev.leaderboardID),
+		})}
+		return
+	}
+	slices, err := writeSlices(ev.leaderboardID, ev.dimensions)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+
+	period := config.Current().Leaderboard.EventRankingKey
+	result := MatchResult{LeaderboardID: ev.leaderboardID, Period: period}
+	for _, slice := range slices {
+		rankingName := ev.leaderboardID + slice + period
+		players := make([]playerRating, 0, len(ev.placements))
+		for _, uid := range ev.placements {
+			player, err := loadRating(ctx, rankingName, uid)
+			if err != nil {
+				ev.responseCh <- apiResponse{err: err}
+				return
+			}
+			players = append(players, player)
+		}
+		positions := make([]boardPosition, len(ev.placements))
+		if slice == "" {
+			for index, uid := range ev.placements {
+				if positions[index], err = positionBefore(ctx, ev.leaderboardID, rankingName, uid); err != nil {
+					ev.responseCh <- apiResponse{err: err}
+					return
+				}
+			}
+		}
+
+		updated := rateMatch(system, players, ev.draw)
+		for _, player := range updated {
+			if err := saveRating(ctx, rankingName, period, system, player); err != nil {
+				ev.responseCh <- apiResponse{err: err}
+				return
+			}
+		}
+		notifyBoard(rankingName)
+
+		if slice != "" {
+			continue
+		}
+		now := time.Now()
+		for index, uid := range ev.placements {
+			if err := checkMilestones(ctx, ev.leaderboardID, rankingName, uid, positions[index], now); err != nil {
+				ev.responseCh <- apiResponse{err: err}
+				return
+			}
+			if err := checkOvertaken(ctx, ev.leaderboardID, rankingName, uid, positions[index]); err != nil {
+				ev.responseCh <- apiResponse{err: err}
+				return
+			}
+		}
+		for index, player := range updated {
+			rating, err := ratingEntry(ctx, rankingName, system, player)
+			if err != nil {
+				ev.responseCh <- apiResponse{err: err}
+				return
+			}
+			rating.Change = player.rating - players[index].rating
+			result.Players = append(result.Players, rating)
+		}
+	}
+	ev.responseCh <- apiResponse{data: result}
+}
+
+// handleGetRating get rating of uid, not found when uid has no match
+func handleGetRating(ctx context.Context, ev getRatingEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	system := ratingOf(ev.leaderboardID)
+	if system == "" {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusNotFound, CodeNotFound,
+			fmt.Sprintf("event type %s sum scores and has no rating", ev.leaderboardID))}
+		return
+	}
+	rankingName := ev.leaderboardID + ev.slice + ev.period
+	if _, err := store.GetScore(ctx, ratingsName(rankingName, "rating"), ev.uid); err == storage.ErrMemberNotFound {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("uid %s has no match", ev.uid))}
+		return
+	} else if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	player, err := loadRating(ctx, rankingName, ev.uid)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	rating, err := ratingEntry(ctx, rankingName, system, player)
+	ev.responseCh <- apiResponse{data: rating, err: err}
+}
+
+// ratingEntry get rating of player with rank in leaderboard
+func ratingEntry(ctx context.Context, rankingName string, system string, player playerRating) (PlayerRating, error) {
+	rank, err := store.GetRank(ctx, rankingName, player.uid)
+	if err != nil {
+		return PlayerRating{}, err
+	}
+	rating := PlayerRating{UID: player.uid, Rank: rank, Rating: player.rating, Matches: int64(player.matches)}
+	if system == config.RatingGlicko2 {
+		rating.Deviation = player.deviation
+		rating.Volatility = player.volatility
+	}
+	return rating, nil
+}
+
+// playerRating is rating state of uid, new player has initial values of config
+type playerRating struct {
+	uid        string
+	rating     float64
+	deviation  float64
+	volatility float64
+	matches    float64
+}
+
+func loadRating(ctx context.Context, rankingName string, uid string) (playerRating, error) {
+	ratings := config.Current().Leaderboard.Ratings
+	player := playerRating{uid: uid}
+	for _, field := range []struct {
+		name    string
+		value   *float64
+		initial float64
+	}{
+		{"rating", &player.rating, ratings.Initial},
+		{"deviation", &player.deviation, ratings.Deviation},
+		{"volatility", &player.volatility, ratings.Volatility},
+		{"matches", &player.matches, 0},
+	} {
+		value, err := store.GetScore(ctx, ratingsName(rankingName, field.name), uid)
+		if err == storage.ErrMemberNotFound {
+			value = field.initial
+		} else if err != nil {
+			return playerRating{}, err
+		}
+		*field.value = value
+	}
+	return player, nil
+}
+
+// saveRating keep rating state of player and publish rating to leaderboard
+func saveRating(ctx context.Context, rankingName string, period string, system string, player playerRating) error {
+	values := map[string]float64{"rating": player.rating, "matches": player.matches}
+	if system == config.RatingGlicko2 {
+		values["deviation"] = player.deviation
+		values["volatility"] = player.volatility
+	}
+	for name, value := range values {
+		if err := setRegistered(ctx, ratingsName(rankingName, name), value, player.uid, ratingsListKey(period)); err != nil {
+			return err
+		}
+	}
+	return setRegistered(ctx, rankingName, player.rating, player.uid, period)
+}
+
+// setRegistered set score of uid and keep ranking name in listKey
+func setRegistered(ctx context.Context, rankingName string, score float64, uid string, listKey string) error {
+	if err := store.IncreaseScore(ctx, rankingName, 0, uid, listKey); err != nil {
+		return err
+	}
+	return store.SetScore(ctx, rankingName, score, uid)
+}
+
+// publishRatings copy rating of every rating leaderboard of period to leaderboard, leaderboards are cleared by rebuild but ratings are not
+func publishRatings(ctx context.Context, period string) error {
+	names, err := store.ListRankings(ctx, ratingsListKey(period))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !strings.HasSuffix(name, ":rating") {
+			continue
+		}
+		rankingName := strings.TrimSuffix(strings.TrimPrefix(name, "ratings:"), ":rating")
+		count, err := store.Count(ctx, name)
+		if err != nil {
+			return err
+		}
+		members, err := store.GetRankRange(ctx, name, 1, count)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			if err := setRegistered(ctx, rankingName, member.Score, member.UID, period); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rateMatch get players with rating after match, players are ordered from first place and every player beat players after it.
+// Match of two players is draw when draw is true.
+func rateMatch(system string, players []playerRating, draw bool) []playerRating {
+	outcome := func(i int, j int) float64 {
+		switch {
+		case draw:
+			return 0.5
+		case i < j:
+			return 1
+		default:
+			return 0
+		}
+	}
+	if system == config.RatingGlicko2 {
+		return rateGlicko2(players, outcome)
+	}
+	return rateElo(players, outcome)
+}
+
+// rateElo update elo rating against every other player, k factor is shared between opponents
+func rateElo(players []playerRating, outcome func(int, int) float64) []playerRating {
+	k := config.Current().Leaderboard.Ratings.KFactor / float64(len(players)-1)
+	updated := make([]playerRating, len(players))
+	for i, player := range players {
+		change := 0.0
+		for j, opponent := range players {
+			if i == j {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, (opponent.rating-player.rating)/400))
+			change += k * (outcome(i, j) - expected)
+		}
+		player.rating += change
+		player.matches++
+		updated[i] = player
+	}
+	return updated
+}
+
+// rateGlicko2 update glicko-2 rating, match is one rating period against every other player
+func rateGlicko2(players []playerRating, outcome func(int, int) float64) []playerRating {
+	tau := config.Current().Leaderboard.Ratings.Tau
+	updated := make([]playerRating, len(players))
+	for i, player := range players {
+		mu := (player.rating - 1500) / glicko2Scale
+		phi := player.deviation / glicko2Scale
+
+		var variance, improvement float64
+		for j, opponent := range players {
+			if i == j {
+				continue
+			}
+			muJ := (opponent.rating - 1500) / glicko2Scale
+			phiJ := opponent.deviation / glicko2Scale
+			g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+			expected := 1 / (1 + math.Exp(-g*(mu-muJ)))
+			variance += g * g * expected * (1 - expected)
+			improvement += g * (outcome(i, j) - expected)
+		}
+		variance = 1 / variance
+		delta := variance * improvement
+
+		sigma := glicko2Volatility(phi, player.volatility, variance, delta, tau)
+		phiStar := math.Sqrt(phi*phi + sigma*sigma)
+		phiNew := 1 / math.Sqrt(1/(phiStar*phiStar)+1/variance)
+		muNew := mu + phiNew*phiNew*improvement
+
+		player.rating = glicko2Scale*muNew + 1500
+		player.deviation = glicko2Scale * phiNew
+		player.volatility = sigma
+		player.matches++
+		updated[i] = player
+	}
+	return updated
+}
+
+// glicko2Volatility get new volatility with Illinois algorithm of step 5 of Glickman's glicko-2 paper
+func glicko2Volatility(phi float64, sigma float64, variance float64, delta float64, tau float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		return ex*(delta*delta-phi*phi-variance-ex)/(2*math.Pow(phi*phi+variance+ex, 2)) - (x-a)/(tau*tau)
+	}
+
+	lower := a
+	var upper float64
+	if delta*delta > phi*phi+variance {
+		upper = math.Log(delta*delta - phi*phi - variance)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		upper = a - k*tau
+	}
+	fLower, fUpper := f(lower), f(upper)
+	for math.Abs(upper-lower) > glicko2Epsilon {
+		next := lower + (lower-upper)*fLower/(fUpper-fLower)
+		fNext := f(next)
+		if fNext*fUpper <= 0 {
+			lower, fLower = upper, fUpper
+		} else {
+			fLower /= 2
+		}
+		upper, fUpper = next, fNext
+	}
+	return math.Exp(lower / 2)
+}
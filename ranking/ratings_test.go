@@ -0,0 +1,141 @@
+package ranking
+
+import (
+	"math"
+	"net/http"
+	"rangkingserver/config"
+	"testing"
+)
+
+func withRatings(ratings config.RatingsConfig) func(c *config.Config) {
+	return func(c *config.Config) {
+		c.Leaderboard.Ratings = ratings
+	}
+}
+
+func near(got float64, want float64, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+// TestGlicko2PaperExample check example of Glickman's "Example of the Glicko-2 system"
+func TestGlicko2PaperExample(t *testing.T) {
+	setTestConfig(t, withRatings(config.RatingsConfig{Initial: 1500, KFactor: 32, Deviation: 350, Volatility: 0.06, Tau: 0.5}))
+	players := []playerRating{
+		{uid: "player", rating: 1500, deviation: 200, volatility: 0.06},
+		{uid: "won", rating: 1400, deviation: 30, volatility: 0.06},
+		{uid: "lost1", rating: 1550, deviation: 100, volatility: 0.06},
+		{uid: "lost2", rating: 1700, deviation: 300, volatility: 0.06},
+	}
+	// player beat first opponent and lose to the others in one rating period
+	outcome := func(i int, j int) float64 {
+		if (i == 0 && j == 1) || (j == 0 && i != 1) {
+			return 1
+		}
+		return 0
+	}
+	got := rateGlicko2(players, outcome)[0]
+
+	for _, check := range []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", got.rating, 1464.06, 0.01},
+		{"deviation", got.deviation, 151.52, 0.01},
+		{"volatility", got.volatility, 0.05999, 0.00001},
+	} {
+		if !near(check.got, check.want, check.tolerance) {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+	if got.matches != 1 {
+		t.Errorf("matches = %v, want 1", got.matches)
+	}
+}
+
+func TestGlicko2Draw(t *testing.T) {
+	setTestConfig(t, withRatings(config.RatingsConfig{Initial: 1500, KFactor: 32, Deviation: 350, Volatility: 0.06, Tau: 0.5}))
+	players := []playerRating{
+		{uid: "a", rating: 1500, deviation: 350, volatility: 0.06},
+		{uid: "b", rating: 1500, deviation: 350, volatility: 0.06},
+	}
+	updated := rateMatch(config.RatingGlicko2, players, true)
+	for _, player := range updated {
+		if !near(player.rating, 1500, 1e-9) || player.deviation >= 350 {
+			t.Errorf("%s after draw of equal players = %+v, want rating 1500 and lower deviation", player.uid, player)
+		}
+	}
+	updated = rateMatch(config.RatingGlicko2, players, false)
+	if !(updated[0].rating > 1500 && updated[1].rating < 1500) || !near(updated[0].rating-1500, 1500-updated[1].rating, 1e-9) {
+		t.Errorf("win of equal players = %+v, want symmetric change", updated)
+	}
+}
+
+func TestEloKFactor(t *testing.T) {
+	setTestConfig(t, withRatings(config.RatingsConfig{Initial: 1500, KFactor: 32, Deviation: 350, Volatility: 0.06, Tau: 0.5}))
+	for _, test := range []struct {
+		name    string
+		players []float64
+		draw    bool
+		want    []float64
+	}{
+		{name: "equal win", players: []float64{1500, 1500}, want: []float64{1516, 1484}},
+		{name: "equal draw", players: []float64{1500, 1500}, draw: true, want: []float64{1500, 1500}},
+		{name: "favourite win", players: []float64{1600, 1400}, want: []float64{1607.6881, 1392.3119}},
+		{name: "favourite draw", players: []float64{1600, 1400}, draw: true, want: []float64{1591.6881, 1408.3119}},
+		{name: "favourite lose", players: []float64{1400, 1600}, want: []float64{1424.3119, 1575.6881}},
+		// every player beat players after it, k factor is shared between opponents
+		{name: "placements", players: []float64{1500, 1500, 1500}, want: []float64{1516, 1500, 1484}},
+		{name: "placements upset", players: []float64{1400, 1500, 1600}, want: []float64{1422.3970, 1500, 1577.6030}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			players := make([]playerRating, len(test.players))
+			for index, rating := range test.players {
+				players[index] = playerRating{rating: rating, matches: 2}
+			}
+			updated := rateMatch(config.RatingElo, players, test.draw)
+			var total float64
+			for index, player := range updated {
+				if !near(player.rating, test.want[index], 0.0001) {
+					t.Errorf("rating of place %d = %.4f, want %.4f", index+1, player.rating, test.want[index])
+				}
+				if player.matches != 3 {
+					t.Errorf("matches of place %d = %v, want 3", index+1, player.matches)
+				}
+				total += player.rating - test.players[index]
+			}
+			if !near(total, 0, 1e-9) {
+				t.Errorf("ratings changed by %v in total, want 0", total)
+			}
+		})
+	}
+}
+
+func TestMatchPlacements(t *testing.T) {
+	startTest(t, func(c *config.Config) {
+		c.Leaderboard.Definitions = []config.LeaderboardDefinition{{EventType: "duel", Rating: config.RatingElo}}
+	})
+	var result MatchResult
+	if code := call(t, LeaderboardsV1, http.MethodPost, V1Prefix+"/duel/matches", MatchRequest{Placements: []string{"c", "b", "a"}}, &result); code != http.StatusOK {
+		t.Fatalf("submit match: status %d", code)
+	}
+	want := map[string]float64{"c": 1516, "b": 1500, "a": 1484}
+	if len(result.Players) != 3 {
+		t.Fatalf("players = %+v, want 3", result.Players)
+	}
+	for index, player := range result.Players {
+		if player.UID != []string{"c", "b", "a"}[index] || !near(player.Rating, want[player.UID], 0.0001) || player.Matches != 1 {
+			t.Errorf("player %d = %+v, want rating %v after first match", index, player, want[player.UID])
+		}
+	}
+
+	if code := call(t, LeaderboardsV1, http.MethodPost, V1Prefix+"/duel/matches", MatchRequest{Winner: "a", Loser: "c", Draw: true}, &result); code != http.StatusOK {
+		t.Fatalf("submit draw: status %d", code)
+	}
+	if change := result.Players[0].Change; !near(change, 32*(0.5-1/(1+math.Pow(10, 32.0/400))), 0.0001) {
+		t.Errorf("change of a after draw with c = %v", change)
+	}
+	if code := call(t, LeaderboardsV1, http.MethodPost, V1Prefix+"/duel/scores", ScoreRequest{UID: "a", Amount: new(float64)}, nil); code != http.StatusBadRequest {
+		t.Errorf("score to rating leaderboard: status %d, want 400", code)
+	}
+}
@@ -0,0 +1,315 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"rangkingserver/webhook"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// seasons of event ranking key, leaderboards of ended season are archived and new season start with carried over scores
+//
+//	seasons                        season -> current season, 1 when not set; started -> unix time current season started, not set in season 1
+//	{ranking}:season:{season}      archived leaderboard, registered under seasonListKey(season)
+//	seasons:seed:{ranking}         uid -> carried over score leaderboard started current season with, registered under seedListKey(period)
+const seasonsIndex = "seasons"
+
+// archiveName get ranking name of leaderboard archived at end of season
+func archiveName(rankingName string, season int64) string {
+	return rankingName + ":season:" + strconv.FormatInt(season, 10)
+}
+
+// seasonListKey get list key of leaderboards archived at end of season
+func seasonListKey(season int64) string {
+	return "seasons:" + strconv.FormatInt(season, 10)
+}
+
+// seedName get ranking name of carried over scores of leaderboard, rebuild from DB start leaderboard from them
+func seedName(rankingName string) string {
+	return "seasons:seed:" + rankingName
+}
+
+// seedListKey get list key of carried over scores of period
+func seedListKey(period string) string {
+	return "seasons:seeds:" + period
+}
+
+// currentSeason get season of event ranking key
+func currentSeason(ctx context.Context) (int64, error) {
+	season, err := store.GetScore(ctx, seasonsIndex, "season")
+	if err == storage.ErrMemberNotFound {
+		return 1, nil
+	}
+	return int64(season), err
+}
+
+// seasonStart get time current season started, zero unix time when no season ended so every play_event is in season
+func seasonStart(ctx context.Context) (time.Time, error) {
+	started, err := store.GetScore(ctx, seasonsIndex, "started")
+	if err == storage.ErrMemberNotFound {
+		return time.Unix(0, 0), nil
+	}
+	return time.Unix(int64(started), 0), err
+}
+
+// seedSeason add carried over scores of period to its leaderboards, rebuild from DB add play_event of season on top of them
+func seedSeason(ctx context.Context, period string) error {
+	names, err := store.ListRankings(ctx, seedListKey(period))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		members, err := store.GetRange(ctx, name, 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			if err := store.IncreaseScore(ctx, strings.TrimPrefix(name, "seasons:seed:"), member.Score, member.UID, period); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// archivedSeasons get ended seasons that are still kept, oldest first
+func archivedSeasons(season int64) []int64 {
+	first := season - int64(config.Current().Leaderboard.Seasons.Keep)
+	if first < 1 {
+		first = 1
+	}
+	archived := []int64{}
+	for ended := first; ended < season; ended++ {
+		archived = append(archived, ended)
+	}
+	return archived
+}
+
+// carryOver get score uid start new season with, highest of carry_over part of old score and floor of highest tier reached
+func carryOver(seasons config.SeasonsConfig, score float64) float64 {
+	carried := score * seasons.CarryOver
+	for _, floor := range seasons.Floors {
+		if score >= floor.MinScore && floor.Score > carried {
+			carried = floor.Score
+		}
+	}
+	return carried
+}
+
+func endSeason(w http.ResponseWriter, r *http.Request) {
+	if err := endLeagueGroups(r.Context(), config.Current().Leaderboard.EventRankingKey); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	responseCh := make(chan apiResponse)
+	eventCh <- endSeasonEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getSeasons(w http.ResponseWriter, r *http.Request) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getSeasonsEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getSeasonEntries(w http.ResponseWriter, r *http.Request, leaderboardID string, value string) {
+	details := make(map[string]string)
+	season, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || season < 1 {
+		details["season"] = "must be positive integer"
+	}
+	limit := config.Current().Leaderboard.Limit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed == 0 {
+			parsed = -1
+		}
+		limit = checkLimit(parsed, details)
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	slice, err := querySlice(leaderboardID, queryDimensions(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getSeasonEntriesEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		season:         season,
+		limit:          limit,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// handleEndSeason archive every leaderboard of event ranking key and seed it with carried over scores in one step per leaderboard.
+// League season end as with clear, team leaderboards and contributions start empty, rating leaderboards keep ratings
+// and milestones can be reached again. Seeds and season start are kept so rebuild from DB only add play_event of new season to seeds.
+//
+// Step of each leaderboard is atomic but leaderboards are swapped one after another, not in one transaction. Seeds and season
+// are replaced only after every leaderboard is archived, so when end of season fail midway rebuild from DB restore leaderboards
+// of old season from old seeds, it must be run before season is ended again.
+func handleEndSeason(ctx context.Context, ev endSeasonEvent) {
+	eventRankingKey := config.Current().Leaderboard.EventRankingKey
+	seasons := config.Current().Leaderboard.Seasons
+	season, err := currentSeason(ctx)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if config.Current().Leaderboard.Leagues.Enabled {
+		if err := endLeagueSeason(ctx); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	ratingBoards := make(map[string]bool)
+	ratingNames, err := store.ListRankings(ctx, ratingsListKey(eventRankingKey))
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	for _, name := range ratingNames {
+		ratingBoards[strings.TrimSuffix(strings.TrimPrefix(name, "ratings:"), ":rating")] = true
+	}
+
+	rankingNames, err := store.ListRankings(ctx, eventRankingKey)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	started := clock()
+	seeds := make(map[string][]storage.Member)
+	var archived int64
+	for _, rankingName := range rankingNames {
+		if ratingBoards[rankingName] {
+			continue
+		}
+		count, err := store.Count(ctx, rankingName)
+		if err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+		members, err := store.GetRankRange(ctx, rankingName, 1, count)
+		if err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+		var seeded []storage.Member
+		for _, member := range members {
+			if score := carryOver(seasons, member.Score); score > 0 {
+				seeded = append(seeded, storage.Member{UID: member.UID, Score: score})
+			}
+		}
+		if err := store.ReplaceRanking(ctx, rankingName, archiveName(rankingName, season), seasonListKey(season), seeded); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+		seeds[rankingName] = seeded
+		archived++
+	}
+	if _, err := store.ClearAll(ctx, seedListKey(eventRankingKey)); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	for _, rankingName := range rankingNames {
+		for _, member := range seeds[rankingName] {
+			if err := setRegistered(ctx, seedName(rankingName), member.Score, member.UID, seedListKey(eventRankingKey)); err != nil {
+				ev.responseCh <- apiResponse{err: err}
+				return
+			}
+		}
+	}
+	for _, listKey := range []string{teamListKey(eventRankingKey), contributionsListKey(eventRankingKey), leaguesListKey(eventRankingKey), milestonesListKey(eventRankingKey)} {
+		if _, err := store.ClearAll(ctx, listKey); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	// seeds are decayed scores, decay taken in old season is not taken again after rebuild
+	if err := clearDecayDebt(ctx); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := store.SetScore(ctx, seasonsIndex, float64(season+1), "season"); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := store.SetScore(ctx, seasonsIndex, float64(started.Unix()), "started"); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if expired := season - int64(seasons.Keep); expired >= 1 {
+		if _, err := store.ClearAll(ctx, seasonListKey(expired)); err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	notifyAllBoards()
+	emitWebhook(webhook.KindBoardReset, BoardReset{Period: eventRankingKey, Cleared: archived, Season: season})
+	zap.L().Info("season ended", zap.Int64("season", season), zap.Int64("archived", archived))
+	ev.responseCh <- apiResponse{data: SeasonResult{Ended: season, Season: season + 1, Archived: archived}}
+}
+
+// handleGetSeasons get current season and archived seasons
+func handleGetSeasons(ctx context.Context, ev getSeasonsEvent) {
+	season, err := currentSeason(ctx)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	ev.responseCh <- apiResponse{data: SeasonList{Season: season, Archived: archivedSeasons(season)}}
+}
+
+// handleGetSeasonEntries get top entries of leaderboard archived at end of season
+func handleGetSeasonEntries(ctx context.Context, ev getSeasonEntriesEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	season, err := currentSeason(ctx)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	kept := false
+	for _, archived := range archivedSeasons(season) {
+		kept = kept || archived == ev.season
+	}
+	if !kept {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("season %d is not archived", ev.season))}
+		return
+	}
+	eventRankingKey := config.Current().Leaderboard.EventRankingKey
+	entries, err := topEntries(ctx, archiveName(ev.leaderboardID+ev.slice+eventRankingKey, ev.season), ev.limit)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	ev.responseCh <- apiResponse{data: EntryList{
+		LeaderboardID: ev.leaderboardID,
+		Dimensions:    sliceDimensions(ev.slice),
+		Period:        eventRankingKey,
+		Season:        ev.season,
+		Entries:       entries,
+	}}
+}
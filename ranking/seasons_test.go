@@ -0,0 +1,95 @@
+package ranking
+
+import (
+	"context"
+	"net/http"
+	"rangkingserver/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCarryOver(t *testing.T) {
+	floors := []config.SeasonFloor{{MinScore: 100, Score: 80}, {MinScore: 1000, Score: 300}}
+	for _, test := range []struct {
+		name      string
+		carryOver float64
+		score     float64
+		want      float64
+	}{
+		{name: "below floors", carryOver: 0.5, score: 50, want: 25},
+		{name: "floor above carried", carryOver: 0.5, score: 100, want: 80},
+		{name: "carried above floor", carryOver: 0.5, score: 400, want: 200},
+		{name: "highest floor", carryOver: 0.1, score: 1000, want: 300},
+		{name: "no carry over", carryOver: 0, score: 99, want: 0},
+		{name: "whole score", carryOver: 1, score: 2000, want: 2000},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			seasons := config.SeasonsConfig{CarryOver: test.carryOver, Floors: floors}
+			if got := carryOver(seasons, test.score); got != test.want {
+				t.Errorf("carry over of %v = %v, want %v", test.score, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEndSeason(t *testing.T) {
+	setClock(t, time.Unix(1700000000, 0))
+	memory := startTest(t, func(c *config.Config) {
+		c.Leaderboard.Definitions = []config.LeaderboardDefinition{{EventType: "1"}, {EventType: "duel", Rating: config.RatingElo}}
+		c.Leaderboard.Seasons.CarryOver = 0.5
+		c.Leaderboard.Seasons.Floors = []config.SeasonFloor{{MinScore: 100, Score: 80}}
+	})
+	ctx := context.Background()
+	eventRankingKey := config.Current().Leaderboard.EventRankingKey
+	submit(t, "1", "a", 300)
+	submit(t, "1", "b", 100)
+	submit(t, "1", "c", 10)
+	if code := call(t, LeaderboardsV1, http.MethodPost, V1Prefix+"/duel/matches", MatchRequest{Winner: "a", Loser: "b"}, nil); code != http.StatusOK {
+		t.Fatalf("submit match: status %d", code)
+	}
+
+	var result SeasonResult
+	if code := call(t, LeaderboardsV1, http.MethodPost, V1Prefix+"/seasons", nil, &result); code != http.StatusOK {
+		t.Fatalf("end season: status %d", code)
+	}
+	// rating leaderboard is not archived
+	if want := (SeasonResult{Ended: 1, Season: 2, Archived: 1}); result != want {
+		t.Errorf("end season = %+v, want %+v", result, want)
+	}
+	var list EntryList
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/entries", nil, &list); code != http.StatusOK {
+		t.Fatalf("get entries: status %d", code)
+	}
+	// a carry half of score, b reached floor, c carry half
+	if want := []Entry{{UID: "a", Rank: 1, Score: 150}, {UID: "b", Rank: 2, Score: 80}, {UID: "c", Rank: 3, Score: 5}}; !reflect.DeepEqual(list.Entries, want) {
+		t.Errorf("entries of season 2 = %+v, want %+v", list.Entries, want)
+	}
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/seasons/1/entries", nil, &list); code != http.StatusOK {
+		t.Fatalf("get season 1 entries: status %d", code)
+	}
+	if want := []Entry{{UID: "a", Rank: 1, Score: 300}, {UID: "b", Rank: 2, Score: 100}, {UID: "c", Rank: 3, Score: 10}}; !reflect.DeepEqual(list.Entries, want) {
+		t.Errorf("entries of season 1 = %+v, want %+v", list.Entries, want)
+	}
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/duel/entries", nil, &list); code != http.StatusOK {
+		t.Fatalf("get duel entries: status %d", code)
+	}
+	if want := []Entry{{UID: "a", Rank: 1, Score: 1516}, {UID: "b", Rank: 2, Score: 1484}}; !reflect.DeepEqual(list.Entries, want) {
+		t.Errorf("duel entries = %+v, want ratings kept %+v", list.Entries, want)
+	}
+	if names, err := memory.ListRankings(ctx, seasonListKey(1)); err != nil || !reflect.DeepEqual(names, []string{archiveName("1"+eventRankingKey, 1)}) {
+		t.Errorf("archived leaderboards = %v, %v", names, err)
+	}
+
+	// rebuild start season from seeds and add play_event of season
+	rebuildFrom(t, testEvents{{UID: "c", EventType: "1", Amount: "1"}})
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/entries", nil, &list); code != http.StatusOK {
+		t.Fatalf("get entries: status %d", code)
+	}
+	if want := []Entry{{UID: "a", Rank: 1, Score: 150}, {UID: "b", Rank: 2, Score: 80}, {UID: "c", Rank: 3, Score: 6}}; !reflect.DeepEqual(list.Entries, want) {
+		t.Errorf("entries rebuilt in season 2 = %+v, want %+v", list.Entries, want)
+	}
+	if started, err := seasonStart(ctx); err != nil || !started.Equal(clock()) {
+		t.Errorf("season start = %v, %v, want %v", started, err, clock())
+	}
+}
@@ -0,0 +1,370 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"strconv"
+	"strings"
+)
+
+// maxTeamNameLength is longest team name
+const maxTeamNameLength = 64
+
+// teamBoardName get ranking name of team leaderboard of leaderboard, registered under teamListKey of period
+func teamBoardName(rankingName string) string {
+	return "teams:" + rankingName
+}
+
+// teamListKey get list key of team leaderboards of period, kept apart from period so they are not listed, counted or archived as leaderboards
+func teamListKey(period string) string {
+	return "team_boards:" + period
+}
+
+// contributionsName get ranking name of score members earned for team in leaderboard
+func contributionsName(rankingName string, team string) string {
+	return "teams:" + rankingName + ":" + team
+}
+
+// contributionsListKey get list key of contributions of period, kept apart from period so they are not listed as leaderboards
+func contributionsListKey(period string) string {
+	return "teams:" + period
+}
+
+// addContribution add amount uid earned to contributions of team and update score of team from its contributions.
+// Score earned stays with team after member leave, new team only get score earned after join.
+func addContribution(ctx context.Context, rankingName string, period string, team string, uid string, amount float64) error {
+	if err := store.IncreaseScore(ctx, contributionsName(rankingName, team), amount, uid, contributionsListKey(period)); err != nil {
+		return err
+	}
+	teamBoard := teamBoardName(rankingName)
+	if config.Current().Leaderboard.Teams.TopK == 0 {
+		if err := store.IncreaseScore(ctx, teamBoard, amount, team, teamListKey(period)); err != nil {
+			return err
+		}
+		notifyBoard(teamBoard)
+		return nil
+	}
+	return setTeamScore(ctx, rankingName, period, team)
+}
+
+// setTeamScore set score of team to sum of top_k contributions, or of every contribution when top_k is 0
+func setTeamScore(ctx context.Context, rankingName string, period string, team string) error {
+	contributions := contributionsName(rankingName, team)
+	stop := int64(config.Current().Leaderboard.Teams.TopK)
+	if stop == 0 {
+		count, err := store.Count(ctx, contributions)
+		if err != nil {
+			return err
+		}
+		stop = count
+	}
+	members, err := store.GetRankRange(ctx, contributions, 1, stop)
+	if err != nil {
+		return err
+	}
+	var score float64
+	for _, member := range members {
+		score += member.Score
+	}
+
+	teamBoard := teamBoardName(rankingName)
+	// register team board under its list key then set score computed from contributions
+	if err := store.IncreaseScore(ctx, teamBoard, 0, team, teamListKey(period)); err != nil {
+		return err
+	}
+	if err := store.SetScore(ctx, teamBoard, score, team); err != nil {
+		return err
+	}
+	notifyBoard(teamBoard)
+	return nil
+}
+
+// rebuildTeamBoards clear team boards of period and set score of every team from its kept contributions.
+// play_event has no team, so rebuild from DB keep contributions and only compute team boards from them again.
+func rebuildTeamBoards(ctx context.Context, period string) error {
+	if _, err := store.ClearAll(ctx, teamListKey(period)); err != nil {
+		return err
+	}
+	names, err := store.ListRankings(ctx, contributionsListKey(period))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		// teams:{ranking name}:{team}, team has no colon
+		separator := strings.LastIndex(name, ":")
+		if !strings.HasPrefix(name, "teams:") || separator < len("teams:") {
+			continue
+		}
+		if err := setTeamScore(ctx, name[len("teams:"):separator], period, name[separator+1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teamOf get team of uid when team leaderboards are enabled
+func teamOf(ctx context.Context, uid string) (string, error) {
+	if !config.Current().Leaderboard.Teams.Enabled {
+		return "", nil
+	}
+	return store.GetTeam(ctx, uid)
+}
+
+// checkTeams team leaderboards must be enabled
+func checkTeams() error {
+	if !config.Current().Leaderboard.Teams.Enabled {
+		return newAPIError(http.StatusNotFound, CodeNotFound, "team leaderboards are disabled")
+	}
+	return nil
+}
+
+// checkTeamName team name must be short and have no separator
+func checkTeamName(team string, details map[string]string) {
+	switch {
+	case team == "" || len(team) > maxTeamNameLength:
+		details["team"] = fmt.Sprintf("must be 1 to %d characters", maxTeamNameLength)
+	case strings.ContainsAny(team, ": \t\r\n"):
+		details["team"] = "must not contain colon or space"
+	}
+}
+
+func getTeams(w http.ResponseWriter, r *http.Request, leaderboardID string) {
+	details := make(map[string]string)
+	period := queryPeriod(r, details)
+	limit := config.Current().Leaderboard.Limit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed == 0 {
+			parsed = -1
+		}
+		limit = checkLimit(parsed, details)
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	slice, err := querySlice(leaderboardID, queryDimensions(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getTeamsEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		period:         period,
+		limit:          limit,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getTeam(w http.ResponseWriter, r *http.Request, leaderboardID string, team string) {
+	details := make(map[string]string)
+	period := queryPeriod(r, details)
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	slice, err := querySlice(leaderboardID, queryDimensions(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseCh := make(chan apiResponse)
+	eventCh <- getTeamEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		leaderboardID:  leaderboardID,
+		slice:          slice,
+		period:         period,
+		team:           team,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getUserTeam(w http.ResponseWriter, r *http.Request, uid string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getUserTeamEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func joinTeam(w http.ResponseWriter, r *http.Request, uid string) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, r, newAPIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be application/json"))
+		return
+	}
+	var body TeamRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, r, invalidArgument(map[string]string{"body": err.Error()}))
+		return
+	}
+	details := make(map[string]string)
+	checkTeamName(body.Team, details)
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	sendSetTeam(w, r, uid, body.Team)
+}
+
+func sendSetTeam(w http.ResponseWriter, r *http.Request, uid string, team string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- setTeamEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		uid:            uid,
+		team:           team,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// handleGetTeams get top teams of team leaderboard
+func handleGetTeams(ctx context.Context, ev getTeamsEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := checkTeams(); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	members, err := store.GetRankRange(ctx, teamBoardName(ev.leaderboardID+ev.slice+ev.period), 1, ev.limit)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	teams := make([]TeamEntry, 0, len(members))
+	for index, member := range members {
+		teams = append(teams, TeamEntry{Team: member.UID, Rank: int64(index + 1), Score: member.Score})
+	}
+	ev.responseCh <- apiResponse{data: TeamEntryList{
+		LeaderboardID: ev.leaderboardID,
+		Dimensions:    sliceDimensions(ev.slice),
+		Period:        ev.period,
+		Teams:         teams,
+	}}
+}
+
+// handleGetTeam get rank, score and contribution of every member of team, highest contribution first
+func handleGetTeam(ctx context.Context, ev getTeamEvent) {
+	if err := checkLeaderboard(ev.leaderboardID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := checkTeams(); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	rankingName := ev.leaderboardID + ev.slice + ev.period
+	teamBoard := teamBoardName(rankingName)
+	rank, err := store.GetRank(ctx, teamBoard, ev.team)
+	if err == storage.ErrMemberNotFound {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("team %s has no score", ev.team))}
+		return
+	}
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	score, err := store.GetScore(ctx, teamBoard, ev.team)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	contributions, err := store.GetRankRange(ctx, contributionsName(rankingName, ev.team), 1, maxEntriesLimit)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	members, err := store.GetTeamMembers(ctx, ev.team)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	isMember := make(map[string]bool, len(members))
+	for _, uid := range members {
+		isMember[uid] = true
+	}
+
+	topK := config.Current().Leaderboard.Teams.TopK
+	breakdown := TeamBreakdown{
+		LeaderboardID: ev.leaderboardID,
+		Dimensions:    sliceDimensions(ev.slice),
+		Period:        ev.period,
+		Team:          ev.team,
+		Rank:          rank,
+		Score:         score,
+		Contributions: make([]Contribution, 0, len(contributions)),
+	}
+	for index, contribution := range contributions {
+		breakdown.Contributions = append(breakdown.Contributions, Contribution{
+			UID:     contribution.UID,
+			Score:   contribution.Score,
+			Counted: topK == 0 || index < topK,
+			Member:  isMember[contribution.UID],
+		})
+	}
+	ev.responseCh <- apiResponse{data: breakdown}
+}
+
+// handleGetUserTeam get team of uid
+func handleGetUserTeam(ctx context.Context, ev getUserTeamEvent) {
+	team, err := store.GetTeam(ctx, ev.uid)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if team == "" {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("uid %s has no team", ev.uid))}
+		return
+	}
+	ev.responseCh <- apiResponse{data: TeamMembership{UID: ev.uid, Team: team}}
+}
+
+// handleSetTeam move uid to team, "" leave team, team must have room for uid
+func handleSetTeam(ctx context.Context, ev setTeamEvent) {
+	if ev.team != "" {
+		members, err := store.GetTeamMembers(ctx, ev.team)
+		if err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+		maxMembers := config.Current().Leaderboard.Teams.MaxMembers
+		if len(members) >= maxMembers && !containsString(members, ev.uid) {
+			ev.responseCh <- apiResponse{err: invalidArgument(map[string]string{
+				"team": fmt.Sprintf("team %s has %d members, at most %d", ev.team, len(members), maxMembers),
+			})}
+			return
+		}
+	}
+	if err := store.SetTeam(ctx, ev.uid, ev.team); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	ev.responseCh <- apiResponse{data: TeamMembership{UID: ev.uid, Team: ev.team}}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
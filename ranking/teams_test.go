@@ -0,0 +1,154 @@
+package ranking
+
+import (
+	"context"
+	"net/http"
+	"rangkingserver/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func enableTeams(topK int) func(c *config.Config) {
+	return func(c *config.Config) {
+		c.Leaderboard.Teams.Enabled = true
+		c.Leaderboard.Teams.TopK = topK
+	}
+}
+
+func setTeam(t *testing.T, uid string, team string) {
+	t.Helper()
+	method, body := http.MethodPut, interface{}(TeamRequest{Team: team})
+	if team == "" {
+		method, body = http.MethodDelete, nil
+	}
+	if code := call(t, UsersV1, method, V1UsersPrefix+"/"+uid+"/team", body, nil); code != http.StatusOK {
+		t.Fatalf("set team of %s to %q: status %d", uid, team, code)
+	}
+}
+
+func teamScores(t *testing.T, leaderboardID string) map[string]float64 {
+	t.Helper()
+	var list TeamEntryList
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/"+leaderboardID+"/teams", nil, &list); code != http.StatusOK {
+		t.Fatalf("get teams: status %d", code)
+	}
+	scores := make(map[string]float64)
+	for _, entry := range list.Teams {
+		scores[entry.Team] = entry.Score
+	}
+	return scores
+}
+
+func TestTeamTopKRecompute(t *testing.T) {
+	startTest(t, enableTeams(2))
+	for _, uid := range []string{"a", "b", "c"} {
+		setTeam(t, uid, "red")
+	}
+	submit(t, "1", "a", 10)
+	submit(t, "1", "b", 20)
+	submit(t, "1", "c", 5)
+	if got := teamScores(t, "1")["red"]; got != 30 {
+		t.Errorf("score of red = %v, want 30 from top 2 contributions", got)
+	}
+
+	// c overtake a and b, top 2 is c and b
+	submit(t, "1", "c", 30)
+	if got := teamScores(t, "1")["red"]; got != 55 {
+		t.Errorf("score of red = %v, want 55 after c enter top 2", got)
+	}
+
+	var breakdown TeamBreakdown
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/teams/red", nil, &breakdown); code != http.StatusOK {
+		t.Fatalf("get team: status %d", code)
+	}
+	want := []Contribution{
+		{UID: "c", Score: 35, Counted: true, Member: true},
+		{UID: "b", Score: 20, Counted: true, Member: true},
+		{UID: "a", Score: 10, Counted: false, Member: true},
+	}
+	if !reflect.DeepEqual(breakdown.Contributions, want) {
+		t.Errorf("contributions = %+v, want %+v", breakdown.Contributions, want)
+	}
+	if breakdown.Score != 55 || breakdown.Rank != 1 {
+		t.Errorf("team rank %d score %v, want 1 and 55", breakdown.Rank, breakdown.Score)
+	}
+}
+
+func TestTeamJoinLeaveMidPeriod(t *testing.T) {
+	startTest(t, enableTeams(0))
+	submit(t, "1", "a", 10)
+	if scores := teamScores(t, "1"); len(scores) != 0 {
+		t.Errorf("teams = %v, score earned before join count for no team", scores)
+	}
+
+	setTeam(t, "a", "red")
+	submit(t, "1", "a", 5)
+	setTeam(t, "a", "blue")
+	submit(t, "1", "a", 7)
+	if got, want := teamScores(t, "1"), map[string]float64{"red": 5, "blue": 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("teams after move = %v, want %v, score stay with team it was earned for", got, want)
+	}
+
+	setTeam(t, "a", "")
+	submit(t, "1", "a", 100)
+	if got, want := teamScores(t, "1"), map[string]float64{"red": 5, "blue": 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("teams after leave = %v, want %v", got, want)
+	}
+	var breakdown TeamBreakdown
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/teams/red", nil, &breakdown); code != http.StatusOK {
+		t.Fatalf("get team: status %d", code)
+	}
+	if want := []Contribution{{UID: "a", Score: 5, Counted: true, Member: false}}; !reflect.DeepEqual(breakdown.Contributions, want) {
+		t.Errorf("contributions of red = %+v, want %+v", breakdown.Contributions, want)
+	}
+	var entry Entry
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/entries/a", nil, &entry); code != http.StatusOK || entry.Score != 122 {
+		t.Errorf("entry of a = %+v status %d, want score 122", entry, code)
+	}
+}
+
+func TestTeamBoardsListedApart(t *testing.T) {
+	memory := startTest(t, enableTeams(2))
+	ctx := context.Background()
+	period := config.Current().Leaderboard.EventRankingKey
+	setTeam(t, "a", "red")
+	setTeam(t, "b", "red")
+	submit(t, "1", "a", 10)
+	submit(t, "1", "b", 20)
+
+	names, err := memory.ListRankings(ctx, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "teams:") {
+			t.Errorf("team board %s is listed under period %s", name, period)
+		}
+	}
+	if names, err := memory.ListRankings(ctx, teamListKey(period)); err != nil || !reflect.DeepEqual(names, []string{teamBoardName("1" + period)}) {
+		t.Errorf("team boards = %v, %v", names, err)
+	}
+
+	// rebuild compute team board again from kept contributions
+	if err := memory.SetScore(ctx, teamBoardName("1"+period), 1, "red"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rebuildTeamBoards(ctx, period); err != nil {
+		t.Fatal(err)
+	}
+	if got := teamScores(t, "1")["red"]; got != 30 {
+		t.Errorf("score of red after rebuild = %v, want 30", got)
+	}
+
+	var result ClearResult
+	if code := call(t, LeaderboardsV1, http.MethodDelete, V1Prefix+"?period="+period, nil, &result); code != http.StatusOK {
+		t.Fatalf("clear: status %d", code)
+	}
+	if result.Cleared != 1 {
+		t.Errorf("cleared %d leaderboards, want 1, team board is not leaderboard", result.Cleared)
+	}
+	if scores := teamScores(t, "1"); len(scores) != 0 {
+		t.Errorf("teams after clear = %v, want none", scores)
+	}
+}
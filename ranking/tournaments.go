@@ -0,0 +1,647 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// tournaments are kept in sorted sets that are never cleared by period
+//
+//	tournaments                          tournament id -> start unix time
+//	tournaments:unfinalized              tournament id -> minus end unix time until tournament is finalized, ended first
+//	tournament:{id}                      Tournament JSON -> end unix time, one member
+//	tournament:{id}:previous             Tournament JSON before last save
+//	tournament:{id}:registrations        uid -> registration unix time
+//	tournament:{id}:board                uid -> score
+//	tournament:{id}:final                uid -> score copied from board at finalization, never written again
+const (
+	// V1TournamentsPrefix is path of v1 tournaments collection
+	V1TournamentsPrefix = "/v1/tournaments"
+	// tournamentsListKey is list key of tournament boards
+	tournamentsListKey = "tournaments:boards"
+	// tournamentFinalsListKey is list key of final standings of tournaments
+	tournamentFinalsListKey = "tournaments:finals"
+	// tournamentPreviousListKey is list key of tournament records replaced by save
+	tournamentPreviousListKey = "tournaments:previous"
+
+	tournamentsIndex = "tournaments"
+	unfinalizedIndex = "tournaments:unfinalized"
+
+	StatusUpcoming = "upcoming"
+	StatusActive   = "active"
+	StatusFinished = "finished"
+
+	// tournamentFinalizeInterval is how often FinalizeTournaments look for ended tournaments
+	tournamentFinalizeInterval = time.Minute
+)
+
+func tournamentName(tournamentID string) string {
+	return "tournament:" + tournamentID
+}
+
+func tournamentBoardName(tournamentID string) string {
+	return tournamentName(tournamentID) + ":board"
+}
+
+func tournamentRegistrationsName(tournamentID string) string {
+	return tournamentName(tournamentID) + ":registrations"
+}
+
+func tournamentFinalName(tournamentID string) string {
+	return tournamentName(tournamentID) + ":final"
+}
+
+// standingsName get ranking name entries of tournament are read from, final standings once tournament is finalized
+func standingsName(tournament Tournament) string {
+	if tournament.FinalizedAt != nil {
+		return tournamentFinalName(tournament.ID)
+	}
+	return tournamentBoardName(tournament.ID)
+}
+
+// TournamentsV1 route /v1/tournaments and /v1/tournaments/{id}/...
+//
+//	POST   /v1/tournaments                          create tournament
+//	GET    /v1/tournaments?status=&limit=           tournaments by start time, status upcoming, active or finished
+//	GET    /v1/tournaments/{id}                     tournament
+//	POST   /v1/tournaments/{id}/registrations       register uid of body
+//	POST   /v1/tournaments/{id}/scores              add amount to score of uid, only from start to end
+//	GET    /v1/tournaments/{id}/entries?limit=      top entries, final after end
+//	GET    /v1/tournaments/{id}/entries/{uid}       rank and score of uid
+func TournamentsV1(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, V1TournamentsPrefix), "/")
+	if path == "" {
+		if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+			return
+		}
+		if r.Method == http.MethodGet {
+			getTournaments(w, r)
+			return
+		}
+		createTournament(w, r)
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	tournamentID := segments[0]
+	switch {
+	case len(segments) == 1:
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getTournament(w, r, tournamentID)
+	case len(segments) == 2 && segments[1] == "registrations":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		registerTournament(w, r, tournamentID)
+	case len(segments) == 2 && segments[1] == "scores":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		submitTournamentScore(w, r, tournamentID)
+	case len(segments) == 2 && segments[1] == "entries":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getTournamentEntries(w, r, tournamentID)
+	case len(segments) == 3 && segments[1] == "entries" && segments[2] != "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getTournamentEntry(w, r, tournamentID, segments[2])
+	default:
+		writeError(w, r, newAPIError(http.StatusNotFound, CodeNotFound, "no route for "+r.URL.Path))
+	}
+}
+
+// decodeBody decode JSON body of r into body, error is written when body is not JSON
+func decodeBody(w http.ResponseWriter, r *http.Request, body interface{}) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, r, newAPIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be application/json"))
+		return false
+	}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(body); err != nil {
+		writeError(w, r, invalidArgument(map[string]string{"body": err.Error()}))
+		return false
+	}
+	return true
+}
+
+func createTournament(w http.ResponseWriter, r *http.Request) {
+	var body TournamentRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	details := make(map[string]string)
+	if body.ID == "" || len(body.ID) > 64 || strings.ContainsAny(body.ID, ":/ ") {
+		details["id"] = "must be 1 to 64 characters without colon, slash or space"
+	}
+	if len(body.Name) > 128 {
+		details["name"] = "must be at most 128 characters"
+	}
+	if body.StartAt.IsZero() {
+		details["start_at"] = "is required"
+	}
+	if body.EndAt.IsZero() {
+		details["end_at"] = "is required"
+	} else if !body.EndAt.After(body.StartAt) {
+		details["end_at"] = "must be after start_at"
+	}
+	if body.RegistrationEndAt != nil {
+		if !body.RegistrationRequired {
+			details["registration_end_at"] = "needs registration_required"
+		} else if body.RegistrationEndAt.After(body.EndAt) {
+			details["registration_end_at"] = "must not be after end_at"
+		}
+	}
+	if body.MaxParticipants < 0 {
+		details["max_participants"] = "must not be negative, 0 is unlimited"
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+
+	tournament := Tournament{
+		ID:                   body.ID,
+		Name:                 body.Name,
+		StartAt:              body.StartAt.UTC(),
+		EndAt:                body.EndAt.UTC(),
+		RegistrationRequired: body.RegistrationRequired,
+		MaxParticipants:      body.MaxParticipants,
+	}
+	if body.RegistrationRequired {
+		registrationEndAt := tournament.EndAt
+		if body.RegistrationEndAt != nil {
+			registrationEndAt = body.RegistrationEndAt.UTC()
+		}
+		tournament.RegistrationEndAt = &registrationEndAt
+	}
+	responseCh := make(chan apiResponse)
+	eventCh <- createTournamentEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		now:            time.Now().UTC(),
+		tournament:     tournament,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getTournaments(w http.ResponseWriter, r *http.Request) {
+	details := make(map[string]string)
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", StatusUpcoming, StatusActive, StatusFinished:
+	default:
+		details["status"] = fmt.Sprintf("must be %s, %s or %s", StatusUpcoming, StatusActive, StatusFinished)
+	}
+	limit := int64(maxEntriesLimit)
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed == 0 {
+			parsed = -1
+		}
+		limit = checkLimit(parsed, details)
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	responseCh := make(chan apiResponse)
+	eventCh <- getTournamentsEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		now:            time.Now().UTC(),
+		status:         status,
+		limit:          limit,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getTournament(w http.ResponseWriter, r *http.Request, tournamentID string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getTournamentEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		now:            time.Now().UTC(),
+		tournamentID:   tournamentID,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func registerTournament(w http.ResponseWriter, r *http.Request, tournamentID string) {
+	var body RegistrationRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.UID == "" {
+		writeError(w, r, invalidArgument(map[string]string{"uid": "is required"}))
+		return
+	}
+	responseCh := make(chan apiResponse)
+	eventCh <- registerTournamentEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		now:            time.Now().UTC(),
+		tournamentID:   tournamentID,
+		uid:            body.UID,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func submitTournamentScore(w http.ResponseWriter, r *http.Request, tournamentID string) {
+	var body TournamentScoreRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	details := make(map[string]string)
+	if body.UID == "" {
+		details["uid"] = "is required"
+	}
+	if body.Amount == nil {
+		details["amount"] = "is required"
+	} else if math.IsNaN(*body.Amount) || math.IsInf(*body.Amount, 0) {
+		details["amount"] = "must be finite number"
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	responseCh := make(chan apiResponse)
+	eventCh <- submitTournamentScoreEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		now:            time.Now().UTC(),
+		tournamentID:   tournamentID,
+		uid:            body.UID,
+		amount:         *body.Amount,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getTournamentEntries(w http.ResponseWriter, r *http.Request, tournamentID string) {
+	details := make(map[string]string)
+	limit := config.Current().Leaderboard.Limit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed == 0 {
+			parsed = -1
+		}
+		limit = checkLimit(parsed, details)
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	responseCh := make(chan apiResponse)
+	eventCh <- getTournamentEntriesEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		now:            time.Now().UTC(),
+		tournamentID:   tournamentID,
+		limit:          limit,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+func getTournamentEntry(w http.ResponseWriter, r *http.Request, tournamentID string, uid string) {
+	responseCh := make(chan apiResponse)
+	eventCh <- getTournamentEntryEvent{
+		requestContext: newRequestContext(r.Context()),
+		responseCh:     responseCh,
+		now:            time.Now().UTC(),
+		tournamentID:   tournamentID,
+		uid:            uid,
+	}
+	writeAPIResponse(w, r, <-responseCh)
+}
+
+// handleCreateTournament keep new tournament, conflict when id is taken
+func handleCreateTournament(ctx context.Context, ev createTournamentEvent) {
+	count, err := store.Count(ctx, tournamentName(ev.tournament.ID))
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if count > 0 {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusConflict, CodeConflict, fmt.Sprintf("tournament %s already exists", ev.tournament.ID))}
+		return
+	}
+	if err := saveTournament(ctx, ev.tournament); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := store.SetScore(ctx, tournamentsIndex, float64(ev.tournament.StartAt.Unix()), ev.tournament.ID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if err := store.SetScore(ctx, unfinalizedIndex, -float64(ev.tournament.EndAt.Unix()), ev.tournament.ID); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	zap.L().Info("tournament created", zap.String("tournament", ev.tournament.ID),
+		zap.Time("start_at", ev.tournament.StartAt), zap.Time("end_at", ev.tournament.EndAt))
+	ev.responseCh <- apiResponse{data: withStatus(ev.tournament, ev.now)}
+}
+
+// handleGetTournaments get tournaments with status, latest start first
+func handleGetTournaments(ctx context.Context, ev getTournamentsEvent) {
+	count, err := store.Count(ctx, tournamentsIndex)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	members, err := store.GetRankRange(ctx, tournamentsIndex, 1, count)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	list := TournamentList{Tournaments: []Tournament{}}
+	for _, member := range members {
+		if int64(len(list.Tournaments)) >= ev.limit {
+			break
+		}
+		tournament, err := loadTournament(ctx, member.UID, ev.now)
+		if err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+		if ev.status == "" || tournament.Status == ev.status {
+			list.Tournaments = append(list.Tournaments, tournament)
+		}
+	}
+	ev.responseCh <- apiResponse{data: list}
+}
+
+// handleGetTournament get tournament with status and number of participants
+func handleGetTournament(ctx context.Context, ev getTournamentEvent) {
+	tournament, err := loadTournament(ctx, ev.tournamentID, ev.now)
+	ev.responseCh <- apiResponse{data: tournament, err: err}
+}
+
+// handleRegisterTournament register uid until registration end, registering again return first registration
+func handleRegisterTournament(ctx context.Context, ev registerTournamentEvent) {
+	tournament, err := loadTournament(ctx, ev.tournamentID, ev.now)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if !tournament.RegistrationRequired {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusConflict, CodeConflict,
+			fmt.Sprintf("tournament %s has no registration, every uid may score", ev.tournamentID))}
+		return
+	}
+	if !ev.now.Before(*tournament.RegistrationEndAt) {
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusConflict, CodeConflict,
+			fmt.Sprintf("registration of tournament %s closed at %s", ev.tournamentID, tournament.RegistrationEndAt.Format(time.RFC3339)))}
+		return
+	}
+	registrations := tournamentRegistrationsName(ev.tournamentID)
+	registeredAt, err := store.GetScore(ctx, registrations, ev.uid)
+	if err == nil {
+		ev.responseCh <- apiResponse{data: Registration{TournamentID: ev.tournamentID, UID: ev.uid, RegisteredAt: time.Unix(int64(registeredAt), 0).UTC()}}
+		return
+	}
+	if err != storage.ErrMemberNotFound {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	if tournament.MaxParticipants > 0 && tournament.Participants >= int64(tournament.MaxParticipants) {
+		ev.responseCh <- apiResponse{err: tournamentFull(tournament)}
+		return
+	}
+	if err := store.SetScore(ctx, registrations, float64(ev.now.Unix()), ev.uid); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	ev.responseCh <- apiResponse{data: Registration{TournamentID: ev.tournamentID, UID: ev.uid, RegisteredAt: time.Unix(ev.now.Unix(), 0).UTC()}}
+}
+
+// handleSubmitTournamentScore add score of participant from start to end of tournament
+func handleSubmitTournamentScore(ctx context.Context, ev submitTournamentScoreEvent) {
+	tournament, err := loadTournament(ctx, ev.tournamentID, ev.now)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	switch tournament.Status {
+	case StatusUpcoming:
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusConflict, CodeConflict,
+			fmt.Sprintf("tournament %s start at %s", ev.tournamentID, tournament.StartAt.Format(time.RFC3339)))}
+		return
+	case StatusFinished:
+		ev.responseCh <- apiResponse{err: newAPIError(http.StatusConflict, CodeConflict,
+			fmt.Sprintf("tournament %s ended at %s", ev.tournamentID, tournament.EndAt.Format(time.RFC3339)))}
+		return
+	}
+
+	board := tournamentBoardName(ev.tournamentID)
+	if tournament.RegistrationRequired {
+		_, err := store.GetScore(ctx, tournamentRegistrationsName(ev.tournamentID), ev.uid)
+		if err == storage.ErrMemberNotFound {
+			ev.responseCh <- apiResponse{err: newAPIError(http.StatusForbidden, CodeForbidden,
+				fmt.Sprintf("uid %s is not registered in tournament %s", ev.uid, ev.tournamentID))}
+			return
+		}
+		if err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	} else if tournament.MaxParticipants > 0 && tournament.Participants >= int64(tournament.MaxParticipants) {
+		// without registration first score of uid take a place
+		_, err := store.GetScore(ctx, board, ev.uid)
+		if err == storage.ErrMemberNotFound {
+			ev.responseCh <- apiResponse{err: tournamentFull(tournament)}
+			return
+		}
+		if err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	if err := store.IncreaseScore(ctx, board, ev.amount, ev.uid, tournamentsListKey); err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	notifyBoard(board)
+	entry, err := userEntry(ctx, board, ev.uid)
+	ev.responseCh <- apiResponse{data: entry, err: err}
+}
+
+// handleGetTournamentEntries get top entries of tournament, final once tournament is finalized
+func handleGetTournamentEntries(ctx context.Context, ev getTournamentEntriesEvent) {
+	tournament, err := loadTournament(ctx, ev.tournamentID, ev.now)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	members, err := store.GetRankRange(ctx, standingsName(tournament), 1, ev.limit)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	list := TournamentEntryList{
+		TournamentID: ev.tournamentID,
+		Status:       tournament.Status,
+		Final:        tournament.FinalizedAt != nil,
+		Entries:      make([]Entry, 0, len(members)),
+	}
+	for index, member := range members {
+		list.Entries = append(list.Entries, Entry{UID: member.UID, Rank: int64(index + 1), Score: member.Score})
+	}
+	ev.responseCh <- apiResponse{data: list}
+}
+
+// handleGetTournamentEntry get rank and score of uid in tournament
+func handleGetTournamentEntry(ctx context.Context, ev getTournamentEntryEvent) {
+	tournament, err := loadTournament(ctx, ev.tournamentID, ev.now)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	entry, err := userEntry(ctx, standingsName(tournament), ev.uid)
+	ev.responseCh <- apiResponse{data: entry, err: err}
+}
+
+func tournamentFull(tournament Tournament) error {
+	return newAPIError(http.StatusConflict, CodeConflict,
+		fmt.Sprintf("tournament %s is full, at most %d participants", tournament.ID, tournament.MaxParticipants))
+}
+
+// loadTournament get tournament with status and participants at now, it only read store.
+// Ended tournament stay unfinalized until FinalizeTournaments finalize it.
+func loadTournament(ctx context.Context, tournamentID string, now time.Time) (Tournament, error) {
+	members, err := store.GetRankRange(ctx, tournamentName(tournamentID), 1, 1)
+	if err != nil {
+		return Tournament{}, err
+	}
+	if len(members) == 0 {
+		return Tournament{}, newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("tournament %s not found", tournamentID))
+	}
+	var tournament Tournament
+	if err := json.Unmarshal([]byte(members[0].UID), &tournament); err != nil {
+		return Tournament{}, err
+	}
+	tournament = withStatus(tournament, now)
+
+	participants := tournamentBoardName(tournamentID)
+	if tournament.RegistrationRequired {
+		participants = tournamentRegistrationsName(tournamentID)
+	}
+	if tournament.Participants, err = store.Count(ctx, participants); err != nil {
+		return Tournament{}, err
+	}
+	return tournament, nil
+}
+
+// finalizeTournament copy board of ended tournament to final standings and mark it finalized, then send tournament.finished
+func finalizeTournament(ctx context.Context, tournament Tournament, now time.Time) error {
+	if err := store.UnionStore(ctx, tournamentFinalName(tournament.ID), []string{tournamentBoardName(tournament.ID)}, tournamentFinalsListKey); err != nil {
+		return err
+	}
+	finalizedAt := now
+	tournament.FinalizedAt = &finalizedAt
+	if err := saveTournament(ctx, tournament); err != nil {
+		return err
+	}
+	if err := store.RemoveMember(ctx, unfinalizedIndex, tournament.ID); err != nil {
+		return err
+	}
+	zap.L().Info("tournament finalized", zap.String("tournament", tournament.ID), zap.Int64("participants", tournament.Participants))
+	return emitTournamentFinished(ctx, tournament)
+}
+
+// FinalizeTournaments finalize ended tournaments every tournamentFinalizeInterval until ctx is done
+func FinalizeTournaments(ctx context.Context) {
+	ticker := time.NewTicker(tournamentFinalizeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !RankingSystemDataReady() {
+			continue
+		}
+		responseCh := make(chan apiResponse, 1)
+		select {
+		case eventCh <- finalizeTournamentsEvent{
+			requestContext: newRequestContext(ctx),
+			responseCh:     responseCh,
+			now:            time.Now(),
+		}:
+		case <-ctx.Done():
+			return
+		}
+		if response := <-responseCh; response.err != nil {
+			zap.L().Warn("cannot finalize tournaments", zap.Error(response.err))
+		}
+	}
+}
+
+// handleFinalizeTournaments finalize tournaments that ended and are not finalized
+func handleFinalizeTournaments(ctx context.Context, ev finalizeTournamentsEvent) {
+	members, err := store.GetRange(ctx, unfinalizedIndex, -float64(ev.now.Unix()), 0)
+	if err != nil {
+		ev.responseCh <- apiResponse{err: err}
+		return
+	}
+	for _, member := range members {
+		tournament, err := loadTournament(ctx, member.UID, ev.now)
+		if err == nil && tournament.Status == StatusFinished && tournament.FinalizedAt == nil {
+			err = finalizeTournament(ctx, tournament, ev.now)
+		}
+		if err != nil {
+			ev.responseCh <- apiResponse{err: err}
+			return
+		}
+	}
+	ev.responseCh <- apiResponse{}
+}
+
+// saveTournament replace stored tournament in one step, readers never see tournament missing. Status and participants are not stored.
+func saveTournament(ctx context.Context, tournament Tournament) error {
+	tournament.Status = ""
+	tournament.Participants = 0
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		return err
+	}
+	name := tournamentName(tournament.ID)
+	return store.ReplaceRanking(ctx, name, name+":previous", tournamentPreviousListKey, []storage.Member{
+		{UID: string(data), Score: float64(tournament.EndAt.Unix())},
+	})
+}
+
+// withStatus set status of tournament at now, active from start_at until end_at
+func withStatus(tournament Tournament, now time.Time) Tournament {
+	switch {
+	case now.Before(tournament.StartAt):
+		tournament.Status = StatusUpcoming
+	case now.Before(tournament.EndAt):
+		tournament.Status = StatusActive
+	default:
+		tournament.Status = StatusFinished
+	}
+	return tournament
+}
@@ -0,0 +1,186 @@
+package ranking
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func createTestTournament(t *testing.T, request TournamentRequest) Tournament {
+	t.Helper()
+	var tournament Tournament
+	if code := call(t, TournamentsV1, http.MethodPost, V1TournamentsPrefix, request, &tournament); code != http.StatusOK {
+		t.Fatalf("create tournament %s: status %d", request.ID, code)
+	}
+	return tournament
+}
+
+func getTestTournament(t *testing.T, tournamentID string) Tournament {
+	t.Helper()
+	var tournament Tournament
+	if code := call(t, TournamentsV1, http.MethodGet, V1TournamentsPrefix+"/"+tournamentID, nil, &tournament); code != http.StatusOK {
+		t.Fatalf("get tournament %s: status %d", tournamentID, code)
+	}
+	return tournament
+}
+
+func register(t *testing.T, tournamentID string, uid string) int {
+	t.Helper()
+	return call(t, TournamentsV1, http.MethodPost, V1TournamentsPrefix+"/"+tournamentID+"/registrations", RegistrationRequest{UID: uid}, nil)
+}
+
+func submitTournament(t *testing.T, tournamentID string, uid string, amount float64) int {
+	t.Helper()
+	return call(t, TournamentsV1, http.MethodPost, V1TournamentsPrefix+"/"+tournamentID+"/scores", TournamentScoreRequest{UID: uid, Amount: &amount}, nil)
+}
+
+// finalizeAt run one pass of FinalizeTournaments at now
+func finalizeAt(t *testing.T, now time.Time) {
+	t.Helper()
+	responseCh := make(chan apiResponse, 1)
+	eventCh <- finalizeTournamentsEvent{requestContext: newRequestContext(context.Background()), responseCh: responseCh, now: now}
+	if response := <-responseCh; response.err != nil {
+		t.Fatal(response.err)
+	}
+}
+
+func TestTournamentRegistration(t *testing.T) {
+	startTest(t, nil)
+	now := time.Now().UTC()
+	createTestTournament(t, TournamentRequest{ID: "cup", StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour), RegistrationRequired: true})
+	createTestTournament(t, TournamentRequest{ID: "open", StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour)})
+
+	if code := submitTournament(t, "cup", "a", 10); code != http.StatusForbidden {
+		t.Errorf("score of unregistered uid: status %d, want 403", code)
+	}
+	var first, again Registration
+	if code := call(t, TournamentsV1, http.MethodPost, V1TournamentsPrefix+"/cup/registrations", RegistrationRequest{UID: "a"}, &first); code != http.StatusOK {
+		t.Fatalf("register: status %d", code)
+	}
+	if code := call(t, TournamentsV1, http.MethodPost, V1TournamentsPrefix+"/cup/registrations", RegistrationRequest{UID: "a"}, &again); code != http.StatusOK || again != first {
+		t.Errorf("register again = %+v status %d, want first registration %+v", again, code, first)
+	}
+	if code := submitTournament(t, "cup", "a", 10); code != http.StatusOK {
+		t.Errorf("score of registered uid: status %d, want 200", code)
+	}
+	if tournament := getTestTournament(t, "cup"); tournament.Participants != 1 {
+		t.Errorf("participants = %d, want 1", tournament.Participants)
+	}
+
+	if code := register(t, "open", "a"); code != http.StatusConflict {
+		t.Errorf("register in tournament without registration: status %d, want 409", code)
+	}
+	if code := register(t, "missing", "a"); code != http.StatusNotFound {
+		t.Errorf("register in missing tournament: status %d, want 404", code)
+	}
+}
+
+func TestTournamentMaxParticipants(t *testing.T) {
+	startTest(t, nil)
+	now := time.Now().UTC()
+	createTestTournament(t, TournamentRequest{ID: "cup", StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour), RegistrationRequired: true, MaxParticipants: 2})
+	createTestTournament(t, TournamentRequest{ID: "open", StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour), MaxParticipants: 2})
+
+	for _, uid := range []string{"a", "b"} {
+		if code := register(t, "cup", uid); code != http.StatusOK {
+			t.Fatalf("register %s: status %d", uid, code)
+		}
+	}
+	if code := register(t, "cup", "c"); code != http.StatusConflict {
+		t.Errorf("register in full tournament: status %d, want 409", code)
+	}
+	if code := register(t, "cup", "a"); code != http.StatusOK {
+		t.Errorf("register again in full tournament: status %d, want 200", code)
+	}
+
+	// without registration first score of uid take a place
+	for _, uid := range []string{"a", "b"} {
+		if code := submitTournament(t, "open", uid, 1); code != http.StatusOK {
+			t.Fatalf("score of %s: status %d", uid, code)
+		}
+	}
+	if code := submitTournament(t, "open", "c", 1); code != http.StatusConflict {
+		t.Errorf("first score in full tournament: status %d, want 409", code)
+	}
+	if code := submitTournament(t, "open", "a", 1); code != http.StatusOK {
+		t.Errorf("score of participant in full tournament: status %d, want 200", code)
+	}
+}
+
+func TestTournamentWindow(t *testing.T) {
+	startTest(t, nil)
+	now := time.Now().UTC()
+	closed := now.Add(-time.Minute)
+	createTestTournament(t, TournamentRequest{ID: "upcoming", StartAt: now.Add(time.Hour), EndAt: now.Add(2 * time.Hour)})
+	createTestTournament(t, TournamentRequest{ID: "ended", StartAt: now.Add(-2 * time.Hour), EndAt: now.Add(-time.Hour)})
+	createTestTournament(t, TournamentRequest{ID: "late", StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour), RegistrationRequired: true, RegistrationEndAt: &closed})
+
+	if code := submitTournament(t, "upcoming", "a", 1); code != http.StatusConflict {
+		t.Errorf("score before start: status %d, want 409", code)
+	}
+	if code := submitTournament(t, "ended", "a", 1); code != http.StatusConflict {
+		t.Errorf("score after end: status %d, want 409", code)
+	}
+	if code := register(t, "late", "a"); code != http.StatusConflict {
+		t.Errorf("register after registration end: status %d, want 409", code)
+	}
+	for id, status := range map[string]string{"upcoming": StatusUpcoming, "ended": StatusFinished, "late": StatusActive} {
+		if tournament := getTestTournament(t, id); tournament.Status != status {
+			t.Errorf("status of %s = %s, want %s", id, tournament.Status, status)
+		}
+	}
+}
+
+func TestTournamentFinalize(t *testing.T) {
+	memory := startTest(t, nil)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	createTestTournament(t, TournamentRequest{ID: "cup", StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour)})
+	submitTournament(t, "cup", "a", 10)
+	submitTournament(t, "cup", "b", 20)
+
+	// reads after end do not finalize
+	after := now.Add(2 * time.Hour)
+	before, err := memory.GetRankRange(ctx, tournamentName("cup"), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responseCh := make(chan apiResponse, 1)
+	eventCh <- getTournamentEvent{requestContext: newRequestContext(ctx), responseCh: responseCh, now: after, tournamentID: "cup"}
+	response := <-responseCh
+	if response.err != nil {
+		t.Fatal(response.err)
+	}
+	if tournament := response.data.(Tournament); tournament.Status != StatusFinished || tournament.FinalizedAt != nil {
+		t.Errorf("tournament read after end = %+v, want finished and not finalized", tournament)
+	}
+	if stored, _ := memory.GetRankRange(ctx, tournamentName("cup"), 1, 1); !reflect.DeepEqual(stored, before) {
+		t.Errorf("read changed stored tournament to %v", stored)
+	}
+
+	finalizeAt(t, after)
+	stored, err := memory.GetRankRange(ctx, tournamentName("cup"), 1, 10)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("stored tournament = %v, %v, want one record", stored, err)
+	}
+	// final standings do not follow board
+	if err := memory.IncreaseScore(ctx, tournamentBoardName("cup"), 100, "a", tournamentsListKey); err != nil {
+		t.Fatal(err)
+	}
+	var list TournamentEntryList
+	if code := call(t, TournamentsV1, http.MethodGet, V1TournamentsPrefix+"/cup/entries", nil, &list); code != http.StatusOK {
+		t.Fatalf("get entries: status %d", code)
+	}
+	if !list.Final || !reflect.DeepEqual(list.Entries, []Entry{{UID: "b", Rank: 1, Score: 20}, {UID: "a", Rank: 2, Score: 10}}) {
+		t.Errorf("entries = %+v, want final standings b 20, a 10", list)
+	}
+	var entry Entry
+	if code := call(t, TournamentsV1, http.MethodGet, V1TournamentsPrefix+"/cup/entries/a", nil, &entry); code != http.StatusOK || entry.Score != 10 {
+		t.Errorf("entry of a = %+v status %d, want final score 10", entry, code)
+	}
+	if tournament := getTestTournament(t, "cup"); tournament.FinalizedAt == nil {
+		t.Error("tournament is not finalized")
+	}
+}
@@ -0,0 +1,69 @@
+package ranking
+
+import "sync"
+
+// boardWatchers wake subscribers of ranking when it changes
+var boardWatchers = struct {
+	sync.Mutex
+	watchers map[string]map[chan struct{}]struct{}
+}{watchers: make(map[string]map[chan struct{}]struct{})}
+
+// subscriptionsDone is closed when server stop, every subscription return
+var subscriptionsDone = make(chan struct{})
+var closeSubscriptions sync.Once
+
+// watchBoard get channel that receive when rankingName changes, call cancel when stop watching
+func watchBoard(rankingName string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	boardWatchers.Lock()
+	watchers, ok := boardWatchers.watchers[rankingName]
+	if !ok {
+		watchers = make(map[chan struct{}]struct{})
+		boardWatchers.watchers[rankingName] = watchers
+	}
+	watchers[ch] = struct{}{}
+	boardWatchers.Unlock()
+
+	return ch, func() {
+		boardWatchers.Lock()
+		delete(watchers, ch)
+		if len(watchers) == 0 {
+			delete(boardWatchers.watchers, rankingName)
+		}
+		boardWatchers.Unlock()
+	}
+}
+
+// notifyBoard wake watchers of rankingName, never block event loop, changes are coalesced while watcher is busy
+func notifyBoard(rankingName string) {
+	boardWatchers.Lock()
+	defer boardWatchers.Unlock()
+	for ch := range boardWatchers.watchers[rankingName] {
+		wake(ch)
+	}
+}
+
+// notifyAllBoards wake every watcher, used when rankings are cleared or rebuilt
+func notifyAllBoards() {
+	boardWatchers.Lock()
+	defer boardWatchers.Unlock()
+	for _, watchers := range boardWatchers.watchers {
+		for ch := range watchers {
+			wake(ch)
+		}
+	}
+}
+
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// CloseSubscriptions end every board subscription, call it before stopping gRPC server
+func CloseSubscriptions() {
+	closeSubscriptions.Do(func() {
+		close(subscriptionsDone)
+	})
+}
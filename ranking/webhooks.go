@@ -0,0 +1,320 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"rangkingserver/config"
+	"rangkingserver/webhook"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// V1WebhooksPrefix is path of v1 webhooks collection
+	V1WebhooksPrefix = "/v1/webhooks"
+
+	minSecretLength = 16
+	maxSecretLength = 255
+	maxURLLength    = 2048
+)
+
+// webhooks is nil when webhooks are disabled
+var webhooks *webhook.Dispatcher
+
+// SetWebhooks send leaderboard events to dispatcher, call before serving requests
+func SetWebhooks(dispatcher *webhook.Dispatcher) {
+	webhooks = dispatcher
+}
+
+// webhookWanted report whether any webhook receive kind
+func webhookWanted(kind string) bool {
+	return webhooks != nil && webhooks.Wants(kind)
+}
+
+// emitWebhook send event of kind to webhooks, never block
+func emitWebhook(kind string, data interface{}) {
+	if webhooks != nil {
+		webhooks.Emit(kind, data)
+	}
+}
+
+// WebhooksV1 route /v1/webhooks and /v1/webhooks/{id}/..., subscriptions are kept in webhook queue and not in event loop
+//
+//	POST   /v1/webhooks                         subscribe url to event kinds with secret
+//	GET    /v1/webhooks                         webhooks, oldest first
+//	GET    /v1/webhooks/{id}                    webhook
+//	DELETE /v1/webhooks/{id}                    delete webhook and its deliveries
+//	GET    /v1/webhooks/{id}/deliveries?limit=  delivery log, newest first
+func WebhooksV1(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if webhooks == nil {
+		writeError(w, r, newAPIError(http.StatusNotFound, CodeNotFound, "webhooks are disabled"))
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, V1WebhooksPrefix), "/")
+	if path == "" {
+		if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+			return
+		}
+		if r.Method == http.MethodGet {
+			getWebhooks(w, r)
+			return
+		}
+		createWebhook(w, r)
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	webhookID := segments[0]
+	switch {
+	case len(segments) == 1:
+		if !allowMethod(w, r, http.MethodGet, http.MethodDelete) {
+			return
+		}
+		if r.Method == http.MethodGet {
+			getWebhook(w, r, webhookID)
+			return
+		}
+		deleteWebhook(w, r, webhookID)
+	case len(segments) == 2 && segments[1] == "deliveries":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		getWebhookDeliveries(w, r, webhookID)
+	default:
+		writeError(w, r, newAPIError(http.StatusNotFound, CodeNotFound, "no route for "+r.URL.Path))
+	}
+}
+
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	var body WebhookRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	details := make(map[string]string)
+	if parsed, err := url.Parse(body.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(body.URL) > maxURLLength {
+		details["url"] = fmt.Sprintf("must be absolute http or https url of at most %d characters", maxURLLength)
+	} else if err := webhook.CheckURL(r.Context(), body.URL, config.Current().Webhooks.AllowedNetworks); err == webhook.ErrForbiddenTarget {
+		details["url"] = "must not be private, loopback, link-local or metadata address unless its network is in webhooks.allowed_networks"
+	} else if err != nil {
+		details["url"] = "host cannot be resolved"
+	}
+	if len(body.Secret) < minSecretLength || len(body.Secret) > maxSecretLength {
+		details["secret"] = fmt.Sprintf("must be %d to %d characters", minSecretLength, maxSecretLength)
+	}
+	var kinds []string
+	for _, kind := range body.Kinds {
+		if !webhook.KnownKind(kind) {
+			details["kinds"] = "must be some of " + strings.Join(webhook.Kinds, ", ")
+			break
+		}
+		duplicate := false
+		for _, added := range kinds {
+			duplicate = duplicate || added == kind
+		}
+		if !duplicate {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(body.Kinds) == 0 {
+		details["kinds"] = "must be some of " + strings.Join(webhook.Kinds, ", ")
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+
+	subscription := webhook.Subscription{
+		ID:        webhook.NewID(),
+		URL:       body.URL,
+		Kinds:     kinds,
+		Secret:    body.Secret,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if err := webhooks.Queue().AddSubscription(r.Context(), subscription); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := webhooks.Refresh(r.Context()); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeAPIResponse(w, r, apiResponse{data: webhookOf(subscription)})
+}
+
+func getWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := webhooks.Queue().Subscriptions(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	list := WebhookList{Webhooks: []Webhook{}}
+	for _, subscription := range subscriptions {
+		list.Webhooks = append(list.Webhooks, webhookOf(subscription))
+	}
+	writeAPIResponse(w, r, apiResponse{data: list})
+}
+
+func getWebhook(w http.ResponseWriter, r *http.Request, webhookID string) {
+	subscription, err := findWebhook(r.Context(), webhookID)
+	writeAPIResponse(w, r, apiResponse{data: webhookOf(subscription), err: err})
+}
+
+// deleteWebhook delete webhook and respond with deleted webhook
+func deleteWebhook(w http.ResponseWriter, r *http.Request, webhookID string) {
+	subscription, err := findWebhook(r.Context(), webhookID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := webhooks.Queue().DeleteSubscription(r.Context(), webhookID); err != nil {
+		writeError(w, r, webhookError(webhookID, err))
+		return
+	}
+	if err := webhooks.Refresh(r.Context()); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeAPIResponse(w, r, apiResponse{data: webhookOf(subscription)})
+}
+
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request, webhookID string) {
+	details := make(map[string]string)
+	limit := config.Current().Leaderboard.Limit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed == 0 {
+			parsed = -1
+		}
+		limit = checkLimit(parsed, details)
+	}
+	if len(details) > 0 {
+		writeError(w, r, invalidArgument(details))
+		return
+	}
+	if _, err := findWebhook(r.Context(), webhookID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	deliveries, err := webhooks.Queue().Deliveries(r.Context(), webhookID, int(limit))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	list := WebhookDeliveryList{WebhookID: webhookID, Deliveries: []WebhookDelivery{}}
+	for _, delivery := range deliveries {
+		list.Deliveries = append(list.Deliveries, webhookDeliveryOf(delivery))
+	}
+	writeAPIResponse(w, r, apiResponse{data: list})
+}
+
+// findWebhook get subscription of id, not found error when it does not exist
+func findWebhook(ctx context.Context, webhookID string) (webhook.Subscription, error) {
+	subscriptions, err := webhooks.Queue().Subscriptions(ctx)
+	if err != nil {
+		return webhook.Subscription{}, err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.ID == webhookID {
+			return subscription, nil
+		}
+	}
+	return webhook.Subscription{}, webhookError(webhookID, webhook.ErrNotFound)
+}
+
+func webhookError(webhookID string, err error) error {
+	if err == webhook.ErrNotFound {
+		return newAPIError(http.StatusNotFound, CodeNotFound, fmt.Sprintf("webhook %s not found", webhookID))
+	}
+	return err
+}
+
+func webhookOf(subscription webhook.Subscription) Webhook {
+	return Webhook{
+		ID:        subscription.ID,
+		URL:       subscription.URL,
+		Kinds:     subscription.Kinds,
+		CreatedAt: subscription.CreatedAt,
+	}
+}
+
+func webhookDeliveryOf(delivery webhook.Delivery) WebhookDelivery {
+	result := WebhookDelivery{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		Kind:           delivery.Kind,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		Payload:        delivery.Payload,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == webhook.StatusPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		result.NextAttemptAt = &nextAttemptAt
+	}
+	return result
+}
+
+// checkOvertaken emit player.overtaken for every uid that uid passed by write, up to webhooks.overtaken_limit uids right below its new rank
+func checkOvertaken(ctx context.Context, leaderboardID string, rankingName string, uid string, before boardPosition) error {
+	limit := int64(config.Current().Webhooks.OvertakenLimit)
+	if limit == 0 || !webhookWanted(webhook.KindPlayerOvertaken) {
+		return nil
+	}
+	after, err := positionOf(ctx, rankingName, uid)
+	if err != nil || after.rank == 0 || (before.rank != 0 && after.rank >= before.rank) {
+		return err
+	}
+	last := before.rank
+	if last == 0 {
+		if last, err = store.Count(ctx, rankingName); err != nil {
+			return err
+		}
+	}
+	if last > after.rank+limit {
+		last = after.rank + limit
+	}
+	if last <= after.rank {
+		return nil
+	}
+	members, err := store.GetRankRange(ctx, rankingName, after.rank+1, last)
+	if err != nil {
+		return err
+	}
+	period := config.Current().Leaderboard.EventRankingKey
+	for index, member := range members {
+		emitWebhook(webhook.KindPlayerOvertaken, PlayerOvertaken{
+			LeaderboardID: leaderboardID,
+			Period:        period,
+			UID:           member.UID,
+			Rank:          after.rank + 1 + int64(index),
+			Score:         member.Score,
+			ByUID:         uid,
+			ByRank:        after.rank,
+			ByScore:       after.score,
+		})
+	}
+	return nil
+}
+
+// emitTournamentFinished emit tournament.finished with top entries of finalized tournament
+func emitTournamentFinished(ctx context.Context, tournament Tournament) error {
+	if !webhookWanted(webhook.KindTournamentFinished) {
+		return nil
+	}
+	entries, err := topEntries(ctx, standingsName(tournament), config.Current().Leaderboard.Limit)
+	if err != nil {
+		return err
+	}
+	emitWebhook(webhook.KindTournamentFinished, TournamentFinished{Tournament: tournament, Entries: entries})
+	return nil
+}
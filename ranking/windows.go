@@ -0,0 +1,137 @@
+package ranking
+
+import (
+	"context"
+	"rangkingserver/config"
+	"rangkingserver/storage"
+	"strconv"
+	"time"
+)
+
+// rolling windows sum scores of buckets, buckets of same length and index of every leaderboard expire together
+//
+//	{board}:bucket:{length}:{index}   uid -> score earned in bucket, board is event type + slice
+//	buckets:{length}:{index}          list key of buckets, cleared once no window cover index
+//	windows                           window leaderboard -> index of newest bucket summed into it,
+//	                                  expired:{length} -> newest expired index
+//	{board}{window}                   window leaderboard, registered under window name
+//
+// Window leaderboard is summed again from its buckets when first read or written in new bucket, scores between are added to it directly.
+// play_event is loaded as sums without time, so rebuild from DB does not fill buckets, windows only have scores kept in store.
+const windowsIndex = "windows"
+
+// bucketLength get name of bucket length in minutes
+func bucketLength(bucket time.Duration) string {
+	return strconv.FormatInt(int64(bucket/time.Minute), 10) + "m"
+}
+
+// bucketIndex get index of bucket that contain t, buckets are aligned to unix epoch
+func bucketIndex(t time.Time, bucket time.Duration) int64 {
+	return t.Unix() / int64(bucket/time.Second)
+}
+
+func bucketName(board string, bucket time.Duration, index int64) string {
+	return board + ":bucket:" + bucketLength(bucket) + ":" + strconv.FormatInt(index, 10)
+}
+
+func bucketsListKey(bucket time.Duration, index int64) string {
+	return "buckets:" + bucketLength(bucket) + ":" + strconv.FormatInt(index, 10)
+}
+
+// addWindowScore add amount to current bucket of every bucket length and to every window leaderboard of board
+func addWindowScore(ctx context.Context, board string, uid string, amount float64, now time.Time) error {
+	windows := config.Current().Leaderboard.Windows
+	written := make(map[time.Duration]bool)
+	for _, window := range windows {
+		if written[window.Bucket] {
+			continue
+		}
+		written[window.Bucket] = true
+		index := bucketIndex(now, window.Bucket)
+		if err := store.IncreaseScore(ctx, bucketName(board, window.Bucket, index), amount, uid, bucketsListKey(window.Bucket, index)); err != nil {
+			return err
+		}
+		if err := expireBuckets(ctx, window.Bucket, index); err != nil {
+			return err
+		}
+	}
+
+	for _, window := range windows {
+		rolled, err := rollWindow(ctx, board, window, now)
+		if err != nil {
+			return err
+		}
+		// summed buckets already have amount
+		if !rolled {
+			if err := store.IncreaseScore(ctx, board+window.Name, amount, uid, window.Name); err != nil {
+				return err
+			}
+		}
+		notifyBoard(board + window.Name)
+	}
+	return nil
+}
+
+// rollWindow sum buckets of window into window leaderboard when it was summed in older bucket, report whether it was summed
+func rollWindow(ctx context.Context, board string, window config.WindowConfig, now time.Time) (bool, error) {
+	rankingName := board + window.Name
+	index := bucketIndex(now, window.Bucket)
+	built, err := store.GetScore(ctx, windowsIndex, rankingName)
+	if err == nil && int64(built) == index {
+		return false, nil
+	}
+	if err != nil && err != storage.ErrMemberNotFound {
+		return false, err
+	}
+
+	sources := make([]string, 0, window.Buckets)
+	for i := index - int64(window.Buckets) + 1; i <= index; i++ {
+		sources = append(sources, bucketName(board, window.Bucket, i))
+	}
+	if err := store.UnionStore(ctx, rankingName, sources, window.Name); err != nil {
+		return false, err
+	}
+	if err := store.SetScore(ctx, windowsIndex, float64(index), rankingName); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// expireBuckets clear buckets of length older than longest window of that length
+func expireBuckets(ctx context.Context, bucket time.Duration, index int64) error {
+	keep := int64(0)
+	for _, window := range config.Current().Leaderboard.Windows {
+		if window.Bucket == bucket && int64(window.Buckets) > keep {
+			keep = int64(window.Buckets)
+		}
+	}
+	marker := "expired:" + bucketLength(bucket)
+	expired, err := store.GetScore(ctx, windowsIndex, marker)
+	if err == storage.ErrMemberNotFound {
+		expired = float64(index - 2*keep)
+	} else if err != nil {
+		return err
+	}
+
+	last := int64(expired)
+	if index-keep <= last {
+		return nil
+	}
+	// bucket is written only while it is current and that write expire up to it minus keep, so buckets after last+keep are empty
+	for i := last + 1; i <= index-keep && i <= last+keep; i++ {
+		if _, err := store.ClearAll(ctx, bucketsListKey(bucket, i)); err != nil {
+			return err
+		}
+	}
+	return store.SetScore(ctx, windowsIndex, float64(index-keep), marker)
+}
+
+// boardName get ranking name of leaderboard slice in period, window leaderboard is rolled to current bucket first
+func boardName(ctx context.Context, leaderboardID string, slice string, period string) (string, error) {
+	if window, ok := config.Current().Window(period); ok {
+		if _, err := rollWindow(ctx, leaderboardID+slice, window, clock()); err != nil {
+			return "", err
+		}
+	}
+	return leaderboardID + slice + period, nil
+}
@@ -0,0 +1,118 @@
+package ranking
+
+import (
+	"context"
+	"net/http"
+	"rangkingserver/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func enableWindows(c *config.Config) {
+	c.Leaderboard.Windows = []config.WindowConfig{
+		{Name: "3h", Bucket: time.Hour, Buckets: 3},
+		{Name: "2h", Bucket: time.Hour, Buckets: 2},
+	}
+}
+
+// setClock make clock return time set by returned func until test end
+func setClock(t *testing.T, start time.Time) func(now time.Time) {
+	t.Helper()
+	now := start
+	clock = func() time.Time { return now }
+	t.Cleanup(func() { clock = time.Now })
+	return func(at time.Time) { now = at }
+}
+
+func windowEntries(t *testing.T, period string) []Entry {
+	t.Helper()
+	var list EntryList
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/entries?period="+period, nil, &list); code != http.StatusOK {
+		t.Fatalf("get entries of %s: status %d", period, code)
+	}
+	return list.Entries
+}
+
+func TestWindowRoll(t *testing.T) {
+	// bucket 1000 start at base
+	base := time.Unix(1000*3600, 0)
+	setNow := setClock(t, base.Add(59*time.Minute))
+	startTest(t, enableWindows)
+
+	submit(t, "1", "a", 10)
+	setNow(base.Add(time.Hour))
+	submit(t, "1", "a", 4)
+	submit(t, "1", "b", 5)
+	if got, want := windowEntries(t, "3h"), []Entry{{UID: "a", Rank: 1, Score: 14}, {UID: "b", Rank: 2, Score: 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("3h in bucket 1001 = %+v, want %+v", got, want)
+	}
+
+	// last second of bucket 1002 still cover bucket 1000
+	setNow(base.Add(3*time.Hour - time.Second))
+	if got, want := windowEntries(t, "3h"), []Entry{{UID: "a", Rank: 1, Score: 14}, {UID: "b", Rank: 2, Score: 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("3h at end of bucket 1002 = %+v, want %+v", got, want)
+	}
+	if got, want := windowEntries(t, "2h"), []Entry{{UID: "b", Rank: 1, Score: 5}, {UID: "a", Rank: 2, Score: 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("2h at end of bucket 1002 = %+v, want %+v", got, want)
+	}
+	// read in bucket 1003 roll window without score
+	setNow(base.Add(3 * time.Hour))
+	if got, want := windowEntries(t, "3h"), []Entry{{UID: "b", Rank: 1, Score: 5}, {UID: "a", Rank: 2, Score: 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("3h in bucket 1003 = %+v, want %+v", got, want)
+	}
+	var entry Entry
+	if code := call(t, LeaderboardsV1, http.MethodGet, V1Prefix+"/1/entries/a?period=2h", nil, &entry); code != http.StatusNotFound {
+		t.Errorf("entry of a in 2h of bucket 1003 = %+v status %d, want 404", entry, code)
+	}
+	if got := windowEntries(t, config.Current().Leaderboard.EventRankingKey); len(got) != 2 || got[0].Score != 14 {
+		t.Errorf("event ranking = %+v, want a 14 and b 5, windows do not change it", got)
+	}
+}
+
+func TestWindowBucketExpiry(t *testing.T) {
+	base := time.Unix(1000*3600, 0)
+	setNow := setClock(t, base)
+	memory := startTest(t, enableWindows)
+	ctx := context.Background()
+	marker := "expired:" + bucketLength(time.Hour)
+
+	submit(t, "1", "a", 10)
+	// windows of same bucket length share bucket, it is written once
+	if score, err := memory.GetScore(ctx, bucketName("1", time.Hour, 1000), "a"); err != nil || score != 10 {
+		t.Errorf("bucket 1000 of a = %v, %v, want 10", score, err)
+	}
+	// first score mark buckets older than longest window as expired
+	if expired, err := memory.GetScore(ctx, windowsIndex, marker); err != nil || expired != 997 {
+		t.Errorf("expired marker = %v, %v, want 997", expired, err)
+	}
+
+	setNow(base.Add(time.Hour))
+	submit(t, "1", "b", 5)
+	setNow(base.Add(3 * time.Hour))
+	submit(t, "1", "c", 1)
+	if names, err := memory.ListRankings(ctx, bucketsListKey(time.Hour, 1000)); err != nil || len(names) != 0 {
+		t.Errorf("buckets of index 1000 = %v, %v, want expired", names, err)
+	}
+	if names, err := memory.ListRankings(ctx, bucketsListKey(time.Hour, 1001)); err != nil || !reflect.DeepEqual(names, []string{bucketName("1", time.Hour, 1001)}) {
+		t.Errorf("buckets of index 1001 = %v, %v, want kept", names, err)
+	}
+	if expired, err := memory.GetScore(ctx, windowsIndex, marker); err != nil || expired != 1000 {
+		t.Errorf("expired marker = %v, %v, want 1000", expired, err)
+	}
+
+	// long gap expire only buckets that can be written, marker jump to newest expired index
+	setNow(base.Add(100 * time.Hour))
+	submit(t, "1", "d", 2)
+	for _, index := range []int64{1001, 1003} {
+		if names, err := memory.ListRankings(ctx, bucketsListKey(time.Hour, index)); err != nil || len(names) != 0 {
+			t.Errorf("buckets of index %d = %v, %v, want expired", index, names, err)
+		}
+	}
+	if expired, err := memory.GetScore(ctx, windowsIndex, marker); err != nil || expired != 1097 {
+		t.Errorf("expired marker = %v, %v, want 1097", expired, err)
+	}
+	if got, want := windowEntries(t, "3h"), []Entry{{UID: "d", Rank: 1, Score: 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("3h after gap = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,4 @@
+// Package rankingpb is gRPC Ranking service of ranking server generated from ranking.proto, server and client share it.
+package rankingpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ranking.proto
@@ -0,0 +1,359 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: ranking.proto
+
+package rankingpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// RankingClient is the client API for Ranking service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RankingClient interface {
+	// SubmitScore add amount to score of uid and return its entry
+	SubmitScore(ctx context.Context, in *SubmitScoreRequest, opts ...grpc.CallOption) (*Entry, error)
+	// SubmitScores add every score of stream, invalid scores are returned in rejected
+	SubmitScores(ctx context.Context, opts ...grpc.CallOption) (Ranking_SubmitScoresClient, error)
+	// GetTop get entries with highest score
+	GetTop(ctx context.Context, in *GetTopRequest, opts ...grpc.CallOption) (*EntryList, error)
+	// GetAroundMe get entries above and below uid
+	GetAroundMe(ctx context.Context, in *GetAroundMeRequest, opts ...grpc.CallOption) (*EntryList, error)
+	// GetMyRank get rank and score of uid
+	GetMyRank(ctx context.Context, in *GetMyRankRequest, opts ...grpc.CallOption) (*Entry, error)
+	// Subscribe send top entries now and every time leaderboard changes
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Ranking_SubscribeClient, error)
+}
+
+type rankingClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRankingClient(cc grpc.ClientConnInterface) RankingClient {
+	return &rankingClient{cc}
+}
+
+func (c *rankingClient) SubmitScore(ctx context.Context, in *SubmitScoreRequest, opts ...grpc.CallOption) (*Entry, error) {
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, "/ranking.v1.Ranking/SubmitScore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankingClient) SubmitScores(ctx context.Context, opts ...grpc.CallOption) (Ranking_SubmitScoresClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ranking_ServiceDesc.Streams[0], "/ranking.v1.Ranking/SubmitScores", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rankingSubmitScoresClient{stream}
+	return x, nil
+}
+
+type Ranking_SubmitScoresClient interface {
+	Send(*SubmitScoreRequest) error
+	CloseAndRecv() (*SubmitScoresResponse, error)
+	grpc.ClientStream
+}
+
+type rankingSubmitScoresClient struct {
+	grpc.ClientStream
+}
+
+func (x *rankingSubmitScoresClient) Send(m *SubmitScoreRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *rankingSubmitScoresClient) CloseAndRecv() (*SubmitScoresResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SubmitScoresResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *rankingClient) GetTop(ctx context.Context, in *GetTopRequest, opts ...grpc.CallOption) (*EntryList, error) {
+	out := new(EntryList)
+	err := c.cc.Invoke(ctx, "/ranking.v1.Ranking/GetTop", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankingClient) GetAroundMe(ctx context.Context, in *GetAroundMeRequest, opts ...grpc.CallOption) (*EntryList, error) {
+	out := new(EntryList)
+	err := c.cc.Invoke(ctx, "/ranking.v1.Ranking/GetAroundMe", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankingClient) GetMyRank(ctx context.Context, in *GetMyRankRequest, opts ...grpc.CallOption) (*Entry, error) {
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, "/ranking.v1.Ranking/GetMyRank", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankingClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Ranking_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ranking_ServiceDesc.Streams[1], "/ranking.v1.Ranking/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rankingSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Ranking_SubscribeClient interface {
+	Recv() (*EntryList, error)
+	grpc.ClientStream
+}
+
+type rankingSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *rankingSubscribeClient) Recv() (*EntryList, error) {
+	m := new(EntryList)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RankingServer is the server API for Ranking service.
+// All implementations must embed UnimplementedRankingServer
+// for forward compatibility
+type RankingServer interface {
+	// SubmitScore add amount to score of uid and return its entry
+	SubmitScore(context.Context, *SubmitScoreRequest) (*Entry, error)
+	// SubmitScores add every score of stream, invalid scores are returned in rejected
+	SubmitScores(Ranking_SubmitScoresServer) error
+	// GetTop get entries with highest score
+	GetTop(context.Context, *GetTopRequest) (*EntryList, error)
+	// GetAroundMe get entries above and below uid
+	GetAroundMe(context.Context, *GetAroundMeRequest) (*EntryList, error)
+	// GetMyRank get rank and score of uid
+	GetMyRank(context.Context, *GetMyRankRequest) (*Entry, error)
+	// Subscribe send top entries now and every time leaderboard changes
+	Subscribe(*SubscribeRequest, Ranking_SubscribeServer) error
+	mustEmbedUnimplementedRankingServer()
+}
+
+// UnimplementedRankingServer must be embedded to have forward compatible implementations.
+type UnimplementedRankingServer struct {
+}
+
+func (UnimplementedRankingServer) SubmitScore(context.Context, *SubmitScoreRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitScore not implemented")
+}
+func (UnimplementedRankingServer) SubmitScores(Ranking_SubmitScoresServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubmitScores not implemented")
+}
+func (UnimplementedRankingServer) GetTop(context.Context, *GetTopRequest) (*EntryList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTop not implemented")
+}
+func (UnimplementedRankingServer) GetAroundMe(context.Context, *GetAroundMeRequest) (*EntryList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAroundMe not implemented")
+}
+func (UnimplementedRankingServer) GetMyRank(context.Context, *GetMyRankRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMyRank not implemented")
+}
+func (UnimplementedRankingServer) Subscribe(*SubscribeRequest, Ranking_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedRankingServer) mustEmbedUnimplementedRankingServer() {}
+
+// UnsafeRankingServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RankingServer will
+// result in compilation errors.
+type UnsafeRankingServer interface {
+	mustEmbedUnimplementedRankingServer()
+}
+
+func RegisterRankingServer(s grpc.ServiceRegistrar, srv RankingServer) {
+	s.RegisterService(&Ranking_ServiceDesc, srv)
+}
+
+func _Ranking_SubmitScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankingServer).SubmitScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ranking.v1.Ranking/SubmitScore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankingServer).SubmitScore(ctx, req.(*SubmitScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ranking_SubmitScores_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RankingServer).SubmitScores(&rankingSubmitScoresServer{stream})
+}
+
+type Ranking_SubmitScoresServer interface {
+	SendAndClose(*SubmitScoresResponse) error
+	Recv() (*SubmitScoreRequest, error)
+	grpc.ServerStream
+}
+
+type rankingSubmitScoresServer struct {
+	grpc.ServerStream
+}
+
+func (x *rankingSubmitScoresServer) SendAndClose(m *SubmitScoresResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *rankingSubmitScoresServer) Recv() (*SubmitScoreRequest, error) {
+	m := new(SubmitScoreRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Ranking_GetTop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankingServer).GetTop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ranking.v1.Ranking/GetTop",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankingServer).GetTop(ctx, req.(*GetTopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ranking_GetAroundMe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAroundMeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankingServer).GetAroundMe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ranking.v1.Ranking/GetAroundMe",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankingServer).GetAroundMe(ctx, req.(*GetAroundMeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ranking_GetMyRank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMyRankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankingServer).GetMyRank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ranking.v1.Ranking/GetMyRank",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankingServer).GetMyRank(ctx, req.(*GetMyRankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ranking_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RankingServer).Subscribe(m, &rankingSubscribeServer{stream})
+}
+
+type Ranking_SubscribeServer interface {
+	Send(*EntryList) error
+	grpc.ServerStream
+}
+
+type rankingSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *rankingSubscribeServer) Send(m *EntryList) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Ranking_ServiceDesc is the grpc.ServiceDesc for Ranking service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ranking_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ranking.v1.Ranking",
+	HandlerType: (*RankingServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitScore",
+			Handler:    _Ranking_SubmitScore_Handler,
+		},
+		{
+			MethodName: "GetTop",
+			Handler:    _Ranking_GetTop_Handler,
+		},
+		{
+			MethodName: "GetAroundMe",
+			Handler:    _Ranking_GetAroundMe_Handler,
+		},
+		{
+			MethodName: "GetMyRank",
+			Handler:    _Ranking_GetMyRank_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubmitScores",
+			Handler:       _Ranking_SubmitScores_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Ranking_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ranking.proto",
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"rangkingserver/config"
+	"rangkingserver/metrics"
+	"rangkingserver/tracing"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ClassifyRPC get uid and cost of gRPC request message, client and IP are filled by interceptor
+type ClassifyRPC func(ctx context.Context, req interface{}) Request
+
+// UnaryServerInterceptor check rate limit of unary call, limited call fail with ResourceExhausted and retry-after header
+func UnaryServerInterceptor(limiter Limiter, classify ClassifyRPC) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allowRPC(ctx, limiter, classify, req, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) }); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor check rate limit of every message received on stream, so each score of client stream and
+// request of server stream take tokens. Limited message fail stream with ResourceExhausted and retry-after header.
+func StreamServerInterceptor(limiter Limiter, classify ClassifyRPC) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &limitedStream{ServerStream: stream, limiter: limiter, classify: classify})
+	}
+}
+
+// limitedStream ServerStream that check rate limit after every received message
+type limitedStream struct {
+	grpc.ServerStream
+	limiter  Limiter
+	classify ClassifyRPC
+}
+
+func (ls *limitedStream) RecvMsg(m interface{}) error {
+	if err := ls.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return allowRPC(ls.Context(), ls.limiter, ls.classify, m, ls.SetHeader)
+}
+
+// allowRPC take tokens of request message, ResourceExhausted error when limited. Limiter errors let message through.
+func allowRPC(ctx context.Context, limiter Limiter, classify ClassifyRPC, req interface{}, setHeader func(metadata.MD) error) error {
+	cfg := config.Current().RateLimit
+	if !cfg.Enabled {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	request := classify(ctx, req)
+	if values := md.Get(cfg.ClientHeader); len(values) > 0 {
+		request.Client = values[0]
+	}
+	request.IP = peerIP(ctx, md, cfg.TrustForwardedFor)
+	decision, err := limiter.Allow(ctx, Buckets(cfg, request))
+	if err != nil {
+		tracing.Logger(ctx).Warn("rate limit check failed, allow call", zap.Error(err))
+		return nil
+	}
+	if !decision.Allowed {
+		metrics.CountRateLimited(decision.Rule)
+		retryAfter := strconv.FormatInt(retryAfterSeconds(decision.RetryAfter), 10)
+		setHeader(metadata.Pairs("retry-after", retryAfter))
+		return status.Errorf(codes.ResourceExhausted, "rate limit %s exceeded, retry after %ss", decision.Rule, retryAfter)
+	}
+	return nil
+}
+
+// peerIP get source IP of call, first x-forwarded-for address when trusted
+func peerIP(ctx context.Context, md metadata.MD, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if values := md.Get("x-forwarded-for"); len(values) > 0 {
+			return strings.TrimSpace(strings.Split(values[0], ",")[0])
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/metrics"
+	"rangkingserver/tracing"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Classify get uid and cost of http request, client and IP are filled by Middleware
+type Classify func(r *http.Request) Request
+
+// Middleware check rate limit of request before handler, limited request get Retry-After header
+// and onLimited write 429 response. Limiter errors let request through so outage of redis does not stop the service.
+func Middleware(limiter Limiter, classify Classify, onLimited http.HandlerFunc, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.Current().RateLimit
+		if !cfg.Enabled || r.Method == http.MethodOptions {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		req := classify(r)
+		req.Client = r.Header.Get(cfg.ClientHeader)
+		req.IP = clientIP(r, cfg.TrustForwardedFor)
+		decision, err := limiter.Allow(r.Context(), Buckets(cfg, req))
+		if err != nil {
+			tracing.Logger(r.Context()).Warn("rate limit check failed, allow request", zap.Error(err))
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if !decision.Allowed {
+			metrics.CountRateLimited(decision.Rule)
+			w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSeconds(decision.RetryAfter), 10))
+			onLimited(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// clientIP get source IP of request, first X-Forwarded-For address when trusted
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
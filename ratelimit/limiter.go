@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"rangkingserver/config"
+	"time"
+)
+
+// keyPrefix is prefix of bucket keys in redis
+const keyPrefix = "ratelimit:"
+
+// Bucket is one token bucket a request take a token from
+type Bucket struct {
+	// Rule is name of rule for metrics, ex. per_ip
+	Rule  string
+	Key   string
+	Rate  float64
+	Burst int
+}
+
+// Decision is result of Allow, RetryAfter and Rule are set when not allowed
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Rule       string
+}
+
+// Limiter take one token from every bucket only when every bucket has one
+type Limiter interface {
+	Allow(ctx context.Context, buckets []Bucket) (Decision, error)
+}
+
+// Request is what rate limit rules of one request are checked on
+type Request struct {
+	// Client identify API client, empty skip per client rule
+	Client string
+	IP     string
+	// UID is user of request, empty skip per uid rule
+	UID string
+	// Expensive request also take token of expensive rule
+	Expensive bool
+}
+
+// Buckets get buckets of req by rules of cfg, rules with rate 0 are skipped
+func Buckets(cfg config.RateLimitConfig, req Request) []Bucket {
+	var buckets []Bucket
+	add := func(rule string, key string, limit config.RateLimitRule) {
+		if key == "" || limit.Rate <= 0 {
+			return
+		}
+		buckets = append(buckets, Bucket{Rule: rule, Key: keyPrefix + key, Rate: limit.Rate, Burst: limit.Burst})
+	}
+	if req.Client != "" {
+		add("per_client", "client:"+req.Client, cfg.PerClient)
+	}
+	add("per_ip", "ip:"+req.IP, cfg.PerIP)
+	if req.UID != "" {
+		add("per_uid", "uid:"+req.UID, cfg.PerUID)
+	}
+	if req.Expensive {
+		if req.Client != "" {
+			add("expensive", "expensive:client:"+req.Client, cfg.Expensive)
+		} else {
+			add("expensive", "expensive:ip:"+req.IP, cfg.Expensive)
+		}
+	}
+	return buckets
+}
+
+// retryAfterSeconds round retry after up to whole seconds for Retry-After header, at least 1
+func retryAfterSeconds(d time.Duration) int64 {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var errUnexpectedResult = errors.New("unexpected rate limit script result")
+
+// sweepInterval is how often idle buckets are removed from MemoryLimiter
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	// idle is time bucket is full again and can be dropped
+	idle time.Time
+}
+
+// MemoryLimiter keep buckets in process memory, limits are per replica
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter create empty MemoryLimiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow take token from every bucket when all have one
+func (ml *MemoryLimiter) Allow(ctx context.Context, buckets []Bucket) (Decision, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := ml.now()
+	ml.sweep(now)
+
+	tokens := make([]float64, len(buckets))
+	var decision Decision
+	for i, bucket := range buckets {
+		t := float64(bucket.Burst)
+		if b, ok := ml.buckets[bucket.Key]; ok {
+			t = math.Min(float64(bucket.Burst), b.tokens+now.Sub(b.updated).Seconds()*bucket.Rate)
+		}
+		tokens[i] = t
+		if t < 1 {
+			wait := time.Duration(math.Ceil((1 - t) / bucket.Rate * float64(time.Second)))
+			if wait > decision.RetryAfter {
+				decision.RetryAfter = wait
+				decision.Rule = bucket.Rule
+			}
+		}
+	}
+	if decision.Rule != "" {
+		return decision, nil
+	}
+
+	for i, bucket := range buckets {
+		refill := time.Duration((float64(bucket.Burst) - tokens[i] + 1) / bucket.Rate * float64(time.Second))
+		ml.buckets[bucket.Key] = &memoryBucket{tokens: tokens[i] - 1, updated: now, idle: now.Add(refill)}
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// sweep drop buckets that are full again, same as expire of redis keys
+func (ml *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(ml.lastSweep) < sweepInterval {
+		return
+	}
+	ml.lastSweep = now
+	for key, bucket := range ml.buckets {
+		if now.After(bucket.idle) {
+			delete(ml.buckets, key)
+		}
+	}
+}
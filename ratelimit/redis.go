@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// takeScript check every bucket of KEYS then take one token from each only when all have one.
+// ARGV[1] is now in milliseconds, then rate and burst of every key. Return {0, 0} when allowed,
+// else {milliseconds until token, 1-based index of bucket}.
+var takeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local tokens = {}
+local wait = 0
+local denied = 0
+for i = 1, #KEYS do
+	local rate = tonumber(ARGV[i * 2])
+	local burst = tonumber(ARGV[i * 2 + 1])
+	local bucket = redis.call('HMGET', KEYS[i], 'tokens', 'ts')
+	local t = tonumber(bucket[1])
+	local ts = tonumber(bucket[2])
+	if t == nil or ts == nil then
+		t = burst
+		ts = now
+	end
+	t = math.min(burst, t + math.max(0, now - ts) * rate / 1000)
+	tokens[i] = t
+	if t < 1 then
+		local w = math.ceil((1 - t) * 1000 / rate)
+		if w > wait then
+			wait = w
+			denied = i
+		end
+	end
+end
+if denied > 0 then
+	return {wait, denied}
+end
+for i = 1, #KEYS do
+	local rate = tonumber(ARGV[i * 2])
+	local burst = tonumber(ARGV[i * 2 + 1])
+	redis.call('HMSET', KEYS[i], 'tokens', tostring(tokens[i] - 1), 'ts', ARGV[1])
+	redis.call('PEXPIRE', KEYS[i], math.ceil(burst * 1000 / rate) + 1000)
+end
+return {0, 0}
+`)
+
+// RedisLimiter keep buckets in redis hashes, replicas using same redis share buckets
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter create limiter on redis client
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+// Allow take token from every bucket in one script so check and take are atomic
+func (rl *RedisLimiter) Allow(ctx context.Context, buckets []Bucket) (Decision, error) {
+	if len(buckets) == 0 {
+		return Decision{Allowed: true}, nil
+	}
+	keys := make([]string, 0, len(buckets))
+	args := make([]interface{}, 0, 1+2*len(buckets))
+	args = append(args, time.Now().UnixNano()/int64(time.Millisecond))
+	for _, bucket := range buckets {
+		keys = append(keys, bucket.Key)
+		args = append(args, strconv.FormatFloat(bucket.Rate, 'f', -1, 64), bucket.Burst)
+	}
+
+	result, err := takeScript.Run(rl.client.WithContext(ctx), keys, args...).Result()
+	if err != nil {
+		return Decision{}, err
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return Decision{}, errUnexpectedResult
+	}
+	wait, _ := values[0].(int64)
+	denied, _ := values[1].(int64)
+	if denied == 0 {
+		return Decision{Allowed: true}, nil
+	}
+	return Decision{
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+		Rule:       buckets[denied-1].Rule,
+	}, nil
+}
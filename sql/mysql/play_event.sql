@@ -32,6 +32,7 @@ CREATE TABLE `play_event` (
   `event_type` int(11) NOT NULL,
   `uid` bigint(20) NOT NULL,
   `value` int(11) NOT NULL DEFAULT 0,
+  `dimensions` varchar(255) NOT NULL DEFAULT '',
   `timestamp` datetime NOT NULL DEFAULT current_timestamp()
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 --
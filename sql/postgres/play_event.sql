@@ -0,0 +1,25 @@
+--
+-- PostgreSQL schema, same columns as sql/mysql/play_event.sql
+--
+
+BEGIN;
+
+--
+-- Table structure for table "play_event"
+--
+
+CREATE TABLE IF NOT EXISTS "play_event" (
+  "id" serial PRIMARY KEY,
+  "event_type" integer NOT NULL,
+  "uid" bigint NOT NULL,
+  "value" integer NOT NULL DEFAULT 0,
+  "dimensions" varchar(255) NOT NULL DEFAULT '',
+  "timestamp" timestamp NOT NULL DEFAULT current_timestamp
+);
+
+--
+-- Indexes for table "play_event"
+--
+CREATE INDEX IF NOT EXISTS "play_event_timestamp" ON "play_event" ("timestamp");
+
+COMMIT;
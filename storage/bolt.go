@@ -0,0 +1,539 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+// Embedded bolt
+//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+
+// bolt file layout
+//
+//	rankings/<rankingName>/score  uid -> score
+//	rankings/<rankingName>/index  sortable score + uid -> nil, ordered same as redis sorted set
+//	lists/<listKey>               rankingName -> nil
+//	friends/<uid>                 friend uid -> nil
+//	teams                         uid -> team, members of team are indexed in memory on load
+var (
+	boltRankingsBucket = []byte("rankings")
+	boltListsBucket    = []byte("lists")
+	boltFriendsBucket  = []byte("friends")
+	boltTeamsBucket    = []byte("teams")
+	boltScoreBucket    = []byte("score")
+	boltIndexBucket    = []byte("index")
+)
+
+// BoltStore LeaderboardStore persist rankings in embedded bolt file.
+// All rankings are loaded into MemoryStore on open, read from memory and write through to disk.
+type BoltStore struct {
+	// mu serialize writes so disk and memory apply in same order
+	mu     sync.Mutex
+	db     *bolt.DB
+	memory *MemoryStore
+}
+
+// NewBoltStore open bolt file at path and load all rankings from the score index
+func NewBoltStore(path string) (*BoltStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	bs := &BoltStore{db: db, memory: NewMemoryStore()}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltRankingsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(boltListsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(boltFriendsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(boltTeamsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := bs.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return bs, nil
+}
+
+// load fill memory store from disk index
+func (bs *BoltStore) load() error {
+	var numMember int
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(boltRankingsBucket).ForEach(func(name, _ []byte) error {
+			index := tx.Bucket(boltRankingsBucket).Bucket(name).Bucket(boltIndexBucket)
+			ss := bs.memory.ranking(string(name))
+			return index.ForEach(func(key, _ []byte) error {
+				score, uid := decodeIndexKey(key)
+				ss.set(uid, score)
+				numMember++
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(boltListsBucket).ForEach(func(listKey, _ []byte) error {
+			list := make(map[string]struct{})
+			bs.memory.lists[string(listKey)] = list
+			return tx.Bucket(boltListsBucket).Bucket(listKey).ForEach(func(name, _ []byte) error {
+				list[string(name)] = struct{}{}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(boltFriendsBucket).ForEach(func(uid, _ []byte) error {
+			friends := make(map[string]struct{})
+			bs.memory.friends[string(uid)] = friends
+			return tx.Bucket(boltFriendsBucket).Bucket(uid).ForEach(func(friend, _ []byte) error {
+				friends[string(friend)] = struct{}{}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(boltTeamsBucket).ForEach(func(uid, team []byte) error {
+			bs.memory.setTeam(string(uid), string(team))
+			return nil
+		})
+	})
+	zap.L().Info("bolt store loaded", zap.Int("rankings", len(bs.memory.rankings)), zap.Int("members", numMember))
+	return err
+}
+
+// IncreaseScore increase uid score and keep ranking name in listKey
+func (bs *BoltStore) IncreaseScore(ctx context.Context, rankingName string, score float64, uid string, listKey string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	current, err := bs.memory.GetScore(ctx, rankingName, uid)
+	if err != nil && err != ErrMemberNotFound {
+		return err
+	}
+	err = bs.db.Update(func(tx *bolt.Tx) error {
+		if err := putBoltScore(tx, rankingName, uid, current+score); err != nil {
+			return err
+		}
+		list, err := tx.Bucket(boltListsBucket).CreateBucketIfNotExists([]byte(listKey))
+		if err != nil {
+			return err
+		}
+		return list.Put([]byte(rankingName), nil)
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.IncreaseScore(ctx, rankingName, score, uid, listKey)
+}
+
+// SetScore value by score
+func (bs *BoltStore) SetScore(ctx context.Context, rankingName string, score float64, uid string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		return putBoltScore(tx, rankingName, uid, score)
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.SetScore(ctx, rankingName, score, uid)
+}
+
+// GetScore get user score via rankingName
+func (bs *BoltStore) GetScore(ctx context.Context, rankingName string, uid string) (float64, error) {
+	return bs.memory.GetScore(ctx, rankingName, uid)
+}
+
+// GetRank get user rank via rankingName
+func (bs *BoltStore) GetRank(ctx context.Context, rankingName string, uid string) (int64, error) {
+	return bs.memory.GetRank(ctx, rankingName, uid)
+}
+
+// GetRange get data from minScore and can get data limit by count
+func (bs *BoltStore) GetRange(ctx context.Context, rankingName string, minScore float64, count int64) ([]Member, error) {
+	return bs.memory.GetRange(ctx, rankingName, minScore, count)
+}
+
+// GetRankRange get members from rank start to stop
+func (bs *BoltStore) GetRankRange(ctx context.Context, rankingName string, start int64, stop int64) ([]Member, error) {
+	return bs.memory.GetRankRange(ctx, rankingName, start, stop)
+}
+
+// GetScores get members of uids that have score
+func (bs *BoltStore) GetScores(ctx context.Context, rankingName string, uids []string) ([]Member, error) {
+	return bs.memory.GetScores(ctx, rankingName, uids)
+}
+
+// Count get number of members in ranking
+func (bs *BoltStore) Count(ctx context.Context, rankingName string) (int64, error) {
+	return bs.memory.Count(ctx, rankingName)
+}
+
+// RemoveMember remove uid from ranking
+func (bs *BoltStore) RemoveMember(ctx context.Context, rankingName string, uid string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		return deleteBoltScore(tx, rankingName, uid)
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.RemoveMember(ctx, rankingName, uid)
+}
+
+// Delete delete ranking via ranking name and remove it from every list
+func (bs *BoltStore) Delete(ctx context.Context, rankingName string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		if err := deleteBoltRanking(tx, rankingName); err != nil {
+			return err
+		}
+		return unlistBoltRanking(tx, rankingName)
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.Delete(ctx, rankingName)
+}
+
+// ClearAll clear all ranking keep in listKey
+func (bs *BoltStore) ClearAll(ctx context.Context, listKey string) (int64, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		lists := tx.Bucket(boltListsBucket)
+		list := lists.Bucket([]byte(listKey))
+		if list == nil {
+			return nil
+		}
+		if err := list.ForEach(func(name, _ []byte) error {
+			return deleteBoltRanking(tx, string(name))
+		}); err != nil {
+			return err
+		}
+		return lists.DeleteBucket([]byte(listKey))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return bs.memory.ClearAll(ctx, listKey)
+}
+
+// ListRankings get all ranking name by listKey
+func (bs *BoltStore) ListRankings(ctx context.Context, listKey string) ([]string, error) {
+	return bs.memory.ListRankings(ctx, listKey)
+}
+
+// UnionStore replace destination with sum of sources and keep destination in listKey
+func (bs *BoltStore) UnionStore(ctx context.Context, destination string, sources []string, listKey string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.memory.mu.RLock()
+	union := bs.memory.union(sources)
+	bs.memory.mu.RUnlock()
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		if err := deleteBoltRanking(tx, destination); err != nil {
+			return err
+		}
+		for uid, score := range union.scores {
+			if err := putBoltScore(tx, destination, uid, score); err != nil {
+				return err
+			}
+		}
+		list, err := tx.Bucket(boltListsBucket).CreateBucketIfNotExists([]byte(listKey))
+		if err != nil {
+			return err
+		}
+		return list.Put([]byte(destination), nil)
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.UnionStore(ctx, destination, sources, listKey)
+}
+
+// ReplaceRanking move rankingName to archiveName and fill rankingName with members in one bolt transaction
+func (bs *BoltStore) ReplaceRanking(ctx context.Context, rankingName string, archiveName string, archiveListKey string, members []Member) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.memory.mu.RLock()
+	var old []Member
+	if ss, ok := bs.memory.rankings[rankingName]; ok {
+		for uid, score := range ss.scores {
+			old = append(old, Member{UID: uid, Score: score})
+		}
+	}
+	bs.memory.mu.RUnlock()
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		if err := deleteBoltRanking(tx, archiveName); err != nil {
+			return err
+		}
+		for _, member := range old {
+			if err := putBoltScore(tx, archiveName, member.UID, member.Score); err != nil {
+				return err
+			}
+		}
+		if err := deleteBoltRanking(tx, rankingName); err != nil {
+			return err
+		}
+		for _, member := range members {
+			if err := putBoltScore(tx, rankingName, member.UID, member.Score); err != nil {
+				return err
+			}
+		}
+		list, err := tx.Bucket(boltListsBucket).CreateBucketIfNotExists([]byte(archiveListKey))
+		if err != nil {
+			return err
+		}
+		return list.Put([]byte(archiveName), nil)
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.ReplaceRanking(ctx, rankingName, archiveName, archiveListKey, members)
+}
+
+// AddFriends add friends to friend set of uid
+func (bs *BoltStore) AddFriends(ctx context.Context, uid string, friends []string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		set, err := tx.Bucket(boltFriendsBucket).CreateBucketIfNotExists([]byte(uid))
+		if err != nil {
+			return err
+		}
+		for _, friend := range friends {
+			if err := set.Put([]byte(friend), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.AddFriends(ctx, uid, friends)
+}
+
+// RemoveFriends remove friends from friend set of uid
+func (bs *BoltStore) RemoveFriends(ctx context.Context, uid string, friends []string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		set := tx.Bucket(boltFriendsBucket).Bucket([]byte(uid))
+		if set == nil {
+			return nil
+		}
+		for _, friend := range friends {
+			if err := set.Delete([]byte(friend)); err != nil {
+				return err
+			}
+		}
+		if key, _ := set.Cursor().First(); key == nil {
+			return tx.Bucket(boltFriendsBucket).DeleteBucket([]byte(uid))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.RemoveFriends(ctx, uid, friends)
+}
+
+// GetFriends get friend set of uid
+func (bs *BoltStore) GetFriends(ctx context.Context, uid string) ([]string, error) {
+	return bs.memory.GetFriends(ctx, uid)
+}
+
+// GetTeam get team of uid
+func (bs *BoltStore) GetTeam(ctx context.Context, uid string) (string, error) {
+	return bs.memory.GetTeam(ctx, uid)
+}
+
+// SetTeam move uid to team
+func (bs *BoltStore) SetTeam(ctx context.Context, uid string, team string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		if team == "" {
+			return tx.Bucket(boltTeamsBucket).Delete([]byte(uid))
+		}
+		return tx.Bucket(boltTeamsBucket).Put([]byte(uid), []byte(team))
+	})
+	if err != nil {
+		return err
+	}
+	return bs.memory.SetTeam(ctx, uid, team)
+}
+
+// GetTeamMembers get members of team
+func (bs *BoltStore) GetTeamMembers(ctx context.Context, team string) ([]string, error) {
+	return bs.memory.GetTeamMembers(ctx, team)
+}
+
+// Ping check bolt file can be read
+func (bs *BoltStore) Ping(ctx context.Context) error {
+	return bs.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Close close bolt file
+func (bs *BoltStore) Close() error {
+	return bs.db.Close()
+}
+
+// putBoltScore write uid score and move its index key
+func putBoltScore(tx *bolt.Tx, rankingName string, uid string, score float64) error {
+	ranking, err := tx.Bucket(boltRankingsBucket).CreateBucketIfNotExists([]byte(rankingName))
+	if err != nil {
+		return err
+	}
+	scores, err := ranking.CreateBucketIfNotExists(boltScoreBucket)
+	if err != nil {
+		return err
+	}
+	index, err := ranking.CreateBucketIfNotExists(boltIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	if old := scores.Get([]byte(uid)); old != nil {
+		if err := index.Delete(encodeIndexKey(decodeScore(old), uid)); err != nil {
+			return err
+		}
+	}
+	if err := scores.Put([]byte(uid), encodeScore(score)); err != nil {
+		return err
+	}
+	return index.Put(encodeIndexKey(score, uid), nil)
+}
+
+func deleteBoltScore(tx *bolt.Tx, rankingName string, uid string) error {
+	ranking := tx.Bucket(boltRankingsBucket).Bucket([]byte(rankingName))
+	if ranking == nil {
+		return nil
+	}
+	scores, index := ranking.Bucket(boltScoreBucket), ranking.Bucket(boltIndexBucket)
+	old := scores.Get([]byte(uid))
+	if old == nil {
+		return nil
+	}
+	if err := index.Delete(encodeIndexKey(decodeScore(old), uid)); err != nil {
+		return err
+	}
+	if err := scores.Delete([]byte(uid)); err != nil {
+		return err
+	}
+	if key, _ := scores.Cursor().First(); key != nil {
+		return nil
+	}
+	// ranking without members is deleted like memory ranking
+	if err := deleteBoltRanking(tx, rankingName); err != nil {
+		return err
+	}
+	return unlistBoltRanking(tx, rankingName)
+}
+
+// unlistBoltRanking remove ranking name from every list, list left empty is removed
+func unlistBoltRanking(tx *bolt.Tx, rankingName string) error {
+	lists := tx.Bucket(boltListsBucket)
+	var empty [][]byte
+	err := lists.ForEach(func(listKey, _ []byte) error {
+		list := lists.Bucket(listKey)
+		if err := list.Delete([]byte(rankingName)); err != nil {
+			return err
+		}
+		if key, _ := list.Cursor().First(); key == nil {
+			empty = append(empty, append([]byte(nil), listKey...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, listKey := range empty {
+		if err := lists.DeleteBucket(listKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteBoltRanking(tx *bolt.Tx, rankingName string) error {
+	err := tx.Bucket(boltRankingsBucket).DeleteBucket([]byte(rankingName))
+	if err == bolt.ErrBucketNotFound {
+		return nil
+	}
+	return err
+}
+
+func encodeScore(score float64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, math.Float64bits(score))
+	return buf
+}
+
+func decodeScore(buf []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(buf))
+}
+
+// encodeIndexKey make key that sort by score then uid in byte order
+func encodeIndexKey(score float64, uid string) []byte {
+	bits := math.Float64bits(score)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	key := make([]byte, 8+len(uid))
+	binary.BigEndian.PutUint64(key, bits)
+	copy(key[8:], uid)
+	return key
+}
+
+func decodeIndexKey(key []byte) (float64, string) {
+	bits := binary.BigEndian.Uint64(key)
+	if bits&(1<<63) != 0 {
+		bits &^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return math.Float64frombits(bits), string(key[8:])
+}
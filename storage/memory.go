@@ -0,0 +1,425 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+// In memory
+//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+
+// sortedSet uid score map with skiplist index, work like redis sorted set
+type sortedSet struct {
+	scores map[string]float64
+	list   *skiplist
+}
+
+func newSortedSet() *sortedSet {
+	return &sortedSet{
+		scores: make(map[string]float64),
+		list:   newSkiplist(),
+	}
+}
+
+func (ss *sortedSet) set(uid string, score float64) {
+	if old, ok := ss.scores[uid]; ok {
+		if old == score {
+			return
+		}
+		ss.list.delete(old, uid)
+	}
+	ss.scores[uid] = score
+	ss.list.insert(score, uid)
+}
+
+func (ss *sortedSet) remove(uid string) {
+	if old, ok := ss.scores[uid]; ok {
+		ss.list.delete(old, uid)
+		delete(ss.scores, uid)
+	}
+}
+
+// MemoryStore LeaderboardStore keep all rankings in process memory, use for test and small deployment
+type MemoryStore struct {
+	mu       sync.RWMutex
+	rankings map[string]*sortedSet
+	lists    map[string]map[string]struct{}
+	friends  map[string]map[string]struct{}
+	teams    map[string]string
+	members  map[string]map[string]struct{}
+}
+
+// NewMemoryStore create empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		rankings: make(map[string]*sortedSet),
+		lists:    make(map[string]map[string]struct{}),
+		friends:  make(map[string]map[string]struct{}),
+		teams:    make(map[string]string),
+		members:  make(map[string]map[string]struct{}),
+	}
+}
+
+// IncreaseScore increase uid score and keep ranking name in listKey
+func (ms *MemoryStore) IncreaseScore(ctx context.Context, rankingName string, score float64, uid string, listKey string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ss := ms.ranking(rankingName)
+	ss.set(uid, ss.scores[uid]+score)
+
+	list, ok := ms.lists[listKey]
+	if !ok {
+		list = make(map[string]struct{})
+		ms.lists[listKey] = list
+	}
+	list[rankingName] = struct{}{}
+	return nil
+}
+
+// SetScore value by score
+func (ms *MemoryStore) SetScore(ctx context.Context, rankingName string, score float64, uid string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.ranking(rankingName).set(uid, score)
+	return nil
+}
+
+// GetScore get user score via rankingName
+func (ms *MemoryStore) GetScore(ctx context.Context, rankingName string, uid string) (float64, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	ss, ok := ms.rankings[rankingName]
+	if !ok {
+		return 0, ErrMemberNotFound
+	}
+	score, ok := ss.scores[uid]
+	if !ok {
+		return 0, ErrMemberNotFound
+	}
+	return score, nil
+}
+
+// GetRank get user rank via rankingName
+func (ms *MemoryStore) GetRank(ctx context.Context, rankingName string, uid string) (int64, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	ss, ok := ms.rankings[rankingName]
+	if !ok {
+		return 0, ErrMemberNotFound
+	}
+	score, ok := ss.scores[uid]
+	if !ok {
+		return 0, ErrMemberNotFound
+	}
+	return ss.list.length - ss.list.rank(score, uid) + 1, nil
+}
+
+// GetRange get data from minScore and can get data limit by count
+func (ms *MemoryStore) GetRange(ctx context.Context, rankingName string, minScore float64, count int64) ([]Member, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var members []Member
+	ss, ok := ms.rankings[rankingName]
+	if !ok {
+		return members, nil
+	}
+	for x := ss.list.tail; x != nil && x.score >= minScore; x = x.backward {
+		if count > 0 && int64(len(members)) >= count {
+			break
+		}
+		members = append(members, Member{UID: x.uid, Score: x.score})
+	}
+	return members, nil
+}
+
+// GetRankRange get members from rank start to stop
+func (ms *MemoryStore) GetRankRange(ctx context.Context, rankingName string, start int64, stop int64) ([]Member, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var members []Member
+	ss, ok := ms.rankings[rankingName]
+	if !ok || start < 1 {
+		return members, nil
+	}
+	// rank of highest score first is length - ascending rank + 1
+	for x := ss.list.byRank(ss.list.length - start + 1); x != nil && start <= stop; x = x.backward {
+		members = append(members, Member{UID: x.uid, Score: x.score})
+		start++
+	}
+	return members, nil
+}
+
+// GetScores get members of uids that have score
+func (ms *MemoryStore) GetScores(ctx context.Context, rankingName string, uids []string) ([]Member, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var members []Member
+	ss, ok := ms.rankings[rankingName]
+	if !ok {
+		return members, nil
+	}
+	for _, uid := range uids {
+		if score, ok := ss.scores[uid]; ok {
+			members = append(members, Member{UID: uid, Score: score})
+		}
+	}
+	return members, nil
+}
+
+// Count get number of members in ranking
+func (ms *MemoryStore) Count(ctx context.Context, rankingName string) (int64, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	ss, ok := ms.rankings[rankingName]
+	if !ok {
+		return 0, nil
+	}
+	return ss.list.length, nil
+}
+
+// RemoveMember remove uid from ranking, empty ranking is deleted like redis sorted set
+func (ms *MemoryStore) RemoveMember(ctx context.Context, rankingName string, uid string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ss, ok := ms.rankings[rankingName]; ok {
+		ss.remove(uid)
+		if len(ss.scores) == 0 {
+			ms.delete(rankingName)
+		}
+	}
+	return nil
+}
+
+// Delete delete ranking via ranking name and remove it from every list
+func (ms *MemoryStore) Delete(ctx context.Context, rankingName string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.delete(rankingName)
+	return nil
+}
+
+// ClearAll clear all ranking keep in listKey
+func (ms *MemoryStore) ClearAll(ctx context.Context, listKey string) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	list, ok := ms.lists[listKey]
+	if !ok {
+		return 0, nil
+	}
+	for rankingName := range list {
+		delete(ms.rankings, rankingName)
+	}
+	delete(ms.lists, listKey)
+	return 1, nil
+}
+
+// ListRankings get all ranking name by listKey
+func (ms *MemoryStore) ListRankings(ctx context.Context, listKey string) ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	names := make([]string, 0, len(ms.lists[listKey]))
+	for rankingName := range ms.lists[listKey] {
+		// empty ranking does not exist in redis either
+		if ss, ok := ms.rankings[rankingName]; ok && len(ss.scores) > 0 {
+			names = append(names, rankingName)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// AddFriends add friends to friend set of uid
+func (ms *MemoryStore) AddFriends(ctx context.Context, uid string, friends []string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	set, ok := ms.friends[uid]
+	if !ok {
+		set = make(map[string]struct{})
+		ms.friends[uid] = set
+	}
+	for _, friend := range friends {
+		set[friend] = struct{}{}
+	}
+	return nil
+}
+
+// RemoveFriends remove friends from friend set of uid
+func (ms *MemoryStore) RemoveFriends(ctx context.Context, uid string, friends []string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	set := ms.friends[uid]
+	for _, friend := range friends {
+		delete(set, friend)
+	}
+	if len(set) == 0 {
+		delete(ms.friends, uid)
+	}
+	return nil
+}
+
+// GetFriends get friend set of uid
+func (ms *MemoryStore) GetFriends(ctx context.Context, uid string) ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	friends := make([]string, 0, len(ms.friends[uid]))
+	for friend := range ms.friends[uid] {
+		friends = append(friends, friend)
+	}
+	sort.Strings(friends)
+	return friends, nil
+}
+
+// GetTeam get team of uid
+func (ms *MemoryStore) GetTeam(ctx context.Context, uid string) (string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return ms.teams[uid], nil
+}
+
+// SetTeam move uid to team
+func (ms *MemoryStore) SetTeam(ctx context.Context, uid string, team string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.setTeam(uid, team)
+	return nil
+}
+
+// GetTeamMembers get members of team
+func (ms *MemoryStore) GetTeamMembers(ctx context.Context, team string) ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	members := make([]string, 0, len(ms.members[team]))
+	for uid := range ms.members[team] {
+		members = append(members, uid)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// Ping memory store is always reachable
+func (ms *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close nothing to release for memory store
+func (ms *MemoryStore) Close() error {
+	return nil
+}
+
+// setTeam move uid to team, caller must hold write lock
+func (ms *MemoryStore) setTeam(uid string, team string) {
+	if old, ok := ms.teams[uid]; ok {
+		delete(ms.members[old], uid)
+		if len(ms.members[old]) == 0 {
+			delete(ms.members, old)
+		}
+		delete(ms.teams, uid)
+	}
+	if team == "" {
+		return
+	}
+	ms.teams[uid] = team
+	members, ok := ms.members[team]
+	if !ok {
+		members = make(map[string]struct{})
+		ms.members[team] = members
+	}
+	members[uid] = struct{}{}
+}
+
+// UnionStore replace destination with sum of sources and keep destination in listKey
+func (ms *MemoryStore) UnionStore(ctx context.Context, destination string, sources []string, listKey string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.rankings[destination] = ms.union(sources)
+	list, ok := ms.lists[listKey]
+	if !ok {
+		list = make(map[string]struct{})
+		ms.lists[listKey] = list
+	}
+	list[destination] = struct{}{}
+	return nil
+}
+
+// ReplaceRanking move rankingName to archiveName and fill rankingName with members under one lock
+func (ms *MemoryStore) ReplaceRanking(ctx context.Context, rankingName string, archiveName string, archiveListKey string, members []Member) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	old, ok := ms.rankings[rankingName]
+	if !ok {
+		old = newSortedSet()
+	}
+	ms.rankings[archiveName] = old
+	replaced := newSortedSet()
+	for _, member := range members {
+		replaced.set(member.UID, member.Score)
+	}
+	ms.rankings[rankingName] = replaced
+	list, ok := ms.lists[archiveListKey]
+	if !ok {
+		list = make(map[string]struct{})
+		ms.lists[archiveListKey] = list
+	}
+	list[archiveName] = struct{}{}
+	return nil
+}
+
+// union get sorted set with sum of scores of sources, caller must hold lock
+func (ms *MemoryStore) union(sources []string) *sortedSet {
+	scores := make(map[string]float64)
+	for _, source := range sources {
+		if ss, ok := ms.rankings[source]; ok {
+			for uid, score := range ss.scores {
+				scores[uid] += score
+			}
+		}
+	}
+	result := newSortedSet()
+	for uid, score := range scores {
+		result.set(uid, score)
+	}
+	return result
+}
+
+// delete remove ranking and its name from every list, list left empty is removed like redis set, caller must hold write lock
+func (ms *MemoryStore) delete(rankingName string) {
+	delete(ms.rankings, rankingName)
+	for listKey, list := range ms.lists {
+		delete(list, rankingName)
+		if len(list) == 0 {
+			delete(ms.lists, listKey)
+		}
+	}
+}
+
+// ranking get or create sorted set, caller must hold write lock
+func (ms *MemoryStore) ranking(rankingName string) *sortedSet {
+	ss, ok := ms.rankings[rankingName]
+	if !ok {
+		ss = newSortedSet()
+		ms.rankings[rankingName] = ss
+	}
+	return ss
+}
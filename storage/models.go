@@ -1,10 +1,12 @@
 package storage
 
 import (
-	"fmt"
+	"context"
 	"rangkingserver/config"
+	"time"
 
 	"github.com/go-redis/redis"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
@@ -18,43 +20,120 @@ type UserData struct {
 	EventType       string `json:"even_type"`
 	Amount          string `json:"amount"`
 	RankingDuration string `json:"ranking_duration"`
+	// Dimensions are dimension values of play_event, ex. game_mode=1&region=eu
+	Dimensions string `json:"dimensions"`
 }
 
-// DataSource struct contain DB connection and RedisClient
+// DataSource struct contain DB connection and leaderboard store
 type DataSource struct {
-	DataSourceName string
-	RedisClient    *redis.Client
+	// DB is pool shared by every DB access, DB and Events are nil when config does not require DB
+	DB     *sqlx.DB
+	Events EventRepository
+	// RedisClient is nil when StorageBackend is not redis
+	RedisClient *redis.Client
+	Store       LeaderboardStore
+}
+
+// DBHealth is DB ping result and pool statistic
+type DBHealth struct {
+	Err             error
+	Latency         time.Duration
+	OpenConnections int
+	InUse           int
+	Idle            int
+	WaitCount       int64
+	WaitDuration    time.Duration
 }
 
 // Close close all connection
 func (ds *DataSource) Close() {
-	// ds.DB.Close()
-	ds.RedisClient.Close()
+	if ds.DB != nil {
+		ds.Events.Close()
+		ds.DB.Close()
+	}
+	ds.Store.Close()
+}
+
+// DBHealth ping DB pool and report pool statistic, DB must not be nil
+func (ds *DataSource) DBHealth() DBHealth {
+	start := time.Now()
+	err := ds.DB.Ping()
+	stats := ds.DB.Stats()
+	return DBHealth{
+		Err:             err,
+		Latency:         time.Since(start),
+		OpenConnections: stats.OpenConnections,
+		InUse:           stats.InUse,
+		Idle:            stats.Idle,
+		WaitCount:       stats.WaitCount,
+		WaitDuration:    stats.WaitDuration,
+	}
 }
 
-// NewDataSource for initial program
+// NewDataSource for initial program, DB pool is opened only when config require it
 func NewDataSource() *DataSource {
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
-		config.DBUser,
-		config.DBPassword,
-		config.DBHost,
-		config.DBPort,
-		config.DBName,
-	)
+	db, events := newDB()
 
-	zap.L().Info("use db: ", zap.String("data source", dataSourceName))
+	switch config.Current().Storage.Backend {
+	case "redis":
+		redisClient := newRedisClient()
+		if err := MigrateRedisKeys(context.Background(), redisClient); err != nil {
+			zap.L().Fatal("cannot migrate redis keys", zap.Error(err))
+		}
+		return &DataSource{
+			DB:          db,
+			Events:      events,
+			RedisClient: redisClient,
+			Store:       NewRedisStore(redisClient),
+		}
+	case "memory":
+		zap.L().Info("use in memory leaderboard store")
+		return &DataSource{
+			DB:     db,
+			Events: events,
+			Store:  NewMemoryStore(),
+		}
+	case "bolt":
+		boltStore, err := NewBoltStore(config.Current().Storage.BoltPath)
+		if err != nil {
+			zap.L().Fatal("cannot open bolt store", zap.String("path", config.Current().Storage.BoltPath), zap.Error(err))
+		}
+		return &DataSource{
+			DB:     db,
+			Events: events,
+			Store:  boltStore,
+		}
+	default:
+		zap.L().Fatal("unknown storage backend", zap.String("backend", config.Current().Storage.Backend))
+	}
+	return nil
+}
 
-	// db, err := sql.Open("mysql", dataSourceName)
-	// if err != nil {
-	// 	zap.L().Panic("cannot open connection", zap.String("source", dataSourceName), zap.Error(err))
-	// }
-	// if err = db.Ping(); err != nil {
-	// 	zap.L().Panic("cannot ping connection", zap.String("source", dataSourceName), zap.Error(err))
-	// }
+// newDB open and ping DB pool and prepare event repository, nil when rebuild and webhook queue do not use DB
+func newDB() (*sqlx.DB, EventRepository) {
+	if !config.Current().DBRequired() {
+		zap.L().Info("no db pool, rebuild from db is disabled")
+		return nil, nil
+	}
+	zap.L().Info("use db: ", zap.String("driver", config.Current().DB.Driver), zap.String("host", config.Current().DB.Host), zap.String("name", config.Current().DB.Name))
+	db, err := OpenDB(config.Current().DB.Driver)
+	if err != nil {
+		zap.L().Fatal("cannot open db", zap.Error(err))
+	}
+	if err := PingDB(db); err != nil {
+		zap.L().Fatal("cannot ping db", zap.Error(err))
+	}
+	events, err := NewEventRepository(db, config.Current().DB.Driver)
+	if err != nil {
+		zap.L().Fatal("cannot create event repository", zap.Error(err))
+	}
+	return db, events
+}
 
+func newRedisClient() *redis.Client {
 	redisClient := redis.NewClient(&redis.Options{
-		Addr:     config.RedisHost + ":" + config.RedisPort,
-		Password: config.RedisPassword,
+		Addr:     config.Current().Redis.Host + ":" + config.Current().Redis.Port,
+		Password: config.Current().Redis.Password,
 		DB:       0,
 	})
 
@@ -63,8 +142,5 @@ func NewDataSource() *DataSource {
 	if err != nil {
 		zap.L().Fatal("status: ", zap.Error(err))
 	}
-	return &DataSource{
-		DataSourceName: dataSourceName,
-		RedisClient:    redisClient,
-	}
+	return redisClient
 }
@@ -0,0 +1,368 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+// Integrate with Redis
+//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+
+// RedisStore LeaderboardStore backed by redis sorted sets
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore create LeaderboardStore from redis client
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// IncreaseScore ZIncrBy increase value in redis and keep ranking name in listKey set
+func (rs *RedisStore) IncreaseScore(ctx context.Context, rankingName string, score float64, uid string, listKey string) error {
+	if _, err := rs.with(ctx).ZIncrBy(rankingName, score, uid).Result(); err != nil {
+		return err
+	}
+	_, err := rs.with(ctx).SAdd(listKey, rankingName).Result()
+	return err
+}
+
+// SetScore value by score
+func (rs *RedisStore) SetScore(ctx context.Context, rankingName string, score float64, uid string) error {
+	_, err := rs.with(ctx).ZAdd(rankingName, redis.Z{
+		Score:  score,
+		Member: uid,
+	}).Result()
+
+	return err
+}
+
+// GetScore get user score via rankingName
+func (rs *RedisStore) GetScore(ctx context.Context, rankingName string, uid string) (float64, error) {
+	score, err := rs.with(ctx).ZScore(rankingName, uid).Result()
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	return score, err
+}
+
+// GetRank get user rank via rankingName
+func (rs *RedisStore) GetRank(ctx context.Context, rankingName string, uid string) (int64, error) {
+	val, err := rs.with(ctx).ZRevRank(rankingName, uid).Result()
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	return val + 1, err
+}
+
+// GetRange get data from minScore and can get data limit by count
+func (rs *RedisStore) GetRange(ctx context.Context, rankingName string, minScore float64, count int64) ([]Member, error) {
+	vals, err := rs.with(ctx).ZRevRangeByScoreWithScores(rankingName, redis.ZRangeBy{
+		Min:    formatScore(minScore),
+		Max:    "+inf",
+		Offset: 0,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]Member, 0, len(vals))
+	for _, val := range vals {
+		members = append(members, Member{UID: val.Member.(string), Score: val.Score})
+	}
+	return members, nil
+}
+
+// GetRankRange ZRevRange members from rank start to stop
+func (rs *RedisStore) GetRankRange(ctx context.Context, rankingName string, start int64, stop int64) ([]Member, error) {
+	if start < 1 || stop < start {
+		return nil, nil
+	}
+	vals, err := rs.with(ctx).ZRevRangeWithScores(rankingName, start-1, stop-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]Member, 0, len(vals))
+	for _, val := range vals {
+		members = append(members, Member{UID: val.Member.(string), Score: val.Score})
+	}
+	return members, nil
+}
+
+// GetScores ZScore every uid in one pipeline
+func (rs *RedisStore) GetScores(ctx context.Context, rankingName string, uids []string) ([]Member, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	pipe := rs.with(ctx).Pipeline()
+	defer pipe.Close()
+	cmds := make([]*redis.FloatCmd, 0, len(uids))
+	for _, uid := range uids {
+		cmds = append(cmds, pipe.ZScore(rankingName, uid))
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	members := make([]Member, 0, len(uids))
+	for index, cmd := range cmds {
+		score, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, Member{UID: uids[index], Score: score})
+	}
+	return members, nil
+}
+
+// Count ZCard number of members in ranking
+func (rs *RedisStore) Count(ctx context.Context, rankingName string) (int64, error) {
+	return rs.with(ctx).ZCard(rankingName).Result()
+}
+
+// RemoveMember ZRem uid from ranking
+func (rs *RedisStore) RemoveMember(ctx context.Context, rankingName string, uid string) error {
+	_, err := rs.with(ctx).ZRem(rankingName, uid).Result()
+	return err
+}
+
+// Delete delete value in redis via ranking name
+func (rs *RedisStore) Delete(ctx context.Context, rankingName string) error {
+	_, err := rs.with(ctx).Del(rankingName).Result()
+	return err
+}
+
+// ClearAll clear all ranking keep in listKey set
+func (rs *RedisStore) ClearAll(ctx context.Context, listKey string) (int64, error) {
+	listRanking, err := rs.with(ctx).SMembers(listKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, rankingName := range listRanking {
+		if _, err := rs.with(ctx).Del(rankingName).Result(); err != nil {
+			return 0, err
+		}
+	}
+	return rs.with(ctx).Del(listKey).Result()
+}
+
+// listRankingsScript get members of KEYS[1] and SRem those whose ranking no longer exist, in one step so ranking
+// registered again meanwhile is not dropped
+var listRankingsScript = redis.NewScript(`
+local names = redis.call('SMEMBERS', KEYS[1])
+local existing = {}
+for _, name in ipairs(names) do
+	if redis.call('EXISTS', name) == 1 then
+		table.insert(existing, name)
+	else
+		redis.call('SREM', KEYS[1], name)
+	end
+end
+return existing
+`)
+
+// ListRankings get all ranking name by listKey, names of deleted rankings are dropped from listKey.
+// Redis has no index of list keys of ranking, so Delete leave its name to be dropped here.
+func (rs *RedisStore) ListRankings(ctx context.Context, listKey string) ([]string, error) {
+	result, err := listRankingsScript.Run(rs.with(ctx), []string{listKey}).Result()
+	if err != nil {
+		return nil, err
+	}
+	values, _ := result.([]interface{})
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		if name, ok := value.(string); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// UnionStore ZUnionStore sources into destination and keep destination in listKey set, Del destination without sources
+func (rs *RedisStore) UnionStore(ctx context.Context, destination string, sources []string, listKey string) error {
+	_, err := rs.with(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		if len(sources) == 0 {
+			pipe.Del(destination)
+		} else {
+			pipe.ZUnionStore(destination, redis.ZStore{Aggregate: "SUM"}, sources...)
+		}
+		pipe.SAdd(listKey, destination)
+		return nil
+	})
+	return err
+}
+
+// ReplaceRanking copy rankingName to archiveName with ZUnionStore then Del and ZAdd members in one MULTI transaction
+func (rs *RedisStore) ReplaceRanking(ctx context.Context, rankingName string, archiveName string, archiveListKey string, members []Member) error {
+	_, err := rs.with(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(archiveName, redis.ZStore{}, rankingName)
+		pipe.Del(rankingName)
+		if len(members) > 0 {
+			zs := make([]redis.Z, 0, len(members))
+			for _, member := range members {
+				zs = append(zs, redis.Z{Score: member.Score, Member: member.UID})
+			}
+			pipe.ZAdd(rankingName, zs...)
+		}
+		pipe.SAdd(archiveListKey, archiveName)
+		return nil
+	})
+	return err
+}
+
+// AddFriends SAdd friends to friend set of uid
+func (rs *RedisStore) AddFriends(ctx context.Context, uid string, friends []string) error {
+	if len(friends) == 0 {
+		return nil
+	}
+	_, err := rs.with(ctx).SAdd(friendsKey(uid), toInterfaces(friends)...).Result()
+	return err
+}
+
+// RemoveFriends SRem friends from friend set of uid
+func (rs *RedisStore) RemoveFriends(ctx context.Context, uid string, friends []string) error {
+	if len(friends) == 0 {
+		return nil
+	}
+	_, err := rs.with(ctx).SRem(friendsKey(uid), toInterfaces(friends)...).Result()
+	return err
+}
+
+// GetFriends SMembers friend set of uid
+func (rs *RedisStore) GetFriends(ctx context.Context, uid string) ([]string, error) {
+	friends, err := rs.with(ctx).SMembers(friendsKey(uid)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(friends)
+	return friends, nil
+}
+
+// GetTeam HGet team of uid
+func (rs *RedisStore) GetTeam(ctx context.Context, uid string) (string, error) {
+	team, err := rs.with(ctx).HGet(redisTeamsKey, uid).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return team, err
+}
+
+// SetTeam move uid from members set of old team to members set of team in one transaction
+func (rs *RedisStore) SetTeam(ctx context.Context, uid string, team string) error {
+	old, err := rs.GetTeam(ctx, uid)
+	if err != nil || old == team {
+		return err
+	}
+	_, err = rs.with(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		if old != "" {
+			pipe.SRem(teamMembersKey(old), uid)
+		}
+		if team == "" {
+			pipe.HDel(redisTeamsKey, uid)
+			return nil
+		}
+		pipe.HSet(redisTeamsKey, uid, team)
+		pipe.SAdd(teamMembersKey(team), uid)
+		return nil
+	})
+	return err
+}
+
+// GetTeamMembers SMembers members set of team
+func (rs *RedisStore) GetTeamMembers(ctx context.Context, team string) ([]string, error) {
+	members, err := rs.with(ctx).SMembers(teamMembersKey(team)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// Ping ping redis
+func (rs *RedisStore) Ping(ctx context.Context) error {
+	return rs.with(ctx).Ping().Err()
+}
+
+// Close close redis connection
+func (rs *RedisStore) Close() error {
+	return rs.client.Close()
+}
+
+// with get client bound to ctx
+func (rs *RedisStore) with(ctx context.Context) *redis.Client {
+	return rs.client.WithContext(ctx)
+}
+
+// redisKeyPrefix is prefix of keys store keep beside rankings, so ranking name cannot collide with them
+const redisKeyPrefix = "store:"
+
+// redisTeamsKey is hash of uid -> team
+const redisTeamsKey = redisKeyPrefix + "teams"
+
+// teamMembersKey get key of members set of team
+func teamMembersKey(team string) string {
+	return redisKeyPrefix + "team_members:" + team
+}
+
+// friendsKey get key of friend set of uid
+func friendsKey(uid string) string {
+	return redisKeyPrefix + "friends:" + uid
+}
+
+// MigrateRedisKeys rename team and friend keys written before they had redisKeyPrefix,
+// key is only renamed when it has type store write there and new key does not exist yet
+func MigrateRedisKeys(ctx context.Context, client *redis.Client) error {
+	client = client.WithContext(ctx)
+	rename := func(old string, wantType string) error {
+		keyType, err := client.Type(old).Result()
+		if err != nil || keyType != wantType {
+			return err
+		}
+		return client.RenameNX(old, redisKeyPrefix+old).Err()
+	}
+	if err := rename("teams", "hash"); err != nil {
+		return err
+	}
+	for _, pattern := range []string{"team_members:*", "friends:*"} {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(cursor, pattern, 1000).Result()
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if err := rename(key, "set"); err != nil {
+					return err
+				}
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// toInterfaces convert values for variadic redis argument
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
+
+// formatScore format score for redis range argument
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
@@ -1,11 +1,14 @@
 package storage
 
 import (
-	"database/sql"
+	"context"
+	"fmt"
 	"rangkingserver/config"
+	"time"
 
-	"github.com/go-redis/redis"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -13,15 +16,114 @@ import (
 // Integrate with DB
 //----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
 
-// GetAllUserEventDataFromDB get daily data from game database `play_event` for store in redis
-func GetAllUserEventDataFromDB(ds *DataSource) ([]UserData, error) {
-	var userDataList []UserData
-	db, err := sql.Open("mysql", ds.DataSourceName)
+// EventRepository read user event from game database `play_event`
+type EventRepository interface {
+	// GetAllUserEventData get sum of value of play_event at or after since group by uid, event type and dimensions for store in leaderboard
+	GetAllUserEventData(ctx context.Context, since time.Time) ([]UserData, error)
+	// Close release prepared statements
+	Close() error
+}
+
+// sqlEventRepository EventRepository on shared DB pool, statements are prepared once for its driver
+type sqlEventRepository struct {
+	sumEventStmt *sqlx.Stmt
+}
+
+// NewEventRepository create EventRepository on db by driver name, mysql or postgres.
+// Without dimensions column of migration 0002 rebuild read every play_event without dimensions until migrate up is run.
+func NewEventRepository(db *sqlx.DB, driverName string) (EventRepository, error) {
+	var columnQuery, sumEventQuery, sumEventNoDimensionsQuery string
+	switch driverName {
+	case "mysql":
+		columnQuery = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'play_event' AND column_name = 'dimensions'"
+		sumEventQuery = "SELECT event_type, uid, dimensions, sum(value) FROM `play_event` WHERE `timestamp` >= ? GROUP by uid,event_type,dimensions"
+		sumEventNoDimensionsQuery = "SELECT event_type, uid, '', sum(value) FROM `play_event` WHERE `timestamp` >= ? GROUP by uid,event_type"
+	case "postgres":
+		columnQuery = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'play_event' AND column_name = 'dimensions'"
+		sumEventQuery = `SELECT event_type, uid, dimensions, SUM(value) FROM "play_event" WHERE "timestamp" >= $1 GROUP BY uid, event_type, dimensions`
+		sumEventNoDimensionsQuery = `SELECT event_type, uid, '', SUM(value) FROM "play_event" WHERE "timestamp" >= $1 GROUP BY uid, event_type`
+	default:
+		return nil, fmt.Errorf("unknown db driver %q", driverName)
+	}
+
+	var columns int
+	if err := db.Get(&columns, columnQuery); err != nil {
+		return nil, err
+	}
+	if columns == 0 {
+		zap.L().Warn("play_event has no dimensions column, rebuild ignore dimensions, run rangkingserver migrate up to add it")
+		sumEventQuery = sumEventNoDimensionsQuery
+	}
+	sumEventStmt, err := db.Preparex(sumEventQuery)
 	if err != nil {
-		zap.L().Panic("cannot open connection", zap.String("source", ds.DataSourceName), zap.Error(err))
+		return nil, err
 	}
-	defer db.Close()
-	rows, err := db.Query("SELECT event_type, uid, sum(value) FROM `play_event`  GROUP by uid,event_type")
+	return &sqlEventRepository{sumEventStmt: sumEventStmt}, nil
+}
+
+// OpenDB open database pool of driver name with data source and pool settings from config
+func OpenDB(driverName string) (*sqlx.DB, error) {
+	var dataSourceName string
+	switch driverName {
+	case "mysql":
+		dataSourceName = mysqlDataSourceName()
+	case "postgres":
+		dataSourceName = postgresDataSourceName()
+	default:
+		return nil, fmt.Errorf("unknown db driver %q", driverName)
+	}
+
+	db, err := sqlx.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(config.Current().DB.MaxOpenConns)
+	db.SetMaxIdleConns(config.Current().DB.MaxIdleConns)
+	db.SetConnMaxLifetime(config.Current().DB.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.Current().DB.ConnMaxIdleTime)
+	return db, nil
+}
+
+// PingDB ping db until success or retry config.Current().DB.PingRetry times
+func PingDB(db *sqlx.DB) error {
+	var err error
+	for attempt := 1; attempt <= config.Current().DB.PingRetry; attempt++ {
+		if err = db.Ping(); err == nil {
+			return nil
+		}
+		zap.L().Warn("cannot ping db", zap.Int("attempt", attempt), zap.Error(err))
+		if attempt < config.Current().DB.PingRetry {
+			time.Sleep(config.Current().DB.PingInterval)
+		}
+	}
+	return err
+}
+
+func mysqlDataSourceName() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+		config.Current().DB.User,
+		config.Current().DB.Password,
+		config.Current().DB.Host,
+		config.Current().DB.Port,
+		config.Current().DB.Name,
+	)
+}
+
+func postgresDataSourceName() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		config.Current().DB.Host,
+		config.Current().DB.Port,
+		config.Current().DB.User,
+		config.Current().DB.Password,
+		config.Current().DB.Name,
+		config.Current().DB.SSLMode,
+	)
+}
+
+// GetAllUserEventData get daily data from game database `play_event` for store in redis, since is compared in UTC
+func (repo *sqlEventRepository) GetAllUserEventData(ctx context.Context, since time.Time) ([]UserData, error) {
+	var userDataList []UserData
+	rows, err := repo.sumEventStmt.QueryContext(ctx, since.UTC())
 	if err != nil {
 		return userDataList, err
 	}
@@ -30,7 +132,7 @@ func GetAllUserEventDataFromDB(ds *DataSource) ([]UserData, error) {
 
 	for rows.Next() {
 		userData := UserData{}
-		err := rows.Scan(&userData.EventType, &userData.UID, &userData.Amount)
+		err := rows.Scan(&userData.EventType, &userData.UID, &userData.Dimensions, &userData.Amount)
 		if err != nil {
 			return userDataList, err
 		}
@@ -44,6 +146,16 @@ func GetAllUserEventDataFromDB(ds *DataSource) ([]UserData, error) {
 	return userDataList, nil
 }
 
+// Close close prepared statements
+func (repo *sqlEventRepository) Close() error {
+	return repo.sumEventStmt.Close()
+}
+
+// GetAllUserEventDataFromDB get daily data from game database `play_event` at or after since for store in redis
+func GetAllUserEventDataFromDB(ctx context.Context, ds *DataSource, since time.Time) ([]UserData, error) {
+	return ds.Events.GetAllUserEventData(ctx, since)
+}
+
 // GetAllUserStatisticFromDB get user statistic data from game database `user_dummy` for store in redis
 // func GetAllUserStatisticFromDB(ds *DataSource) ([]UserStatistic, error) {
 // 	var userDataList []UserStatistic
@@ -81,109 +193,3 @@ func GetAllUserEventDataFromDB(ds *DataSource) ([]UserData, error) {
 // func GetUserProfileFromDB(ds *DataSource) (map[string]string, error) {
 
 // }
-
-//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
-// Integrate with Redis
-//----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
-
-// rankingName = eventtype + GameMode + SubTitle
-
-// IncreaseScoreDataRedisByRankingData ZIncrBy increase value in redis and get ranking type
-func IncreaseScoreDataRedisByRankingData(ds *DataSource, rankingName string, score float64, uid string) error {
-	_, err := ds.RedisClient.ZIncrBy(rankingName+config.EventRankingKey, score, uid).Result()
-	_, err = ds.RedisClient.SAdd(config.EventRankingKey, rankingName+config.EventRankingKey).Result()
-	return err
-}
-
-// IncreaseScoreDataRedisByRankingKey increase value by ranking key
-func IncreaseScoreDataRedisByRankingKey(ds *DataSource, rankingName string, score float64, uid string, rankingKey string) error {
-	_, err := ds.RedisClient.ZIncrBy(rankingName+rankingKey, score, uid).Result()
-	_, err = ds.RedisClient.SAdd(rankingKey, rankingName+rankingKey).Result()
-	return err
-}
-
-// IncreaseScoreDataWorldRankingRedis ZIncrBy increase value in redis Hall of fame
-func IncreaseScoreDataWorldRankingRedis(ds *DataSource, rankingName string, score float64, uid string) error {
-	_, err := ds.RedisClient.ZIncrBy(rankingName, score, uid).Result()
-	_, err = ds.RedisClient.SAdd(config.WorldRankingKey, rankingName).Result()
-
-	return err
-}
-
-// SetScoreDataRedis value by score
-func SetScoreDataRedis(ds *DataSource, rankingName string, score float64, uid string) error {
-	_, err := ds.RedisClient.ZAdd(rankingName, redis.Z{
-		Score:  score,
-		Member: uid,
-	}).Result()
-
-	return err
-}
-
-// DeleteRedis  delete value in redis via ranking name
-func DeleteRedis(ds *DataSource, rankingName string, score float64, uid string) error {
-	_, err := ds.RedisClient.Del(rankingName).Result()
-	return err
-}
-
-// GetScoreRedis get user score via rankingName
-func GetScoreRedis(ds *DataSource, rankingName string, uid string) (float64, error) {
-	score, err := ds.RedisClient.ZScore(rankingName, uid).Result()
-	return score, err
-}
-
-// GetUserRank get user rank via rankingName
-func GetUserRank(ds *DataSource, rankingName string, uid string) (int64, error) {
-	val, err := ds.RedisClient.ZRevRank(rankingName, uid).Result()
-	return val + 1, err
-}
-
-// GetRedisAllRanking get all data and can get data limit by limit
-func GetRedisAllRanking(ds *DataSource, rankingName string, isGetNoLimit bool) ([]redis.Z, error) {
-
-	var vals []redis.Z
-	var err error
-	if isGetNoLimit {
-		vals, err = ds.RedisClient.ZRevRangeByScoreWithScores(rankingName, redis.ZRangeBy{
-			Min:    "0",
-			Max:    "+inf",
-			Offset: 0,
-		}).Result()
-	} else {
-		vals, err = ds.RedisClient.ZRevRangeByScoreWithScores(rankingName, redis.ZRangeBy{
-			Min:    "1",
-			Max:    "+inf",
-			Offset: 0,
-			Count:  config.NumLimitRankingData,
-		}).Result()
-	}
-
-	return vals, err
-
-}
-
-// ClearAllRankingByKey clear type daily ranking
-func ClearAllRankingByKey(ds *DataSource, key string) (int64, error) {
-	listKey, err := ds.RedisClient.SMembers(key).Result()
-	for _, key := range listKey {
-		ds.RedisClient.Del(key).Result()
-	}
-	result, err := ds.RedisClient.Del(key).Result()
-	return result, err
-}
-
-// ClearAllWorldRankingDataRedis clear all world ranking data store in redis
-func ClearAllWorldRankingDataRedis(ds *DataSource) (int64, error) {
-	listKey, err := ds.RedisClient.SMembers(config.WorldRankingKey).Result()
-	for _, key := range listKey {
-		ds.RedisClient.Del(key).Result()
-	}
-	result, err := ds.RedisClient.Del(config.WorldRankingKey).Result()
-	return result, err
-}
-
-// GetAllKeyRankingByDuraion get all key by member
-func GetAllKeyRankingByDuraion(ds *DataSource, durationKey string) ([]string, error) {
-	listKey, err := ds.RedisClient.SMembers(durationKey).Result()
-	return listKey, err
-}
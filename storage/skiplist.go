@@ -0,0 +1,172 @@
+package storage
+
+import "math/rand"
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+// skiplistLevel forward link of node in one level, span is number of nodes it jumps over
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int64
+}
+
+type skiplistNode struct {
+	uid      string
+	score    float64
+	backward *skiplistNode
+	levels   []skiplistLevel
+}
+
+// skiplist keep members sorted by score then uid ascending, same order as redis sorted set
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int64
+	level  int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{levels: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomSkiplistLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// less report whether (score, uid) is ordered before node
+func (n *skiplistNode) less(score float64, uid string) bool {
+	return n.score < score || (n.score == score && n.uid < uid)
+}
+
+// after report whether node is ordered after (score, uid)
+func (n *skiplistNode) after(score float64, uid string) bool {
+	return n.score > score || (n.score == score && n.uid > uid)
+}
+
+// insert add uid with score, caller make sure uid is not in list
+func (sl *skiplist) insert(score float64, uid string) {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int64
+
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		if i != sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, uid) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomSkiplistLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.header
+			update[i].levels[i].span = sl.length
+		}
+		sl.level = level
+	}
+
+	x = &skiplistNode{uid: uid, score: score, levels: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < sl.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != sl.header {
+		x.backward = update[0]
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+	sl.length++
+}
+
+// delete remove uid with score, return false when not found
+func (sl *skiplist) delete(score float64, uid string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, uid) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.levels[0].forward
+	if x == nil || x.score != score || x.uid != uid {
+		return false
+	}
+
+	for i := 0; i < sl.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+	for sl.level > 1 && sl.header.levels[sl.level-1].forward == nil {
+		sl.level--
+	}
+	sl.length--
+	return true
+}
+
+// rank get 1-based ascending rank of uid with score, 0 when not found
+func (sl *skiplist) rank(score float64, uid string) int64 {
+	var rank int64
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !x.levels[i].forward.after(score, uid) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if x != sl.header && x.uid == uid {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank get node of 1-based ascending rank, nil when out of range
+func (sl *skiplist) byRank(rank int64) *skiplistNode {
+	var traversed int64
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == rank && x != sl.header {
+			return x
+		}
+	}
+	return nil
+}
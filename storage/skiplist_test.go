@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// sortedMembers get members in ascending skiplist order, score then uid
+func sortedMembers(scores map[string]float64) []Member {
+	members := make([]Member, 0, len(scores))
+	for uid, score := range scores {
+		members = append(members, Member{UID: uid, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].UID < members[j].UID
+	})
+	return members
+}
+
+// checkSkiplist compare rank, byRank, tail and backward links of list with ascending members
+func checkSkiplist(t *testing.T, sl *skiplist, want []Member) {
+	t.Helper()
+	if sl.length != int64(len(want)) {
+		t.Fatalf("length = %d, want %d", sl.length, len(want))
+	}
+	for index, member := range want {
+		rank := int64(index + 1)
+		if got := sl.rank(member.Score, member.UID); got != rank {
+			t.Fatalf("rank of %s = %d, want %d", member.UID, got, rank)
+		}
+		node := sl.byRank(rank)
+		if node == nil || node.uid != member.UID || node.score != member.Score {
+			t.Fatalf("byRank %d = %+v, want %s", rank, node, member.UID)
+		}
+	}
+	if sl.byRank(0) != nil || sl.byRank(int64(len(want))+1) != nil {
+		t.Fatal("byRank out of range is not nil")
+	}
+	index := len(want) - 1
+	for x := sl.tail; x != nil; x = x.backward {
+		if index < 0 || x.uid != want[index].UID {
+			t.Fatalf("backward walk at %d got %s", index, x.uid)
+		}
+		index--
+	}
+	if index != -1 {
+		t.Fatalf("backward walk stopped with %d members left", index+1)
+	}
+}
+
+func TestSkiplistOrderTiesByUID(t *testing.T) {
+	sl := newSkiplist()
+	for _, member := range []Member{{"c", 2}, {"a", 2}, {"b", 1}, {"d", 3}} {
+		sl.insert(member.Score, member.UID)
+	}
+	checkSkiplist(t, sl, []Member{{"b", 1}, {"a", 2}, {"c", 2}, {"d", 3}})
+	if sl.rank(2, "missing") != 0 {
+		t.Error("rank of missing uid is not 0")
+	}
+}
+
+func TestSkiplistDelete(t *testing.T) {
+	sl := newSkiplist()
+	for _, member := range []Member{{"a", 1}, {"b", 2}, {"c", 3}} {
+		sl.insert(member.Score, member.UID)
+	}
+	if sl.delete(2, "a") {
+		t.Error("delete with wrong score succeeded")
+	}
+	if !sl.delete(3, "c") {
+		t.Fatal("delete of tail failed")
+	}
+	checkSkiplist(t, sl, []Member{{"a", 1}, {"b", 2}})
+	if !sl.delete(1, "a") || !sl.delete(2, "b") {
+		t.Fatal("delete failed")
+	}
+	checkSkiplist(t, sl, nil)
+	if sl.tail != nil || sl.level != 1 {
+		t.Errorf("empty list has tail %v and level %d", sl.tail, sl.level)
+	}
+}
+
+func TestSkiplistRandomUpdates(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	sl := newSkiplist()
+	scores := make(map[string]float64)
+	for step := 0; step < 5000; step++ {
+		uid := "uid" + strconv.Itoa(random.Intn(300))
+		if old, ok := scores[uid]; ok {
+			if !sl.delete(old, uid) {
+				t.Fatalf("step %d: delete of %s failed", step, uid)
+			}
+			delete(scores, uid)
+			if random.Intn(3) == 0 {
+				continue
+			}
+		}
+		// few distinct scores so ties are common
+		score := float64(random.Intn(50))
+		sl.insert(score, uid)
+		scores[uid] = score
+		if step%500 == 0 {
+			checkSkiplist(t, sl, sortedMembers(scores))
+		}
+	}
+	checkSkiplist(t, sl, sortedMembers(scores))
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMemberNotFound is returned when uid has no score in the ranking
+var ErrMemberNotFound = errors.New("member not found in ranking")
+
+// Member is one uid and its score inside a ranking
+type Member struct {
+	UID   string
+	Score float64
+}
+
+// LeaderboardStore is the set of ranking operations used by the ranking package.
+// Every ranking is a sorted set of uid by score, and rankings are grouped under a
+// list key (ex. config.Current().Leaderboard.EventRankingKey) so they can be listed and cleared together.
+// Friend sets and team membership of uid are kept apart from rankings and are not cleared with them.
+type LeaderboardStore interface {
+	// IncreaseScore add score to uid in rankingName and register rankingName under listKey
+	IncreaseScore(ctx context.Context, rankingName string, score float64, uid string, listKey string) error
+	// SetScore replace uid score in rankingName
+	SetScore(ctx context.Context, rankingName string, score float64, uid string) error
+	// GetScore get uid score in rankingName
+	GetScore(ctx context.Context, rankingName string, uid string) (float64, error)
+	// GetRank get 1-based rank of uid in rankingName, highest score first
+	GetRank(ctx context.Context, rankingName string, uid string) (int64, error)
+	// GetRange get members with score >= minScore, highest score first, count <= 0 is no limit
+	GetRange(ctx context.Context, rankingName string, minScore float64, count int64) ([]Member, error)
+	// GetRankRange get members from 1-based rank start to stop inclusive, highest score first
+	GetRankRange(ctx context.Context, rankingName string, start int64, stop int64) ([]Member, error)
+	// GetScores get members of uids that have score in rankingName, in order of uids
+	GetScores(ctx context.Context, rankingName string, uids []string) ([]Member, error)
+	// Count get number of members in rankingName
+	Count(ctx context.Context, rankingName string) (int64, error)
+	// RemoveMember remove uid from rankingName, nothing happen when uid has no score. Ranking without members is deleted.
+	RemoveMember(ctx context.Context, rankingName string, uid string) error
+	// Delete remove rankingName, it is no longer listed under any list key
+	Delete(ctx context.Context, rankingName string) error
+	// ClearAll remove every ranking registered under listKey and listKey itself
+	ClearAll(ctx context.Context, listKey string) (int64, error)
+	// ListRankings get ranking names registered under listKey that have members, sorted
+	ListRankings(ctx context.Context, listKey string) ([]string, error)
+	// UnionStore replace destination with sum of scores of uid in sources and register destination under listKey,
+	// missing sources are empty
+	UnionStore(ctx context.Context, destination string, sources []string, listKey string) error
+	// ReplaceRanking move rankingName to archiveName registered under archiveListKey and fill rankingName with members
+	// in one step, readers see old or new ranking and never empty one between
+	ReplaceRanking(ctx context.Context, rankingName string, archiveName string, archiveListKey string, members []Member) error
+	// AddFriends add friends to friend set of uid
+	AddFriends(ctx context.Context, uid string, friends []string) error
+	// RemoveFriends remove friends from friend set of uid
+	RemoveFriends(ctx context.Context, uid string, friends []string) error
+	// GetFriends get friend set of uid sorted by uid
+	GetFriends(ctx context.Context, uid string) ([]string, error)
+	// GetTeam get team of uid, "" when uid has no team
+	GetTeam(ctx context.Context, uid string) (string, error)
+	// SetTeam move uid to team, "" remove uid from its team
+	SetTeam(ctx context.Context, uid string, team string) error
+	// GetTeamMembers get members of team sorted by uid
+	GetTeamMembers(ctx context.Context, team string) ([]string, error)
+	// Ping check store is reachable
+	Ping(ctx context.Context) error
+	// Close release store resources
+	Close() error
+}
@@ -0,0 +1,312 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// storeFactory open empty store for test, names and uids are prefixed with prefix so tests can share redis
+type storeFactory func(t *testing.T) (store LeaderboardStore, prefix string)
+
+func TestMemoryStore(t *testing.T) {
+	testLeaderboardStore(t, func(t *testing.T) (LeaderboardStore, string) {
+		return NewMemoryStore(), ""
+	})
+}
+
+func TestBoltStore(t *testing.T) {
+	testLeaderboardStore(t, func(t *testing.T) (LeaderboardStore, string) {
+		store, err := NewBoltStore(filepath.Join(t.TempDir(), "ranking.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store, ""
+	})
+}
+
+// TestRedisStore run against REDIS_TEST_ADDR (ex. localhost:6379), skipped when it is not set or not reachable
+func TestRedisStore(t *testing.T) {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		t.Skipf("redis %s is not reachable: %v", addr, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	testLeaderboardStore(t, func(t *testing.T) (LeaderboardStore, string) {
+		prefix := "storetest:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"
+		t.Cleanup(func() {
+			keys, _ := client.Keys("*" + prefix + "*").Result()
+			if len(keys) > 0 {
+				client.Del(keys...)
+			}
+			uids, _ := client.HKeys(redisTeamsKey).Result()
+			for _, uid := range uids {
+				if len(uid) > len(prefix) && uid[:len(prefix)] == prefix {
+					client.HDel(redisTeamsKey, uid)
+				}
+			}
+		})
+		return NewRedisStore(client), prefix
+	})
+}
+
+// testLeaderboardStore check every backend behave like redis sorted sets
+func testLeaderboardStore(t *testing.T, open storeFactory) {
+	ctx := context.Background()
+
+	t.Run("scores and ranks", func(t *testing.T) {
+		store, p := open(t)
+		board, list := p+"board", p+"list"
+		mustIncrease(t, store, board, 10, p+"a", list)
+		mustIncrease(t, store, board, 30, p+"b", list)
+		mustIncrease(t, store, board, 20, p+"c", list)
+		mustIncrease(t, store, board, 5, p+"a", list)
+
+		if score, err := store.GetScore(ctx, board, p+"a"); err != nil || score != 15 {
+			t.Errorf("GetScore a = %v, %v, want 15", score, err)
+		}
+		if err := store.SetScore(ctx, board, 25, p+"a"); err != nil {
+			t.Fatal(err)
+		}
+		for uid, want := range map[string]int64{"b": 1, "a": 2, "c": 3} {
+			if rank, err := store.GetRank(ctx, board, p+uid); err != nil || rank != want {
+				t.Errorf("GetRank %s = %d, %v, want %d", uid, rank, err, want)
+			}
+		}
+		if _, err := store.GetScore(ctx, board, p+"missing"); err != ErrMemberNotFound {
+			t.Errorf("GetScore of missing uid: got %v, want ErrMemberNotFound", err)
+		}
+		if _, err := store.GetRank(ctx, p+"missing", p+"a"); err != ErrMemberNotFound {
+			t.Errorf("GetRank in missing ranking: got %v, want ErrMemberNotFound", err)
+		}
+		if count, err := store.Count(ctx, board); err != nil || count != 3 {
+			t.Errorf("Count = %d, %v, want 3", count, err)
+		}
+		if count, err := store.Count(ctx, p+"missing"); err != nil || count != 0 {
+			t.Errorf("Count of missing ranking = %d, %v, want 0", count, err)
+		}
+	})
+
+	t.Run("ranges", func(t *testing.T) {
+		store, p := open(t)
+		board := p + "board"
+		// tie of b and d is ordered by uid descending like ZREVRANGE
+		for uid, score := range map[string]float64{"a": 10, "b": 30, "c": 20, "d": 30, "e": -5} {
+			mustIncrease(t, store, board, score, p+uid, p+"list")
+		}
+		want := []Member{{p + "d", 30}, {p + "b", 30}, {p + "c", 20}, {p + "a", 10}, {p + "e", -5}}
+
+		assertMembers(t, "GetRankRange 1..5", mustRankRange(t, store, board, 1, 5), want)
+		assertMembers(t, "GetRankRange 2..3", mustRankRange(t, store, board, 2, 3), want[1:3])
+		assertMembers(t, "GetRankRange 4..10", mustRankRange(t, store, board, 4, 10), want[3:])
+		assertMembers(t, "GetRankRange 0..2", mustRankRange(t, store, board, 0, 2), nil)
+		assertMembers(t, "GetRankRange 6..8", mustRankRange(t, store, board, 6, 8), nil)
+		assertMembers(t, "GetRankRange 3..2", mustRankRange(t, store, board, 3, 2), nil)
+
+		members, err := store.GetRange(ctx, board, 20, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertMembers(t, "GetRange min 20", members, want[:3])
+		if members, err = store.GetRange(ctx, board, 0, 2); err != nil {
+			t.Fatal(err)
+		}
+		assertMembers(t, "GetRange min 0 count 2", members, want[:2])
+
+		scores, err := store.GetScores(ctx, board, []string{p + "a", p + "missing", p + "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertMembers(t, "GetScores", scores, []Member{{p + "a", 10}, {p + "b", 30}})
+	})
+
+	t.Run("remove and delete", func(t *testing.T) {
+		store, p := open(t)
+		board, other, list, otherList := p+"board", p+"other", p+"list", p+"other-list"
+		mustIncrease(t, store, board, 1, p+"a", list)
+		mustIncrease(t, store, board, 2, p+"b", list)
+		mustIncrease(t, store, board, 2, p+"b", otherList)
+		mustIncrease(t, store, other, 3, p+"a", list)
+
+		if err := store.RemoveMember(ctx, board, p+"a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RemoveMember(ctx, board, p+"missing"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetScore(ctx, board, p+"a"); err != ErrMemberNotFound {
+			t.Errorf("GetScore of removed uid: got %v, want ErrMemberNotFound", err)
+		}
+		assertMembers(t, "after RemoveMember", mustRankRange(t, store, board, 1, 10), []Member{{p + "b", 4}})
+
+		if err := store.Delete(ctx, board); err != nil {
+			t.Fatal(err)
+		}
+		if count, _ := store.Count(ctx, board); count != 0 {
+			t.Errorf("Count of deleted ranking = %d, want 0", count)
+		}
+		assertNames(t, store, list, []string{other})
+		assertNames(t, store, otherList, []string{})
+
+		// ranking left without members is not listed
+		if err := store.RemoveMember(ctx, other, p+"a"); err != nil {
+			t.Fatal(err)
+		}
+		assertNames(t, store, list, []string{})
+	})
+
+	t.Run("clear all", func(t *testing.T) {
+		store, p := open(t)
+		list, kept := p+"list", p+"kept"
+		mustIncrease(t, store, p+"x", 1, p+"a", list)
+		mustIncrease(t, store, p+"y", 1, p+"a", list)
+		mustIncrease(t, store, p+"z", 1, p+"a", kept)
+		assertNames(t, store, list, []string{p + "x", p + "y"})
+
+		if _, err := store.ClearAll(ctx, list); err != nil {
+			t.Fatal(err)
+		}
+		assertNames(t, store, list, []string{})
+		for _, rankingName := range []string{p + "x", p + "y"} {
+			if count, _ := store.Count(ctx, rankingName); count != 0 {
+				t.Errorf("Count of cleared %s = %d, want 0", rankingName, count)
+			}
+		}
+		assertNames(t, store, kept, []string{p + "z"})
+	})
+
+	t.Run("union and replace", func(t *testing.T) {
+		store, p := open(t)
+		list := p + "list"
+		mustIncrease(t, store, p+"day1", 10, p+"a", list)
+		mustIncrease(t, store, p+"day1", 5, p+"b", list)
+		mustIncrease(t, store, p+"day2", 7, p+"a", list)
+
+		if err := store.UnionStore(ctx, p+"week", []string{p + "day1", p + "day2", p + "missing"}, p+"windows"); err != nil {
+			t.Fatal(err)
+		}
+		assertMembers(t, "UnionStore", mustRankRange(t, store, p+"week", 1, 10), []Member{{p + "a", 17}, {p + "b", 5}})
+		assertNames(t, store, p+"windows", []string{p + "week"})
+
+		if err := store.ReplaceRanking(ctx, p+"day1", p+"day1:archive", p+"archive", []Member{{p + "c", 1}}); err != nil {
+			t.Fatal(err)
+		}
+		assertMembers(t, "archived", mustRankRange(t, store, p+"day1:archive", 1, 10), []Member{{p + "a", 10}, {p + "b", 5}})
+		assertMembers(t, "replaced", mustRankRange(t, store, p+"day1", 1, 10), []Member{{p + "c", 1}})
+		assertNames(t, store, p+"archive", []string{p + "day1:archive"})
+	})
+
+	t.Run("friends and teams", func(t *testing.T) {
+		store, p := open(t)
+		if err := store.AddFriends(ctx, p+"a", []string{p + "c", p + "b", p + "d"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RemoveFriends(ctx, p+"a", []string{p + "d"}); err != nil {
+			t.Fatal(err)
+		}
+		if friends, err := store.GetFriends(ctx, p+"a"); err != nil || !reflect.DeepEqual(friends, []string{p + "b", p + "c"}) {
+			t.Errorf("GetFriends = %v, %v", friends, err)
+		}
+
+		for uid, team := range map[string]string{"a": "red", "b": "red", "c": "blue"} {
+			if err := store.SetTeam(ctx, p+uid, p+team); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := store.SetTeam(ctx, p+"b", p+"blue"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetTeam(ctx, p+"c", ""); err != nil {
+			t.Fatal(err)
+		}
+		if team, err := store.GetTeam(ctx, p+"b"); err != nil || team != p+"blue" {
+			t.Errorf("GetTeam b = %q, %v, want blue", team, err)
+		}
+		if team, err := store.GetTeam(ctx, p+"c"); err != nil || team != "" {
+			t.Errorf("GetTeam c = %q, %v, want no team", team, err)
+		}
+		for team, want := range map[string][]string{"red": {p + "a"}, "blue": {p + "b"}} {
+			if members, err := store.GetTeamMembers(ctx, p+team); err != nil || !reflect.DeepEqual(members, want) {
+				t.Errorf("GetTeamMembers %s = %v, %v, want %v", team, members, err, want)
+			}
+		}
+	})
+}
+
+func TestBoltStoreDeleteSurviveReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ranking.db")
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustIncrease(t, store, "board", 1, "a", "list")
+	mustIncrease(t, store, "other", 2, "a", "list")
+	mustIncrease(t, store, "gone", 3, "a", "list")
+	if err := store.Delete(ctx, "board"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveMember(ctx, "gone", "a"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if store, err = NewBoltStore(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	assertNames(t, store, "list", []string{"other"})
+	assertMembers(t, "reopened", mustRankRange(t, store, "other", 1, 10), []Member{{"a", 2}})
+}
+
+func mustIncrease(t *testing.T, store LeaderboardStore, rankingName string, score float64, uid string, listKey string) {
+	t.Helper()
+	if err := store.IncreaseScore(context.Background(), rankingName, score, uid, listKey); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustRankRange(t *testing.T, store LeaderboardStore, rankingName string, start int64, stop int64) []Member {
+	t.Helper()
+	members, err := store.GetRankRange(context.Background(), rankingName, start, stop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return members
+}
+
+func assertMembers(t *testing.T, name string, got []Member, want []Member) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func assertNames(t *testing.T, store LeaderboardStore, listKey string, want []string) {
+	t.Helper()
+	names, err := store.ListRankings(context.Background(), listKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ListRankings %s = %v, want %v", listKey, names, want)
+	}
+}
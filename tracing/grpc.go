@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataCarrier read and write W3C trace headers in gRPC metadata
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	values := metadata.MD(mc).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (mc metadataCarrier) Set(key string, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for key := range mc {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// UnaryServerInterceptor start server span of unary gRPC call, same as Middleware for http
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startRPC(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	endRPC(span, err)
+	return resp, err
+}
+
+// StreamServerInterceptor start server span that last whole gRPC stream
+func StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startRPC(stream.Context(), info.FullMethod)
+	err := handler(srv, &tracedStream{ServerStream: stream, ctx: ctx})
+	endRPC(span, err)
+	return err
+}
+
+func startRPC(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	ctx, span := Tracer().Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCMethodKey.String(method),
+		),
+	)
+	requestID := metadataCarrier(md).Get(requestIDHeader)
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID), span
+}
+
+func endRPC(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, code.String())
+	}
+	span.End()
+}
+
+// tracedStream is server stream with context of span
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ts *tracedStream) Context() context.Context {
+	return ts.ctx
+}
@@ -0,0 +1,217 @@
+package tracing
+
+import (
+	"context"
+	"rangkingserver/storage"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedStore LeaderboardStore that start span around every call of wrapped store
+type tracedStore struct {
+	store   storage.LeaderboardStore
+	backend string
+}
+
+// TraceStore wrap store to start span storage.<operation> for every call
+func TraceStore(store storage.LeaderboardStore, backend string) storage.LeaderboardStore {
+	return &tracedStore{store: store, backend: backend}
+}
+
+func (ts *tracedStore) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(ts.backend),
+			semconv.DBOperationKey.String(operation),
+		),
+	)
+}
+
+func (ts *tracedStore) IncreaseScore(ctx context.Context, rankingName string, score float64, uid string, listKey string) error {
+	ctx, span := ts.start(ctx, "IncreaseScore")
+	err := ts.store.IncreaseScore(ctx, rankingName, score, uid, listKey)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) SetScore(ctx context.Context, rankingName string, score float64, uid string) error {
+	ctx, span := ts.start(ctx, "SetScore")
+	err := ts.store.SetScore(ctx, rankingName, score, uid)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) GetScore(ctx context.Context, rankingName string, uid string) (float64, error) {
+	ctx, span := ts.start(ctx, "GetScore")
+	score, err := ts.store.GetScore(ctx, rankingName, uid)
+	EndSpan(span, ignoreNotFound(err))
+	return score, err
+}
+
+func (ts *tracedStore) GetRank(ctx context.Context, rankingName string, uid string) (int64, error) {
+	ctx, span := ts.start(ctx, "GetRank")
+	rank, err := ts.store.GetRank(ctx, rankingName, uid)
+	EndSpan(span, ignoreNotFound(err))
+	return rank, err
+}
+
+func (ts *tracedStore) GetRange(ctx context.Context, rankingName string, minScore float64, count int64) ([]storage.Member, error) {
+	ctx, span := ts.start(ctx, "GetRange")
+	members, err := ts.store.GetRange(ctx, rankingName, minScore, count)
+	EndSpan(span, err)
+	return members, err
+}
+
+func (ts *tracedStore) GetRankRange(ctx context.Context, rankingName string, start int64, stop int64) ([]storage.Member, error) {
+	ctx, span := ts.start(ctx, "GetRankRange")
+	members, err := ts.store.GetRankRange(ctx, rankingName, start, stop)
+	EndSpan(span, err)
+	return members, err
+}
+
+func (ts *tracedStore) GetScores(ctx context.Context, rankingName string, uids []string) ([]storage.Member, error) {
+	ctx, span := ts.start(ctx, "GetScores")
+	members, err := ts.store.GetScores(ctx, rankingName, uids)
+	EndSpan(span, err)
+	return members, err
+}
+
+func (ts *tracedStore) Count(ctx context.Context, rankingName string) (int64, error) {
+	ctx, span := ts.start(ctx, "Count")
+	count, err := ts.store.Count(ctx, rankingName)
+	EndSpan(span, err)
+	return count, err
+}
+
+func (ts *tracedStore) RemoveMember(ctx context.Context, rankingName string, uid string) error {
+	ctx, span := ts.start(ctx, "RemoveMember")
+	err := ts.store.RemoveMember(ctx, rankingName, uid)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) Delete(ctx context.Context, rankingName string) error {
+	ctx, span := ts.start(ctx, "Delete")
+	err := ts.store.Delete(ctx, rankingName)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) ClearAll(ctx context.Context, listKey string) (int64, error) {
+	ctx, span := ts.start(ctx, "ClearAll")
+	result, err := ts.store.ClearAll(ctx, listKey)
+	EndSpan(span, err)
+	return result, err
+}
+
+func (ts *tracedStore) ListRankings(ctx context.Context, listKey string) ([]string, error) {
+	ctx, span := ts.start(ctx, "ListRankings")
+	names, err := ts.store.ListRankings(ctx, listKey)
+	EndSpan(span, err)
+	return names, err
+}
+
+func (ts *tracedStore) UnionStore(ctx context.Context, destination string, sources []string, listKey string) error {
+	ctx, span := ts.start(ctx, "UnionStore")
+	err := ts.store.UnionStore(ctx, destination, sources, listKey)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) ReplaceRanking(ctx context.Context, rankingName string, archiveName string, archiveListKey string, members []storage.Member) error {
+	ctx, span := ts.start(ctx, "ReplaceRanking")
+	err := ts.store.ReplaceRanking(ctx, rankingName, archiveName, archiveListKey, members)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) AddFriends(ctx context.Context, uid string, friends []string) error {
+	ctx, span := ts.start(ctx, "AddFriends")
+	err := ts.store.AddFriends(ctx, uid, friends)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) RemoveFriends(ctx context.Context, uid string, friends []string) error {
+	ctx, span := ts.start(ctx, "RemoveFriends")
+	err := ts.store.RemoveFriends(ctx, uid, friends)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) GetFriends(ctx context.Context, uid string) ([]string, error) {
+	ctx, span := ts.start(ctx, "GetFriends")
+	friends, err := ts.store.GetFriends(ctx, uid)
+	EndSpan(span, err)
+	return friends, err
+}
+
+func (ts *tracedStore) GetTeam(ctx context.Context, uid string) (string, error) {
+	ctx, span := ts.start(ctx, "GetTeam")
+	team, err := ts.store.GetTeam(ctx, uid)
+	EndSpan(span, err)
+	return team, err
+}
+
+func (ts *tracedStore) SetTeam(ctx context.Context, uid string, team string) error {
+	ctx, span := ts.start(ctx, "SetTeam")
+	err := ts.store.SetTeam(ctx, uid, team)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) GetTeamMembers(ctx context.Context, team string) ([]string, error) {
+	ctx, span := ts.start(ctx, "GetTeamMembers")
+	members, err := ts.store.GetTeamMembers(ctx, team)
+	EndSpan(span, err)
+	return members, err
+}
+
+func (ts *tracedStore) Ping(ctx context.Context) error {
+	ctx, span := ts.start(ctx, "Ping")
+	err := ts.store.Ping(ctx)
+	EndSpan(span, err)
+	return err
+}
+
+func (ts *tracedStore) Close() error {
+	return ts.store.Close()
+}
+
+// tracedEvents EventRepository that start span around every call of wrapped repository
+type tracedEvents struct {
+	events storage.EventRepository
+	driver string
+}
+
+// TraceEvents wrap event repository to start span for every call
+func TraceEvents(events storage.EventRepository, driver string) storage.EventRepository {
+	return &tracedEvents{events: events, driver: driver}
+}
+
+func (te *tracedEvents) GetAllUserEventData(ctx context.Context, since time.Time) ([]storage.UserData, error) {
+	ctx, span := Tracer().Start(ctx, "storage.GetAllUserEventData",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(te.driver),
+			semconv.DBSQLTableKey.String("play_event"),
+		),
+	)
+	userDataList, err := te.events.GetAllUserEventData(ctx, since)
+	EndSpan(span, err)
+	return userDataList, err
+}
+
+func (te *tracedEvents) Close() error {
+	return te.events.Close()
+}
+
+func ignoreNotFound(err error) error {
+	if err == storage.ErrMemberNotFound {
+		return nil
+	}
+	return err
+}
@@ -76,6 +76,16 @@ func ToFloat64(s string) float64 {
 	}
 	return u64
 }
+
+// ToDuration parse duration such as 1h30m, 0 when s is not duration
+func ToDuration(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		zap.L().Error("cannot convert string to duration", zap.Error(err))
+	}
+	return d
+}
+
 func GetFormatDBTime() string {
 	var timeFormat = "2006-01-02 15:04:05"
 	return timeFormat
@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"rangkingserver/config"
+	"rangkingserver/metrics"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// eventBufferSize is number of emitted events waiting to be enqueued, events are dropped when it is full
+	eventBufferSize = 1024
+	// pruneInterval is how often delivery log older than retention is pruned
+	pruneInterval = time.Hour
+	// maxErrorLength is length error of attempt is truncated to
+	maxErrorLength = 1024
+	// maxResponseBytes is part of response body read before connection is reused
+	maxResponseBytes = 64 << 10
+)
+
+// emitted is event with its JSON body
+type emitted struct {
+	event   Event
+	payload []byte
+}
+
+// Dispatcher enqueue emitted events for every subscription of their kind and post due deliveries.
+// Emit never block, so it can be called from event loop.
+type Dispatcher struct {
+	queue  Queue
+	client *http.Client
+	events chan emitted
+
+	mu            sync.RWMutex
+	subscriptions []Subscription
+}
+
+// NewDispatcher create dispatcher on queue, client nil use transport that refuse addresses not allowed by
+// webhooks.allowed_networks. Redirects are not followed.
+func NewDispatcher(queue Queue, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Transport: guardedTransport()}
+	}
+	posting := *client
+	posting.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Dispatcher{
+		queue:  queue,
+		client: &posting,
+		events: make(chan emitted, eventBufferSize),
+	}
+}
+
+// Queue get queue of dispatcher
+func (d *Dispatcher) Queue() Queue {
+	return d.queue
+}
+
+// Refresh reload subscriptions from queue, they are also reloaded every poll interval to see changes of other replicas
+func (d *Dispatcher) Refresh(ctx context.Context) error {
+	subscriptions, err := d.queue.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.subscriptions = subscriptions
+	d.mu.Unlock()
+	return nil
+}
+
+// Wants report whether any subscription registered for kind, so callers skip work of events nobody receive
+func (d *Dispatcher) Wants(kind string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, subscription := range d.subscriptions {
+		if subscription.Wants(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Emit queue event of kind with data for subscribers, event is dropped when nobody subscribed or buffer is full
+func (d *Dispatcher) Emit(kind string, data interface{}) {
+	if !d.Wants(kind) {
+		return
+	}
+	event := Event{ID: NewID(), Kind: kind, CreatedAt: time.Now().UTC(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		zap.L().Error("cannot marshal webhook event", zap.String("kind", kind), zap.Error(err))
+		return
+	}
+	select {
+	case d.events <- emitted{event: event, payload: payload}:
+	default:
+		metrics.CountWebhookDelivery(kind, "dropped")
+		zap.L().Warn("webhook event buffer is full, event dropped", zap.String("kind", kind), zap.String("event", event.ID))
+	}
+}
+
+// Run enqueue emitted events and post due deliveries until ctx is done, then enqueue events still buffered
+func (d *Dispatcher) Run(ctx context.Context) {
+	delivered := make(chan struct{})
+	go func() {
+		d.deliverLoop(ctx)
+		close(delivered)
+	}()
+	for {
+		select {
+		case ev := <-d.events:
+			d.enqueue(ctx, ev)
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.Background(), config.Current().Webhooks.Timeout)
+			for drained := false; !drained; {
+				select {
+				case ev := <-d.events:
+					d.enqueue(drainCtx, ev)
+				default:
+					drained = true
+				}
+			}
+			cancel()
+			<-delivered
+			return
+		}
+	}
+}
+
+// enqueue add delivery of event for every subscription of its kind
+func (d *Dispatcher) enqueue(ctx context.Context, ev emitted) {
+	d.mu.RLock()
+	var deliveries []Delivery
+	for _, subscription := range d.subscriptions {
+		if !subscription.Wants(ev.event.Kind) {
+			continue
+		}
+		deliveries = append(deliveries, Delivery{
+			ID:             NewID(),
+			SubscriptionID: subscription.ID,
+			EventID:        ev.event.ID,
+			Kind:           ev.event.Kind,
+			Payload:        ev.payload,
+			Status:         StatusPending,
+			NextAttemptAt:  ev.event.CreatedAt,
+			CreatedAt:      ev.event.CreatedAt,
+		})
+	}
+	d.mu.RUnlock()
+	if err := d.queue.Enqueue(ctx, deliveries); err != nil {
+		metrics.CountWebhookDelivery(ev.event.Kind, "dropped")
+		zap.L().Error("cannot enqueue webhook deliveries", zap.String("kind", ev.event.Kind), zap.String("event", ev.event.ID), zap.Error(err))
+	}
+}
+
+// deliverLoop reload subscriptions, post due deliveries and prune delivery log every poll interval
+func (d *Dispatcher) deliverLoop(ctx context.Context) {
+	interval := config.Current().Webhooks.PollInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if next := config.Current().Webhooks.PollInterval; next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+		if err := d.Refresh(ctx); err != nil && ctx.Err() == nil {
+			zap.L().Warn("cannot load webhook subscriptions", zap.Error(err))
+		}
+		d.deliverDue(ctx)
+		if time.Since(pruned) >= pruneInterval {
+			pruned = time.Now()
+			count, err := d.queue.Prune(ctx, pruned.Add(-config.Current().Webhooks.Retention))
+			if err != nil && ctx.Err() == nil {
+				zap.L().Warn("cannot prune webhook deliveries", zap.Error(err))
+			}
+			if count > 0 {
+				zap.L().Debug("webhook deliveries pruned", zap.Int64("count", count))
+			}
+		}
+	}
+}
+
+// deliverDue claim due deliveries batch size at a time and post each batch concurrently
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		cfg := config.Current().Webhooks
+		now := time.Now().UTC()
+		deliveries, err := d.queue.Claim(ctx, now, now.Add(2*cfg.Timeout), cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				zap.L().Warn("cannot claim webhook deliveries", zap.Error(err))
+			}
+			return
+		}
+		subscriptions := make(map[string]Subscription)
+		d.mu.RLock()
+		for _, subscription := range d.subscriptions {
+			subscriptions[subscription.ID] = subscription
+		}
+		d.mu.RUnlock()
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func(delivery Delivery) {
+				defer wg.Done()
+				d.deliver(ctx, cfg, subscriptions, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+		if len(deliveries) < cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// deliver post delivery and save result, delivery interrupted by shutdown is claimed again after lease
+func (d *Dispatcher) deliver(ctx context.Context, cfg config.WebhooksConfig, subscriptions map[string]Subscription, delivery Delivery) {
+	now := time.Now().UTC()
+	subscription, ok := subscriptions[delivery.SubscriptionID]
+	var err error
+	if ok {
+		delivery.ResponseStatus, err = d.post(ctx, cfg, subscription, delivery, now)
+	} else {
+		delivery.ResponseStatus, err = 0, ErrNotFound
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	result := StatusDelivered
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.Error = ""
+	case delivery.Attempts >= cfg.MaxAttempts || !ok:
+		result = StatusFailed
+		delivery.Status = StatusFailed
+	default:
+		result = "retry"
+		delivery.NextAttemptAt = now.Add(backoff(cfg, delivery.Attempts))
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+		if len(delivery.Error) > maxErrorLength {
+			delivery.Error = delivery.Error[:maxErrorLength]
+		}
+	}
+	metrics.CountWebhookDelivery(delivery.Kind, result)
+	if result == StatusFailed {
+		zap.L().Warn("webhook delivery failed", zap.String("delivery", delivery.ID), zap.String("subscription", delivery.SubscriptionID),
+			zap.String("kind", delivery.Kind), zap.Int("attempts", delivery.Attempts), zap.Error(err))
+	}
+	if err := d.queue.Update(ctx, delivery); err != nil {
+		zap.L().Error("cannot save webhook delivery", zap.String("delivery", delivery.ID), zap.Error(err))
+	}
+}
+
+// post send signed payload of delivery to subscription, any status other than 2xx is error
+func (d *Dispatcher) post(ctx context.Context, cfg config.WebhooksConfig, subscription Subscription, delivery Delivery, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rangkingserver-webhook")
+	req.Header.Set(HeaderID, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.Kind)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, delivery.Payload))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
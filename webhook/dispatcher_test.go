@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"rangkingserver/config"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+// setWebhooksConfig set fast retries for test and restore previous config after it
+func setWebhooksConfig(t *testing.T, allowed []string) config.WebhooksConfig {
+	t.Helper()
+	previous := config.Current()
+	cfg := config.Default()
+	cfg.Webhooks.Enabled = true
+	cfg.Webhooks.MaxAttempts = 3
+	cfg.Webhooks.Backoff = 10 * time.Millisecond
+	cfg.Webhooks.MaxBackoff = 40 * time.Millisecond
+	cfg.Webhooks.Timeout = 2 * time.Second
+	cfg.Webhooks.PollInterval = 5 * time.Millisecond
+	cfg.Webhooks.AllowedNetworks = allowed
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+	return cfg.Webhooks
+}
+
+// received is one request subscriber got
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// subscriber is httptest server that answer with statuses in order, last status is repeated
+type subscriber struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+}
+
+func newSubscriber(t *testing.T, statuses ...int) *subscriber {
+	t.Helper()
+	s := &subscriber{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, received{header: r.Header.Clone(), body: body})
+		status := s.statuses[len(s.statuses)-1]
+		if len(s.requests) <= len(s.statuses) {
+			status = s.statuses[len(s.requests)-1]
+		}
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *subscriber) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.requests...)
+}
+
+// startDispatcher subscribe url to every kind and run dispatcher on memory queue until test end
+func startDispatcher(t *testing.T, url string) (*Dispatcher, Subscription) {
+	t.Helper()
+	queue := NewMemoryQueue()
+	subscription := Subscription{ID: NewID(), URL: url, Kinds: Kinds, Secret: testSecret, CreatedAt: time.Now().UTC()}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := queue.AddSubscription(ctx, subscription); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := NewDispatcher(queue, nil)
+	if err := dispatcher.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return dispatcher, subscription
+}
+
+// waitDelivery poll delivery log of subscription until its only delivery leave pending
+func waitDelivery(t *testing.T, queue Queue, subscriptionID string) Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, err := queue.Deliveries(context.Background(), subscriptionID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) > 1 {
+			t.Fatalf("got %d deliveries, want 1", len(deliveries))
+		}
+		if len(deliveries) == 1 && deliveries[0].Status != StatusPending {
+			return deliveries[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("delivery is still pending")
+	return Delivery{}
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"1","kind":"board.reset"}`)
+	timestamp := int64(1700000000)
+	signature := Sign(testSecret, timestamp, body)
+	if !strings.HasPrefix(signature, "sha256=") || len(signature) != len("sha256=")+64 {
+		t.Fatalf("signature %q is not sha256=hex", signature)
+	}
+	if !Verify(testSecret, "1700000000", body, signature) {
+		t.Fatal("signature of same secret, timestamp and body is not verified")
+	}
+	for name, verified := range map[string]bool{
+		"other secret":    Verify("fedcba9876543210", "1700000000", body, signature),
+		"other timestamp": Verify(testSecret, "1700000001", body, signature),
+		"bad timestamp":   Verify(testSecret, "now", body, signature),
+		"other body":      Verify(testSecret, "1700000000", []byte(`{"id":"2","kind":"board.reset"}`), signature),
+		"bad signature":   Verify(testSecret, "1700000000", body, "sha256=00"),
+	} {
+		if verified {
+			t.Errorf("%s is verified", name)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := config.WebhooksConfig{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{
+		1:   time.Second,
+		2:   2 * time.Second,
+		3:   4 * time.Second,
+		4:   8 * time.Second,
+		5:   10 * time.Second,
+		100: 10 * time.Second,
+	} {
+		if got := backoff(cfg, attempts); got != want {
+			t.Errorf("backoff after %d attempts = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestAllowedIP(t *testing.T) {
+	for address, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00:ec2::254":   false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::ffff:10.0.0.1": false,
+	} {
+		if got := AllowedIP(net.ParseIP(address), nil); got != want {
+			t.Errorf("AllowedIP(%s) = %v, want %v", address, got, want)
+		}
+	}
+	if !AllowedIP(net.ParseIP("10.1.2.3"), []string{"10.0.0.0/8"}) {
+		t.Error("address in allowed network is not allowed")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	if err := CheckURL(ctx, "http://169.254.169.254/latest/meta-data", nil); err != ErrForbiddenTarget {
+		t.Errorf("metadata address: got %v, want ErrForbiddenTarget", err)
+	}
+	if err := CheckURL(ctx, "http://localhost:8080/hook", nil); err != ErrForbiddenTarget {
+		t.Errorf("localhost: got %v, want ErrForbiddenTarget", err)
+	}
+	if err := CheckURL(ctx, "http://127.0.0.1:8080/hook", []string{"127.0.0.0/8"}); err != nil {
+		t.Errorf("allowed loopback: got %v", err)
+	}
+}
+
+func TestDispatcherDeliversSignedEvent(t *testing.T) {
+	setWebhooksConfig(t, []string{"127.0.0.0/8"})
+	server := newSubscriber(t, http.StatusNoContent)
+	dispatcher, subscription := startDispatcher(t, server.URL)
+
+	dispatcher.Emit(KindBoardReset, map[string]string{"period": "all"})
+	delivery := waitDelivery(t, dispatcher.Queue(), subscription.ID)
+	if delivery.Status != StatusDelivered || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusNoContent || delivery.Error != "" {
+		t.Fatalf("delivery = %+v, want delivered after 1 attempt with status 204", delivery)
+	}
+	if delivery.LastAttemptAt == nil {
+		t.Error("last attempt time is not set")
+	}
+
+	requests := server.received()
+	if len(requests) != 1 {
+		t.Fatalf("subscriber got %d requests, want 1", len(requests))
+	}
+	header, body := requests[0].header, requests[0].body
+	if header.Get(HeaderID) != delivery.ID || header.Get(HeaderEvent) != KindBoardReset {
+		t.Errorf("headers %s=%q %s=%q, want delivery id and kind", HeaderID, header.Get(HeaderID), HeaderEvent, header.Get(HeaderEvent))
+	}
+	if !Verify(testSecret, header.Get(HeaderTimestamp), body, header.Get(HeaderSignature)) {
+		t.Error("signature of delivered body is not verified")
+	}
+	if timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64); err != nil || time.Since(time.Unix(timestamp, 0)) > time.Minute {
+		t.Errorf("timestamp %q is not current unix seconds", header.Get(HeaderTimestamp))
+	}
+	var event struct {
+		ID   string            `json:"id"`
+		Kind string            `json:"kind"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != delivery.EventID || event.Kind != KindBoardReset || event.Data["period"] != "all" {
+		t.Errorf("body %s does not match event of delivery %+v", body, delivery)
+	}
+}
+
+func TestDispatcherRetriesUntilDelivered(t *testing.T) {
+	setWebhooksConfig(t, []string{"127.0.0.0/8"})
+	server := newSubscriber(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	dispatcher, subscription := startDispatcher(t, server.URL)
+
+	dispatcher.Emit(KindBoardReset, map[string]string{"period": "all"})
+	delivery := waitDelivery(t, dispatcher.Queue(), subscription.ID)
+	if delivery.Status != StatusDelivered || delivery.Attempts != 3 || delivery.ResponseStatus != http.StatusOK || delivery.Error != "" {
+		t.Fatalf("delivery = %+v, want delivered after 3 attempts", delivery)
+	}
+	requests := server.received()
+	if len(requests) != 3 {
+		t.Fatalf("subscriber got %d requests, want 3", len(requests))
+	}
+	for _, request := range requests[1:] {
+		if request.header.Get(HeaderID) != delivery.ID || string(request.body) != string(requests[0].body) {
+			t.Error("retry is not same delivery with same body")
+		}
+	}
+}
+
+func TestDispatcherFailsAfterMaxAttempts(t *testing.T) {
+	cfg := setWebhooksConfig(t, []string{"127.0.0.0/8"})
+	server := newSubscriber(t, http.StatusInternalServerError)
+	dispatcher, subscription := startDispatcher(t, server.URL)
+
+	started := time.Now()
+	dispatcher.Emit(KindBoardReset, map[string]string{"period": "all"})
+	delivery := waitDelivery(t, dispatcher.Queue(), subscription.ID)
+	if delivery.Status != StatusFailed || delivery.Attempts != cfg.MaxAttempts || delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("delivery = %+v, want failed after %d attempts with status 500", delivery, cfg.MaxAttempts)
+	}
+	if delivery.Error != "unexpected status 500" {
+		t.Errorf("error = %q, want unexpected status 500", delivery.Error)
+	}
+	// waits after attempt 1 and 2 are 10ms and 20ms
+	if elapsed := time.Since(started); elapsed < backoff(cfg, 1)+backoff(cfg, 2) {
+		t.Errorf("failed after %v, retries did not wait for backoff", elapsed)
+	}
+	time.Sleep(5 * cfg.PollInterval)
+	if requests := server.received(); len(requests) != cfg.MaxAttempts {
+		t.Errorf("subscriber got %d requests, want %d", len(requests), cfg.MaxAttempts)
+	}
+}
+
+func TestDispatcherRefusesLoopbackNotAllowed(t *testing.T) {
+	setWebhooksConfig(t, nil)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+	dispatcher, subscription := startDispatcher(t, server.URL)
+
+	dispatcher.Emit(KindBoardReset, map[string]string{"period": "all"})
+	delivery := waitDelivery(t, dispatcher.Queue(), subscription.ID)
+	if delivery.Status != StatusFailed || !strings.Contains(delivery.Error, ErrForbiddenTarget.Error()) {
+		t.Fatalf("delivery = %+v, want failed with %v", delivery, ErrForbiddenTarget)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("loopback subscriber was posted to")
+	}
+}
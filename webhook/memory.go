@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryQueue keep subscriptions and deliveries in process memory, they are lost on restart
+type MemoryQueue struct {
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+	deliveries    map[string]Delivery
+}
+
+// NewMemoryQueue create empty queue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		subscriptions: make(map[string]Subscription),
+		deliveries:    make(map[string]Delivery),
+	}
+}
+
+func (mq *MemoryQueue) AddSubscription(ctx context.Context, subscription Subscription) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	mq.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (mq *MemoryQueue) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	subscriptions := make([]Subscription, 0, len(mq.subscriptions))
+	for _, subscription := range mq.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sortSubscriptions(subscriptions)
+	return subscriptions, nil
+}
+
+func (mq *MemoryQueue) DeleteSubscription(ctx context.Context, id string) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if _, ok := mq.subscriptions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(mq.subscriptions, id)
+	for deliveryID, delivery := range mq.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(mq.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (mq *MemoryQueue) Enqueue(ctx context.Context, deliveries []Delivery) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	for _, delivery := range deliveries {
+		mq.deliveries[delivery.ID] = delivery
+	}
+	return nil
+}
+
+// Claim move NextAttemptAt of claimed deliveries to lease
+func (mq *MemoryQueue) Claim(ctx context.Context, now time.Time, lease time.Time, limit int) ([]Delivery, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	var due []Delivery
+	for _, delivery := range mq.deliveries {
+		if delivery.Status == StatusPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, delivery := range due {
+		claimed := delivery
+		claimed.NextAttemptAt = lease
+		mq.deliveries[delivery.ID] = claimed
+	}
+	return due, nil
+}
+
+// Update ignore delivery of deleted subscription
+func (mq *MemoryQueue) Update(ctx context.Context, delivery Delivery) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if _, ok := mq.deliveries[delivery.ID]; ok {
+		mq.deliveries[delivery.ID] = delivery
+	}
+	return nil
+}
+
+func (mq *MemoryQueue) Deliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	deliveries := []Delivery{}
+	for _, delivery := range mq.deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sortDeliveries(deliveries)
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (mq *MemoryQueue) Prune(ctx context.Context, before time.Time) (int64, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	var pruned int64
+	for id, delivery := range mq.deliveries {
+		if delivery.Status != StatusPending && delivery.CreatedAt.Before(before) {
+			delete(mq.deliveries, id)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// sortSubscriptions sort oldest first
+func sortSubscriptions(subscriptions []Subscription) {
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if !subscriptions[i].CreatedAt.Equal(subscriptions[j].CreatedAt) {
+			return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+		}
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+}
+
+// sortDeliveries sort newest first
+func sortDeliveries(deliveries []Delivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+}
@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// webhooks are kept in redis under
+//
+//	webhooks:subscriptions     hash of id -> Subscription JSON
+//	webhooks:deliveries        hash of id -> Delivery JSON
+//	webhooks:due               pending delivery id -> next attempt unix milliseconds
+//	webhooks:created           delivery id -> created unix milliseconds
+//	webhooks:log:{id}          delivery id of subscription -> created unix milliseconds
+const (
+	subscriptionsKey = "webhooks:subscriptions"
+	deliveriesKey    = "webhooks:deliveries"
+	dueKey           = "webhooks:due"
+	createdKey       = "webhooks:created"
+)
+
+func logKey(subscriptionID string) string {
+	return "webhooks:log:" + subscriptionID
+}
+
+// claimScript get up to ARGV[2] ids of KEYS[1] due at ARGV[1] and move them to lease ARGV[3], so claim is atomic between replicas
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[3], id)
+end
+return ids
+`)
+
+// RedisQueue keep queue in redis, replicas using same redis share subscriptions and deliveries
+type RedisQueue struct {
+	client *redis.Client
+}
+
+// NewRedisQueue create queue on redis client
+func NewRedisQueue(client *redis.Client) *RedisQueue {
+	return &RedisQueue{client: client}
+}
+
+func (rq *RedisQueue) with(ctx context.Context) *redis.Client {
+	return rq.client.WithContext(ctx)
+}
+
+func unixMilli(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Millisecond))
+}
+
+func (rq *RedisQueue) AddSubscription(ctx context.Context, subscription Subscription) error {
+	data, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+	return rq.with(ctx).HSet(subscriptionsKey, subscription.ID, data).Err()
+}
+
+func (rq *RedisQueue) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	values, err := rq.with(ctx).HGetAll(subscriptionsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := make([]Subscription, 0, len(values))
+	for _, value := range values {
+		var subscription Subscription
+		if err := json.Unmarshal([]byte(value), &subscription); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	sortSubscriptions(subscriptions)
+	return subscriptions, nil
+}
+
+// DeleteSubscription delete subscription, its deliveries and delivery log in one MULTI transaction
+func (rq *RedisQueue) DeleteSubscription(ctx context.Context, id string) error {
+	exists, err := rq.with(ctx).HExists(subscriptionsKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	ids, err := rq.with(ctx).ZRange(logKey(id), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	_, err = rq.with(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HDel(subscriptionsKey, id)
+		pipe.Del(logKey(id))
+		if len(ids) > 0 {
+			members := make([]interface{}, 0, len(ids))
+			for _, deliveryID := range ids {
+				members = append(members, deliveryID)
+			}
+			pipe.HDel(deliveriesKey, ids...)
+			pipe.ZRem(dueKey, members...)
+			pipe.ZRem(createdKey, members...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (rq *RedisQueue) Enqueue(ctx context.Context, deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	_, err := rq.with(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		for _, delivery := range deliveries {
+			data, err := json.Marshal(delivery)
+			if err != nil {
+				return err
+			}
+			pipe.HSet(deliveriesKey, delivery.ID, data)
+			pipe.ZAdd(dueKey, redis.Z{Score: unixMilli(delivery.NextAttemptAt), Member: delivery.ID})
+			pipe.ZAdd(createdKey, redis.Z{Score: unixMilli(delivery.CreatedAt), Member: delivery.ID})
+			pipe.ZAdd(logKey(delivery.SubscriptionID), redis.Z{Score: unixMilli(delivery.CreatedAt), Member: delivery.ID})
+		}
+		return nil
+	})
+	return err
+}
+
+func (rq *RedisQueue) Claim(ctx context.Context, now time.Time, lease time.Time, limit int) ([]Delivery, error) {
+	result, err := claimScript.Run(rq.with(ctx), []string{dueKey},
+		strconv.FormatFloat(unixMilli(now), 'f', 0, 64), limit, strconv.FormatFloat(unixMilli(lease), 'f', 0, 64)).Result()
+	if err != nil {
+		return nil, err
+	}
+	values, _ := result.([]interface{})
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return rq.get(ctx, ids)
+}
+
+// get deliveries of ids in order, deleted deliveries are skipped
+func (rq *RedisQueue) get(ctx context.Context, ids []string) ([]Delivery, error) {
+	deliveries := []Delivery{}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+	values, err := rq.with(ctx).HMGet(deliveriesKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var delivery Delivery
+		if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// Update ignore delivery of deleted subscription
+func (rq *RedisQueue) Update(ctx context.Context, delivery Delivery) error {
+	exists, err := rq.with(ctx).HExists(deliveriesKey, delivery.ID).Result()
+	if err != nil || !exists {
+		return err
+	}
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	_, err = rq.with(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HSet(deliveriesKey, delivery.ID, data)
+		if delivery.Status == StatusPending {
+			pipe.ZAdd(dueKey, redis.Z{Score: unixMilli(delivery.NextAttemptAt), Member: delivery.ID})
+		} else {
+			pipe.ZRem(dueKey, delivery.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+func (rq *RedisQueue) Deliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error) {
+	ids, err := rq.with(ctx).ZRevRange(logKey(subscriptionID), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	return rq.get(ctx, ids)
+}
+
+func (rq *RedisQueue) Prune(ctx context.Context, before time.Time) (int64, error) {
+	ids, err := rq.with(ctx).ZRangeByScore(createdKey, redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatFloat(unixMilli(before), 'f', 0, 64),
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	deliveries, err := rq.get(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	var pruned int64
+	_, err = rq.with(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		for _, delivery := range deliveries {
+			if delivery.Status == StatusPending {
+				continue
+			}
+			pipe.HDel(deliveriesKey, delivery.ID)
+			pipe.ZRem(createdKey, delivery.ID)
+			pipe.ZRem(logKey(delivery.SubscriptionID), delivery.ID)
+			pruned++
+		}
+		return nil
+	})
+	return pruned, err
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLQueue keep queue in webhook_subscription and webhook_delivery tables of migration 0003, mysql or postgres
+type SQLQueue struct {
+	db *sqlx.DB
+}
+
+// NewSQLQueue create queue on db, queries are rebound to placeholders of db driver
+func NewSQLQueue(db *sqlx.DB) *SQLQueue {
+	return &SQLQueue{db: db}
+}
+
+type subscriptionRow struct {
+	ID        string    `db:"id"`
+	URL       string    `db:"url"`
+	Kinds     string    `db:"kinds"`
+	Secret    string    `db:"secret"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type deliveryRow struct {
+	ID             string     `db:"id"`
+	SubscriptionID string     `db:"subscription_id"`
+	EventID        string     `db:"event_id"`
+	Kind           string     `db:"kind"`
+	Payload        string     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastAttemptAt  *time.Time `db:"last_attempt_at"`
+	ResponseStatus int        `db:"response_status"`
+	LastError      string     `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+const deliveryColumns = "id, subscription_id, event_id, kind, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, created_at"
+
+func (row deliveryRow) delivery() Delivery {
+	delivery := Delivery{
+		ID:             row.ID,
+		SubscriptionID: row.SubscriptionID,
+		EventID:        row.EventID,
+		Kind:           row.Kind,
+		Payload:        []byte(row.Payload),
+		Status:         row.Status,
+		Attempts:       row.Attempts,
+		NextAttemptAt:  row.NextAttemptAt.UTC(),
+		ResponseStatus: row.ResponseStatus,
+		Error:          row.LastError,
+		CreatedAt:      row.CreatedAt.UTC(),
+	}
+	if row.LastAttemptAt != nil {
+		lastAttemptAt := row.LastAttemptAt.UTC()
+		delivery.LastAttemptAt = &lastAttemptAt
+	}
+	return delivery
+}
+
+func (sq *SQLQueue) AddSubscription(ctx context.Context, subscription Subscription) error {
+	_, err := sq.db.ExecContext(ctx, sq.db.Rebind("INSERT INTO webhook_subscription (id, url, kinds, secret, created_at) VALUES (?, ?, ?, ?, ?)"),
+		subscription.ID, subscription.URL, strings.Join(subscription.Kinds, ","), subscription.Secret, subscription.CreatedAt.UTC())
+	return err
+}
+
+func (sq *SQLQueue) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	var rows []subscriptionRow
+	if err := sq.db.SelectContext(ctx, &rows, "SELECT id, url, kinds, secret, created_at FROM webhook_subscription ORDER BY created_at, id"); err != nil {
+		return nil, err
+	}
+	subscriptions := make([]Subscription, 0, len(rows))
+	for _, row := range rows {
+		subscriptions = append(subscriptions, Subscription{
+			ID:        row.ID,
+			URL:       row.URL,
+			Kinds:     strings.Split(row.Kinds, ","),
+			Secret:    row.Secret,
+			CreatedAt: row.CreatedAt.UTC(),
+		})
+	}
+	return subscriptions, nil
+}
+
+// DeleteSubscription delete subscription and its deliveries in one transaction
+func (sq *SQLQueue) DeleteSubscription(ctx context.Context, id string) error {
+	tx, err := sq.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM webhook_subscription WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		if err == nil {
+			err = ErrNotFound
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM webhook_delivery WHERE subscription_id = ?"), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (sq *SQLQueue) Enqueue(ctx context.Context, deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	tx, err := sq.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := tx.Rebind("INSERT INTO webhook_delivery (" + deliveryColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	for _, delivery := range deliveries {
+		if _, err := tx.ExecContext(ctx, query, delivery.ID, delivery.SubscriptionID, delivery.EventID, delivery.Kind, string(delivery.Payload),
+			delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(), nil, delivery.ResponseStatus, delivery.Error, delivery.CreatedAt.UTC()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Claim lock due rows with SELECT FOR UPDATE and move their next attempt to lease in same transaction
+func (sq *SQLQueue) Claim(ctx context.Context, now time.Time, lease time.Time, limit int) ([]Delivery, error) {
+	tx, err := sq.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var rows []deliveryRow
+	if err := tx.SelectContext(ctx, &rows, tx.Rebind("SELECT "+deliveryColumns+" FROM webhook_delivery WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ? FOR UPDATE"),
+		StatusPending, now.UTC(), limit); err != nil {
+		return nil, err
+	}
+	deliveries := make([]Delivery, 0, len(rows))
+	if len(rows) == 0 {
+		return deliveries, nil
+	}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+		deliveries = append(deliveries, row.delivery())
+	}
+	query, args, err := sqlx.In("UPDATE webhook_delivery SET next_attempt_at = ? WHERE id IN (?)", lease.UTC(), ids)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+func (sq *SQLQueue) Update(ctx context.Context, delivery Delivery) error {
+	var lastAttemptAt interface{}
+	if delivery.LastAttemptAt != nil {
+		lastAttemptAt = delivery.LastAttemptAt.UTC()
+	}
+	_, err := sq.db.ExecContext(ctx, sq.db.Rebind("UPDATE webhook_delivery SET status = ?, attempts = ?, next_attempt_at = ?, last_attempt_at = ?, response_status = ?, last_error = ? WHERE id = ?"),
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(), lastAttemptAt, delivery.ResponseStatus, delivery.Error, delivery.ID)
+	return err
+}
+
+func (sq *SQLQueue) Deliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error) {
+	var rows []deliveryRow
+	if err := sq.db.SelectContext(ctx, &rows, sq.db.Rebind("SELECT "+deliveryColumns+" FROM webhook_delivery WHERE subscription_id = ? ORDER BY created_at DESC, id DESC LIMIT ?"),
+		subscriptionID, limit); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	deliveries := make([]Delivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, row.delivery())
+	}
+	return deliveries, nil
+}
+
+func (sq *SQLQueue) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := sq.db.ExecContext(ctx, sq.db.Rebind("DELETE FROM webhook_delivery WHERE status <> ? AND created_at < ?"), StatusPending, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"rangkingserver/config"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned when subscriber address is private, loopback, link-local or metadata address
+// and is not in webhooks.allowed_networks
+var ErrForbiddenTarget = errors.New("webhook target address is not allowed")
+
+// blockedNetworks are not public unicast but are not reported by net.IP helpers
+var blockedNetworks = []*net.IPNet{
+	mustCIDR("100.64.0.0/10"), // carrier grade nat
+	mustCIDR("192.0.0.0/24"),  // ietf protocol assignments
+	mustCIDR("198.18.0.0/15"), // benchmarking
+	mustCIDR("64:ff9b::/96"),  // nat64 can reach any ipv4 address
+}
+
+func mustCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// AllowedIP report whether subscriber at ip can be posted to, public unicast address or address in one of allowed networks.
+// Metadata endpoints (169.254.169.254, fd00:ec2::254) are link-local or private and blocked with them.
+func AllowedIP(ip net.IP, allowed []string) bool {
+	for _, cidr := range allowed {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL resolve host of subscriber url, ErrForbiddenTarget when any of its addresses is not allowed
+func CheckURL(ctx context.Context, rawURL string, allowed []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !AllowedIP(ip, allowed) {
+			return ErrForbiddenTarget
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !AllowedIP(addr.IP, allowed) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
+
+// guardedTransport dial subscribers without proxy and refuse connection to address that is not allowed,
+// so host that resolve to private address after subscription is checked too
+func guardedTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !AllowedIP(ip, config.Current().Webhooks.AllowedNetworks) {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"rangkingserver/config"
+	"strconv"
+	"time"
+)
+
+// event kinds subscribers register for
+const (
+	KindPlayerOvertaken    = "player.overtaken"
+	KindBoardReset         = "board.reset"
+	KindTournamentFinished = "tournament.finished"
+	KindMilestoneReached   = "milestone.reached"
+)
+
+// Kinds is every event kind
+var Kinds = []string{KindPlayerOvertaken, KindBoardReset, KindTournamentFinished, KindMilestoneReached}
+
+// KnownKind report whether kind is one of Kinds
+func KnownKind(kind string) bool {
+	for _, known := range Kinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// delivery status
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// headers of delivery request, signature is "sha256=" and hex HMAC-SHA256 of timestamp, "." and body
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// ErrNotFound is returned when subscription does not exist
+var ErrNotFound = errors.New("webhook subscription not found")
+
+// Subscription is URL that receive events of Kinds, payloads are signed with Secret
+type Subscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Kinds     []string  `json:"kinds"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Wants report whether subscription registered for kind
+func (s Subscription) Wants(kind string) bool {
+	for _, wanted := range s.Kinds {
+		if wanted == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is JSON body sent to subscribers, Data depend on Kind
+type Event struct {
+	ID        string      `json:"id"`
+	Kind      string      `json:"kind"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Delivery is one event sent to one subscription, pending until delivered or MaxAttempts failed
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	Kind           string          `json:"kind"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// Queue keep subscriptions and deliveries so pending deliveries survive restart and are shared by replicas
+type Queue interface {
+	AddSubscription(ctx context.Context, subscription Subscription) error
+	// Subscriptions get every subscription, oldest first
+	Subscriptions(ctx context.Context) ([]Subscription, error)
+	// DeleteSubscription delete subscription and its deliveries, ErrNotFound when it does not exist
+	DeleteSubscription(ctx context.Context, id string) error
+	Enqueue(ctx context.Context, deliveries []Delivery) error
+	// Claim get up to limit pending deliveries due at now and hide them from other claims until lease
+	Claim(ctx context.Context, now time.Time, lease time.Time, limit int) ([]Delivery, error)
+	// Update save delivery after attempt, pending delivery is due again at NextAttemptAt
+	Update(ctx context.Context, delivery Delivery) error
+	// Deliveries get up to limit deliveries of subscription, newest first
+	Deliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error)
+	// Prune delete delivered and failed deliveries created before time, return number deleted
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// NewID get random 128 bit hex id
+func NewID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// Sign get signature header of body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify check signature and timestamp headers of received body, receiver should also reject old timestamps
+func Verify(secret string, timestamp string, body []byte, signature string) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature))
+}
+
+// backoff get wait after attempts failed attempts, doubled from backoff up to max_backoff
+func backoff(cfg config.WebhooksConfig, attempts int) time.Duration {
+	wait := cfg.Backoff
+	for i := 1; i < attempts && wait < cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > cfg.MaxBackoff {
+		wait = cfg.MaxBackoff
+	}
+	return wait
+}